@@ -33,6 +33,7 @@ func NewAgentCmd(globalFlags *flags.GlobalFlags) *cobra.Command {
 	agentCmd.AddCommand(NewContainerTunnelCmd(globalFlags))
 	agentCmd.AddCommand(NewGitCredentialsCmd(globalFlags))
 	agentCmd.AddCommand(NewDockerCredentialsCmd(globalFlags))
+	agentCmd.AddCommand(NewCleanupProcessesCmd())
 	return agentCmd
 }
 
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/loft-sh/devpod/pkg/agent"
+	"github.com/spf13/cobra"
+)
+
+// NewCleanupProcessesCmd creates a new command
+func NewCleanupProcessesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cleanup-processes",
+		Short: "Stops DevPod helper processes whose client is gone",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			stale, err := agent.FindStaleProcesses("/proc")
+			if err != nil {
+				return fmt.Errorf("find stale processes: %w", err)
+			}
+
+			// print one line per stopped process for the caller to report
+			for _, process := range stale {
+				osProcess, err := os.FindProcess(process.PID)
+				if err == nil {
+					err = osProcess.Signal(syscall.SIGTERM)
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "error stopping process %d: %v\n", process.PID, err)
+					continue
+				}
+
+				fmt.Printf("%d %s\n", process.PID, process.Command)
+			}
+
+			return nil
+		},
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/loft-sh/devpod/cmd/flags"
 	"github.com/loft-sh/devpod/pkg/agent/tunnel"
@@ -21,12 +22,16 @@ import (
 type CredentialsServerCmd struct {
 	*flags.GlobalFlags
 
-	User string
+	Users      []string
+	HelperPath string
+	RuntimeDir string
 
 	ConfigureGitHelper    bool
 	ConfigureDockerHelper bool
 
 	ForwardPorts bool
+
+	Cleanup bool
 }
 
 // NewCredentialsServerCmd creates a new command
@@ -45,13 +50,25 @@ func NewCredentialsServerCmd(flags *flags.GlobalFlags) *cobra.Command {
 	credentialsServerCmd.Flags().BoolVar(&cmd.ConfigureGitHelper, "configure-git-helper", false, "If true will configure git helper")
 	credentialsServerCmd.Flags().BoolVar(&cmd.ConfigureDockerHelper, "configure-docker-helper", false, "If true will configure docker helper")
 	credentialsServerCmd.Flags().BoolVar(&cmd.ForwardPorts, "forward-ports", false, "If true will automatically try to forward open ports within the container")
-	credentialsServerCmd.Flags().StringVar(&cmd.User, "user", "", "The user to use")
+	credentialsServerCmd.Flags().BoolVar(&cmd.Cleanup, "cleanup", false, "If true will remove credential helpers left behind by a failed credentials server and exit")
+	credentialsServerCmd.Flags().StringArrayVar(&cmd.Users, "user", []string{}, "The user to configure the credential helpers for, can be specified multiple times")
+	credentialsServerCmd.Flags().StringVar(&cmd.HelperPath, "helper-path", "", "The absolute path of the devpod binary the credential helpers should invoke. Defaults to the path of this binary")
+	credentialsServerCmd.Flags().StringVar(&cmd.RuntimeDir, "runtime-dir", "", "The folder for the credentials socket, which is created with permissions 0700 and owned by the user. Defaults to ~/.devpod/run of each user, can only be used with a single user")
 	_ = credentialsServerCmd.MarkFlagRequired("user")
 	return credentialsServerCmd
 }
 
 // Run runs the command logic
 func (cmd *CredentialsServerCmd) Run(ctx context.Context, _ []string) error {
+	if cmd.Cleanup {
+		return credentials.CleanupCredentialHelpers(cmd.Users, log.Default.ErrorStreamOnly())
+	} else if cmd.HelperPath != "" {
+		err := validateHelperPath(cmd.HelperPath)
+		if err != nil {
+			return err
+		}
+	}
+
 	// create a grpc client
 	tunnelClient, err := tunnelserver.NewTunnelClient(os.Stdin, os.Stdout, true)
 	if err != nil {
@@ -86,7 +103,24 @@ func (cmd *CredentialsServerCmd) Run(ctx context.Context, _ []string) error {
 	}
 
 	// run the credentials server
-	return credentials.RunCredentialsServer(ctx, cmd.User, port, true, cmd.ConfigureGitHelper, cmd.ConfigureDockerHelper, tunnelClient, log)
+	return credentials.RunCredentialsServer(ctx, cmd.Users, port, cmd.HelperPath, cmd.RuntimeDir, true, cmd.ConfigureGitHelper, cmd.ConfigureDockerHelper, tunnelClient, log)
+}
+
+// validateHelperPath makes sure the credential helpers can invoke the given binary, so a wrong
+// path fails right away instead of when git or docker first need credentials
+func validateHelperPath(helperPath string) error {
+	if !filepath.IsAbs(helperPath) {
+		return fmt.Errorf("helper path %s needs to be absolute", helperPath)
+	}
+
+	stat, err := os.Stat(helperPath)
+	if err != nil {
+		return fmt.Errorf("helper path %s: %w", helperPath, err)
+	} else if !stat.Mode().IsRegular() || stat.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("helper path %s is not an executable file", helperPath)
+	}
+
+	return nil
 }
 
 func forwardPorts(ctx context.Context, client tunnel.TunnelClient, log log.Logger) error {
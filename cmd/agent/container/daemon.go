@@ -31,19 +31,17 @@ func NewDaemonCmd() *cobra.Command {
 
 // Run runs the command logic
 func (cmd *DaemonCmd) Run(_ *cobra.Command, _ []string) error {
+	var timeout time.Duration
 	cmd.Timeout = strings.TrimSpace(cmd.Timeout)
-	if cmd.Timeout == "" {
-		return nil
-	}
-
-	duration, err := time.ParseDuration(cmd.Timeout)
-	if err != nil {
-		return errors.Wrap(err, "parse duration")
-	} else if duration == 0 {
-		return nil
+	if cmd.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(cmd.Timeout)
+		if err != nil {
+			return errors.Wrap(err, "parse duration")
+		}
 	}
 
-	err = os.WriteFile(agent.ContainerActivityFile, nil, 0777)
+	err := os.WriteFile(agent.ContainerActivityFile, nil, 0777)
 	if err != nil {
 		return err
 	}
@@ -52,6 +50,17 @@ func (cmd *DaemonCmd) Run(_ *cobra.Command, _ []string) error {
 	for {
 		time.Sleep(10 * time.Second)
 
+		duration := effectiveTimeout(timeout, sessionTimeout())
+		if duration <= 0 {
+			continue
+		}
+
+		// a session requested to keep the container running
+		_, err := os.Stat(agent.ContainerKeepRunningFile)
+		if err == nil {
+			continue
+		}
+
 		stat, err := os.Stat(agent.ContainerActivityFile)
 		if err != nil {
 			continue
@@ -65,3 +74,30 @@ func (cmd *DaemonCmd) Run(_ *cobra.Command, _ []string) error {
 		return command.Kill("1")
 	}
 }
+
+// sessionTimeout returns the inactivity timeout requested by the last ssh session
+func sessionTimeout() time.Duration {
+	out, err := os.ReadFile(agent.ContainerIdleTimeoutFile)
+	if err != nil {
+		return 0
+	}
+
+	duration, err := time.ParseDuration(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0
+	}
+
+	return duration
+}
+
+// effectiveTimeout returns the smallest of the configured and session timeouts,
+// where a timeout of zero or less means no timeout
+func effectiveTimeout(configured, session time.Duration) time.Duration {
+	if configured <= 0 {
+		return session
+	} else if session <= 0 || configured < session {
+		return configured
+	}
+
+	return session
+}
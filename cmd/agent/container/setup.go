@@ -143,6 +143,9 @@ func (cmd *SetupContainerCmd) Run(ctx context.Context) error {
 		return err
 	}
 
+	// the ide needs to be installed again
+	_ = os.Remove(agent.ContainerIDEReadyFile)
+
 	// setup container
 	err = setup.SetupContainer(setupInfo, workspaceInfo.CLIOptions.WorkspaceEnv, cmd.ChownWorkspace, logger)
 	if err != nil {
@@ -155,8 +158,15 @@ func (cmd *SetupContainerCmd) Run(ctx context.Context) error {
 		return err
 	}
 
-	// start container daemon if necessary
-	if !workspaceInfo.CLIOptions.Proxy && !workspaceInfo.CLIOptions.DisableDaemon && workspaceInfo.ContainerTimeout != "" {
+	// signal that the ide is ready
+	err = os.WriteFile(agent.ContainerIDEReadyFile, nil, 0777)
+	if err != nil {
+		logger.Debugf("Error writing ide ready file: %v", err)
+	}
+
+	// start container daemon if necessary, it is also needed without a configured timeout
+	// as ssh sessions can request an inactivity timeout via --idle-timeout
+	if !workspaceInfo.CLIOptions.Proxy && !workspaceInfo.CLIOptions.DisableDaemon {
 		err = single.Single("devpod.daemon.pid", func() (*exec.Cmd, error) {
 			logger.Debugf("Start DevPod Container Daemon with Inactivity Timeout %s", workspaceInfo.ContainerTimeout)
 			binaryPath, err := os.Executable()
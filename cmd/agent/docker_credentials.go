@@ -8,12 +8,11 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 
 	"github.com/loft-sh/devpod/cmd/flags"
+	"github.com/loft-sh/devpod/pkg/credentials"
 	"github.com/loft-sh/devpod/pkg/dockercredentials"
-	devpodhttp "github.com/loft-sh/devpod/pkg/http"
 	"github.com/loft-sh/log"
 	"github.com/spf13/cobra"
 )
@@ -22,7 +21,8 @@ import (
 type DockerCredentialsCmd struct {
 	*flags.GlobalFlags
 
-	Port int
+	Port   int
+	Socket string
 }
 
 // NewDockerCredentialsCmd creates a new command
@@ -38,7 +38,7 @@ func NewDockerCredentialsCmd(flags *flags.GlobalFlags) *cobra.Command {
 		},
 	}
 	dockerCredentialsCmd.Flags().IntVar(&cmd.Port, "port", 0, "If specified, will use the given port")
-	_ = dockerCredentialsCmd.MarkFlagRequired("port")
+	dockerCredentialsCmd.Flags().StringVar(&cmd.Socket, "socket", "", "The socket of the credentials server, defaults to ~/.devpod/run/credentials.sock of the current user")
 	return dockerCredentialsCmd
 }
 
@@ -63,7 +63,12 @@ func (cmd *DockerCredentialsCmd) handleList(log log.Logger) error {
 		return err
 	}
 
-	response, err := devpodhttp.GetHTTPClient().Post("http://localhost:"+strconv.Itoa(cmd.Port)+"/docker-credentials", "application/json", bytes.NewReader(rawJSON))
+	client, serverURL, err := credentials.NewHelperClient(cmd.Port, cmd.Socket)
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Post(serverURL+"/docker-credentials", "application/json", bytes.NewReader(rawJSON))
 	if err != nil {
 		log.Errorf("Error retrieving list credentials: %v", err)
 		return nil
@@ -116,7 +121,12 @@ func (cmd *DockerCredentialsCmd) handleGet(log log.Logger) error {
 		return err
 	}
 
-	response, err := devpodhttp.GetHTTPClient().Post("http://localhost:"+strconv.Itoa(cmd.Port)+"/docker-credentials", "application/json", bytes.NewReader(rawJSON))
+	client, serverURL, err := credentials.NewHelperClient(cmd.Port, cmd.Socket)
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Post(serverURL+"/docker-credentials", "application/json", bytes.NewReader(rawJSON))
 	if err != nil {
 		log.Errorf("Error retrieving credentials: %v", err)
 		return nil
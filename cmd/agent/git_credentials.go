@@ -8,11 +8,10 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"strconv"
 
 	"github.com/loft-sh/devpod/cmd/flags"
+	devpodcredentials "github.com/loft-sh/devpod/pkg/credentials"
 	"github.com/loft-sh/devpod/pkg/gitcredentials"
-	devpodhttp "github.com/loft-sh/devpod/pkg/http"
 	"github.com/loft-sh/log"
 	"github.com/spf13/cobra"
 )
@@ -21,7 +20,8 @@ import (
 type GitCredentialsCmd struct {
 	*flags.GlobalFlags
 
-	Port int
+	Port   int
+	Socket string
 }
 
 // NewGitCredentialsCmd creates a new command
@@ -37,7 +37,7 @@ func NewGitCredentialsCmd(flags *flags.GlobalFlags) *cobra.Command {
 		},
 	}
 	gitCredentialsCmd.Flags().IntVar(&cmd.Port, "port", 0, "If specified, will use the given port")
-	_ = gitCredentialsCmd.MarkFlagRequired("port")
+	gitCredentialsCmd.Flags().StringVar(&cmd.Socket, "socket", "", "The socket of the credentials server, defaults to ~/.devpod/run/credentials.sock of the current user")
 	return gitCredentialsCmd
 }
 
@@ -63,7 +63,12 @@ func (cmd *GitCredentialsCmd) Run(ctx context.Context, args []string, log log.Lo
 		return err
 	}
 
-	response, err := devpodhttp.GetHTTPClient().Post("http://localhost:"+strconv.Itoa(cmd.Port)+"/git-credentials", "application/json", bytes.NewReader(rawJSON))
+	client, serverURL, err := devpodcredentials.NewHelperClient(cmd.Port, cmd.Socket)
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Post(serverURL+"/git-credentials", "application/json", bytes.NewReader(rawJSON))
 	if err != nil {
 		log.Errorf("Error retrieving credentials: %v", err)
 		return nil
@@ -20,6 +20,7 @@ type InstallDotfilesCmd struct {
 
 	Repository    string
 	InstallScript string
+	Force         bool
 }
 
 // NewInstallDotfilesCmd creates a new command
@@ -37,6 +38,7 @@ func NewInstallDotfilesCmd(flags *flags.GlobalFlags) *cobra.Command {
 	}
 	installDotfilesCmd.Flags().StringVar(&cmd.Repository, "repository", "", "The dotfiles repository")
 	installDotfilesCmd.Flags().StringVar(&cmd.InstallScript, "install-script", "", "The dotfiles install command to execute")
+	installDotfilesCmd.Flags().BoolVar(&cmd.Force, "force", false, "If true will clone and install the dotfiles again even if they are already set up")
 	return installDotfilesCmd
 }
 
@@ -44,6 +46,13 @@ func NewInstallDotfilesCmd(flags *flags.GlobalFlags) *cobra.Command {
 func (cmd *InstallDotfilesCmd) Run(ctx context.Context) error {
 	logger := log.Default.ErrorStreamOnly()
 
+	if cmd.Force {
+		err := os.RemoveAll("dotfiles")
+		if err != nil {
+			return err
+		}
+	}
+
 	_, err := os.Stat("dotfiles")
 	if err == nil {
 		logger.Info("dotfiles already set up, skipping")
@@ -45,6 +45,8 @@ func NewBuildCmd(flags *flags.GlobalFlags) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			cmd.ForwardAllowlist = devPodConfig.ContextOption(config.ContextOptionSSHForwardAllowlist)
+			cmd.ForwardDenylist = devPodConfig.ContextOption(config.ContextOptionSSHForwardDenylist)
 
 			// check permissions
 			if !cmd.SkipPush && cmd.Repository != "" {
@@ -129,7 +131,7 @@ func (cmd *BuildCmd) build(ctx context.Context, workspaceClient client.Workspace
 	}
 	defer workspaceClient.Unlock()
 
-	err = startWait(ctx, workspaceClient, true, log)
+	err = startWait(ctx, workspaceClient, true, false, defaultBusyWarning, workspace2.DefaultPollInterval(), 0, log)
 	if err != nil {
 		return err
 	}
@@ -195,6 +197,7 @@ func (cmd *BuildCmd) buildAgentClient(ctx context.Context, workspaceClient clien
 		workspaceClient.AgentInjectDockerCredentials(),
 		workspaceClient.WorkspaceConfig(),
 		log,
+		tunnelserver.WithForwardPolicy(tunnelserver.ParseForwardPolicy(cmd.ForwardAllowlist, cmd.ForwardDenylist)),
 	)
 	if err != nil {
 		return errors.Wrap(err, "run tunnel machine")
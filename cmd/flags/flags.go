@@ -8,6 +8,7 @@ type GlobalFlags struct {
 	Context   string
 	Provider  string
 	LogOutput string
+	Color     string
 
 	Debug  bool
 	Silent bool
@@ -23,6 +24,7 @@ func SetGlobalFlags(flags *flag.FlagSet) *GlobalFlags {
 
 	flags.StringVar(&globalFlags.DevPodHome, "devpod-home", "", "If defined will override the default devpod home. You can also use DEVPOD_HOME to set this")
 	flags.StringVar(&globalFlags.LogOutput, "log-output", "plain", "The log format to use. Can be either plain, raw or json")
+	flags.StringVar(&globalFlags.Color, "color", "auto", "Whether to colorize the DevPod log output, either auto, always or never. Auto only colorizes if stderr is a terminal. The output of remote commands is not affected")
 	flags.StringVar(&globalFlags.Context, "context", "", "The context to use")
 	flags.StringVar(&globalFlags.Provider, "provider", "", "The provider to use. Needs to be configured for the selected context.")
 	flags.BoolVar(&globalFlags.Debug, "debug", false, "Prints the stack trace if an error occurs")
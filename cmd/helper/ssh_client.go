@@ -5,6 +5,7 @@ import (
 	"os"
 
 	command2 "github.com/loft-sh/devpod/pkg/command"
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/ssh"
@@ -14,6 +15,8 @@ type SSHClient struct {
 	Address string
 	KeyFile string
 	User    string
+	Proxy   string
+	Resolve []string
 }
 
 // NewSSHClientCmd creates a new ssh command
@@ -30,6 +33,8 @@ func NewSSHClientCmd() *cobra.Command {
 	sshCmd.Flags().StringVar(&cmd.KeyFile, "key-file", "", "SSH Key file to use")
 	sshCmd.Flags().StringVar(&cmd.Address, "address", "", "Address to connect to")
 	sshCmd.Flags().StringVar(&cmd.User, "user", "root", "User to connect as")
+	sshCmd.Flags().StringArrayVar(&cmd.Resolve, "resolve", []string{}, "Resolve the host to the given ip in the form host:ip instead of using DNS, can be specified multiple times")
+	sshCmd.Flags().StringVar(&cmd.Proxy, "proxy", "", "HTTP CONNECT proxy to connect through, e.g. http://proxy:3128. Defaults to HTTPS_PROXY / HTTP_PROXY respecting NO_PROXY")
 	_ = sshCmd.MarkFlagRequired("address")
 	return sshCmd
 }
@@ -40,7 +45,12 @@ func (cmd *SSHClient) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
-	sshClient, err := ssh.Dial("tcp", cmd.Address, sshConfig)
+	resolver, err := devssh.NewResolver(cmd.Resolve)
+	if err != nil {
+		return err
+	}
+
+	sshClient, err := devssh.DialWithProxy(ctx, cmd.Address, cmd.Proxy, resolver, sshConfig)
 	if err != nil {
 		return err
 	}
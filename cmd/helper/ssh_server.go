@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gliderlabs/ssh"
@@ -22,10 +23,13 @@ import (
 type SSHServerCmd struct {
 	*flags.GlobalFlags
 
-	Token         string
-	Address       string
-	Stdio         bool
-	TrackActivity bool
+	Token              string
+	Address            string
+	Stdio              bool
+	TrackActivity      bool
+	IdleTimeout        string
+	KeepRunning        string
+	RestrictedCommands string
 }
 
 // NewSSHServerCmd creates a new ssh command
@@ -43,6 +47,9 @@ func NewSSHServerCmd(flags *flags.GlobalFlags) *cobra.Command {
 	sshCmd.Flags().StringVar(&cmd.Address, "address", fmt.Sprintf("0.0.0.0:%d", helperssh.DefaultPort), "Address to listen to")
 	sshCmd.Flags().BoolVar(&cmd.Stdio, "stdio", false, "Will listen on stdout and stdin instead of an address")
 	sshCmd.Flags().BoolVar(&cmd.TrackActivity, "track-activity", false, "If enabled will write the last activity time to a file")
+	sshCmd.Flags().StringVar(&cmd.IdleTimeout, "idle-timeout", "", "If set together with --track-activity, requests the container to stop after this inactivity duration")
+	sshCmd.Flags().StringVar(&cmd.KeepRunning, "keep-running", "", "If true, the container is not stopped due to inactivity until a session passes false")
+	sshCmd.Flags().StringVar(&cmd.RestrictedCommands, "restricted-commands", "", "If set, only these commands are allowed to be executed. Base64 encoded json list of commands")
 	sshCmd.Flags().StringVar(&cmd.Token, "token", "", "Base64 encoded token to use")
 	return sshCmd
 }
@@ -91,10 +98,20 @@ func (cmd *SSHServerCmd) Run(_ *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
+	if cmd.RestrictedCommands != "" {
+		allowedCommands, err := helperssh.DecodeRestrictedCommands(cmd.RestrictedCommands)
+		if err != nil {
+			return err
+		}
+
+		server.Restrict(allowedCommands)
+	}
 
 	// should we listen on stdout & stdin?
 	if cmd.Stdio {
+		writeKeepRunning(cmd.KeepRunning)
 		if cmd.TrackActivity {
+			writeIdleTimeout(cmd.IdleTimeout)
 			go func() {
 				_, err = os.Stat(agent.ContainerActivityFile)
 				if err != nil {
@@ -132,3 +149,43 @@ func (cmd *SSHServerCmd) Run(_ *cobra.Command, _ []string) error {
 
 	return server.ListenAndServe()
 }
+
+// writeKeepRunning creates or removes the file that keeps the container daemon from stopping
+// the container. An empty value keeps the request of a previous session.
+func writeKeepRunning(keepRunning string) {
+	switch strings.TrimSpace(keepRunning) {
+	case "true":
+		err := os.WriteFile(agent.ContainerKeepRunningFile, nil, 0777)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing keep running file: %v\n", err)
+			return
+		}
+
+		_ = os.Chmod(agent.ContainerKeepRunningFile, 0777)
+	case "false":
+		err := os.Remove(agent.ContainerKeepRunningFile)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error removing keep running file: %v\n", err)
+		}
+	}
+}
+
+// writeIdleTimeout stores the inactivity timeout requested by this session for the
+// container daemon. An empty timeout resets the timeout of a previous session.
+func writeIdleTimeout(idleTimeout string) {
+	idleTimeout = strings.TrimSpace(idleTimeout)
+	if idleTimeout == "" {
+		_, err := os.Stat(agent.ContainerIdleTimeoutFile)
+		if err != nil {
+			return
+		}
+	}
+
+	err := os.WriteFile(agent.ContainerIdleTimeoutFile, []byte(idleTimeout), 0777)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing idle timeout: %v\n", err)
+		return
+	}
+
+	_ = os.Chmod(agent.ContainerIdleTimeoutFile, 0777)
+}
@@ -1,16 +1,20 @@
 package machine
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
+	"syscall"
+	"time"
 
 	"github.com/loft-sh/devpod/cmd/flags"
 	devagent "github.com/loft-sh/devpod/pkg/agent"
 	"github.com/loft-sh/devpod/pkg/client"
 	"github.com/loft-sh/devpod/pkg/config"
 	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/devpod/pkg/ssh/server"
 	"github.com/loft-sh/devpod/pkg/workspace"
 	"github.com/loft-sh/log"
 	"github.com/mattn/go-isatty"
@@ -64,7 +68,7 @@ func (cmd *SSHCmd) Run(ctx context.Context, args []string) error {
 	defer writer.Close()
 
 	// start the ssh session
-	return StartSSHSession(ctx, "", cmd.Command, cmd.AgentForwarding, func(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	return StartSSHSession(ctx, SessionOptions{Command: cmd.Command, AgentForwarding: cmd.AgentForwarding}, func(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 		command := fmt.Sprintf("'%s' helper ssh-server --stdio", machineClient.AgentPath())
 		if cmd.Debug {
 			command += " --debug"
@@ -77,12 +81,60 @@ func (cmd *SSHCmd) Run(ctx context.Context, args []string) error {
 				Stderr:  stderr,
 			})
 		}, machineClient.AgentLocal(), machineClient.AgentPath(), machineClient.AgentURL(), true, command, stdin, stdout, stderr, log.Default.ErrorStreamOnly())
-	}, writer)
+	}, os.Stdout, writer, writer)
 }
 
 type ExecFunc func(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Writer) error
 
-func StartSSHSession(ctx context.Context, user, command string, agentForwarding bool, exec ExecFunc, stderr io.Writer) error {
+// SessionOptions configure the ssh session started by StartSSHSession
+type SessionOptions struct {
+	// User is the user to log in as, defaults to the user of the ssh server
+	User string
+
+	// Command is the command to run, an interactive shell is started if it is empty
+	Command string
+
+	// RCCommand runs via sh in its own session before the interactive shell starts, only the
+	// variables it exports are taken over into the shell
+	RCCommand string
+
+	// Env are the environment variables to set in the session
+	Env map[string]string
+
+	// AgentForwarding forwards the local ssh agent, restricted to AgentKeys if not empty
+	AgentForwarding bool
+	AgentKeys       []string
+
+	// NoStdin doesn't read from stdin and requests no pty, like < /dev/null
+	NoStdin bool
+
+	// NoEcho disables the echo of the remote pty
+	NoEcho bool
+
+	// IdleDisconnect closes an interactive session without input for this long, 0 disables it
+	IdleDisconnect time.Duration
+
+	// Recorder records the session if not nil
+	Recorder *devssh.Recorder
+
+	// ClientOptions configure the ssh client
+	ClientOptions devssh.ClientOptions
+
+	// OnCopy is called with the content of OSC 52 clipboard writes of the session if not nil
+	OnCopy func(content []byte)
+
+	// GPGAgentSocket is the socket of the local gpg agent to forward to the session if not empty
+	GPGAgentSocket string
+
+	// ForwardSignals sends signals to the remote command instead of terminating DevPod
+	ForwardSignals bool
+}
+
+// StartSSHSession starts an ssh session via exec as configured by options. The session output
+// is written to stdout and stderr, while execStderr receives the stderr of exec.
+func StartSSHSession(ctx context.Context, options SessionOptions, exec ExecFunc, stdout, stderr, execStderr io.Writer) error {
+	env := options.Env
+	recorder := options.Recorder
 	// create readers
 	stdoutReader, stdoutWriter, err := os.Pipe()
 	if err != nil {
@@ -98,11 +150,11 @@ func StartSSHSession(ctx context.Context, user, command string, agentForwarding
 	// start ssh machine
 	errChan := make(chan error, 1)
 	go func() {
-		errChan <- exec(ctx, stdinReader, stdoutWriter, stderr)
+		errChan <- exec(ctx, stdinReader, stdoutWriter, execStderr)
 	}()
 
 	// start ssh client as root / default user
-	sshClient, err := devssh.StdioClientWithUser(stdoutReader, stdinWriter, user, false)
+	sshClient, err := devssh.StdioClientWithOptions(stdoutReader, stdinWriter, options.User, options.ClientOptions)
 	if err != nil {
 		return err
 	}
@@ -111,19 +163,24 @@ func StartSSHSession(ctx context.Context, user, command string, agentForwarding
 	// create a new session
 	session, err := sshClient.NewSession()
 	if err != nil {
-		return err
+		return devssh.ChannelError(err)
 	}
 	defer session.Close()
 
-	var (
-		stdout io.Writer = os.Stdout
-		stdin  io.Reader = os.Stdin
-	)
+	var stdin io.Reader = os.Stdin
+	if options.NoStdin {
+		// equivalent to < /dev/null, this also means no pty is requested
+		stdin = bytes.NewReader(nil)
+	}
 
 	// request agent forwarding
 	authSock := os.Getenv("SSH_AUTH_SOCK")
-	if agentForwarding && authSock != "" {
-		err = agent.ForwardToRemote(sshClient, authSock)
+	if options.AgentForwarding && authSock != "" {
+		if len(options.AgentKeys) > 0 {
+			err = devssh.ForwardFilteredAgentToRemote(sshClient, authSock, options.AgentKeys)
+		} else {
+			err = agent.ForwardToRemote(sshClient, authSock)
+		}
 		if err != nil {
 			return errors.Errorf("forward agent: %v", err)
 		}
@@ -134,16 +191,47 @@ func StartSSHSession(ctx context.Context, user, command string, agentForwarding
 		}
 	}
 
+	// request gpg agent forwarding
+	if options.GPGAgentSocket != "" {
+		err = devssh.ForwardGPGAgentToRemote(sshClient, options.GPGAgentSocket)
+		if err != nil {
+			return errors.Errorf("forward gpg agent: %v", err)
+		}
+
+		sessionEnv := map[string]string{server.GPGAgentForwardingEnv: "true"}
+		for k, v := range env {
+			sessionEnv[k] = v
+		}
+		env = sessionEnv
+	}
+
+	// run the rc command before the shell starts and take over the variables it exports
+	if options.Command == "" && options.RCCommand != "" {
+		env, err = runRCCommand(sshClient, options.RCCommand, env, stdout, stderr)
+		if err != nil {
+			return err
+		}
+	}
+
+	// a terminating signal ends the session, so the deferred cleanup of the callers still runs
+	terminatedBy := make(chan syscall.Signal, 1)
+	onSignal := func(sig syscall.Signal) {
+		terminatedBy <- sig
+		_ = session.Close()
+	}
+	if options.ForwardSignals {
+		// the signals are sent to the remote command, DevPod exits with its exit code
+		onSignal = nil
+	}
+
 	stdoutFile, validOut := stdout.(*os.File)
 	stdinFile, validIn := stdin.(*os.File)
 	if validOut && validIn && isatty.IsTerminal(stdoutFile.Fd()) {
-		state, err := term.MakeRaw(int(stdinFile.Fd()))
+		restoreTerminal, err := devssh.MakeRaw(int(stdinFile.Fd()), onSignal)
 		if err != nil {
 			return err
 		}
-		defer func() {
-			_ = term.Restore(int(stdinFile.Fd()), state)
-		}()
+		defer restoreTerminal()
 
 		windowChange := devssh.WatchWindowSize(ctx)
 		go func() {
@@ -158,27 +246,82 @@ func StartSSHSession(ctx context.Context, user, command string, agentForwarding
 					continue
 				}
 				_ = session.WindowChange(height, width)
+				if recorder != nil {
+					recorder.Resize(width, height)
+				}
 			}
 		}()
 
-		err = session.RequestPty("xterm-256color", 128, 128, ssh.TerminalModes{})
+		// the local terminal is raw and doesn't echo, so the echo of the input comes from the
+		// remote pty. Disabling it there keeps the input out of the output and recordings, the
+		// local terminal is restored by restoreTerminal however the session ends
+		modes := ssh.TerminalModes{}
+		if options.NoEcho {
+			modes[ssh.ECHO] = 0
+		}
+		err = session.RequestPty("xterm-256color", 128, 128, modes)
 		if err != nil {
 			return err
 		}
 	}
 
+	// close an interactive session without input
+	var idleReader *devssh.IdleReader
+	if options.IdleDisconnect > 0 && validOut && validIn && isatty.IsTerminal(stdoutFile.Fd()) {
+		idleReader = devssh.NewIdleReader(stdin, options.IdleDisconnect, func() {
+			_ = session.Close()
+		})
+		defer idleReader.Stop()
+
+		stdin = idleReader
+	}
+
+	// record the session
+	sessionStderr := stderr
+	if recorder != nil {
+		width, height := 80, 24
+		if validOut && isatty.IsTerminal(stdoutFile.Fd()) {
+			if w, h, err := term.GetSize(int(stdoutFile.Fd())); err == nil {
+				width, height = w, h
+			}
+		}
+
+		err = recorder.Start(width, height)
+		if err != nil {
+			return errors.Wrap(err, "start recording")
+		}
+
+		stdin = recorder.Input(stdin)
+		stdout = recorder.Output(stdout)
+		sessionStderr = recorder.Output(stderr)
+	}
+
+	// OSC 52 sequences are passed through, this additionally sets the local clipboard
+	if options.OnCopy != nil {
+		stdout = devssh.NewOSC52Writer(stdout, options.OnCopy)
+	}
+
+	err = devssh.SetEnv(session, env)
+	if err != nil {
+		return err
+	}
+
 	session.Stdin = stdin
 	session.Stdout = stdout
-	session.Stderr = stderr
-	if command == "" {
+	session.Stderr = sessionStderr
+	if options.Command == "" {
 		err = session.Shell()
 	} else {
-		err = session.Start(command)
+		err = session.Start(options.Command)
 	}
 	if err != nil {
 		return err
 	}
 
+	if options.ForwardSignals {
+		defer devssh.ForwardSignals(session)()
+	}
+
 	// set correct window size
 	if validOut && validIn && isatty.IsTerminal(stdoutFile.Fd()) {
 		width, height, err := term.GetSize(int(stdoutFile.Fd()))
@@ -189,9 +332,54 @@ func StartSSHSession(ctx context.Context, user, command string, agentForwarding
 
 	// wait until done
 	err = session.Wait()
-	if err != nil {
+	select {
+	case sig := <-terminatedBy:
+		return &devssh.SignalError{Signal: sig}
+	default:
+	}
+	if idleReader != nil && idleReader.Idle() {
+		return devssh.ErrIdleDisconnect
+	} else if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// runRCCommand runs the rc command in its own session and returns env together with the variables
+// the rc command exported. A failure of the rc command only prints a warning.
+func runRCCommand(sshClient *ssh.Client, rcCommand string, env map[string]string, stdout, stderr io.Writer) (map[string]string, error) {
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return nil, devssh.ChannelError(err)
+	}
+	defer session.Close()
+
+	err = devssh.SetEnv(session, env)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &bytes.Buffer{}
+	session.Stdout = out
+	session.Stderr = stderr
+	runErr := session.Run(devssh.RCCommand(rcCommand))
+	output, rcEnv := devssh.ParseRCCommandOutput(out.Bytes())
+	_, _ = stdout.Write(output)
+
+	var exitErr *ssh.ExitError
+	if errors.As(runErr, &exitErr) {
+		fmt.Fprintf(stderr, "warning: rc command failed with exit code %d\n", exitErr.ExitStatus())
+	} else if runErr != nil {
+		return nil, errors.Wrap(runErr, "run rc command")
+	}
+
+	sessionEnv := map[string]string{}
+	for k, v := range env {
+		sessionEnv[k] = v
+	}
+	for k, v := range rcEnv {
+		sessionEnv[k] = v
+	}
+	return sessionEnv, nil
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -14,12 +15,16 @@ import (
 	"github.com/loft-sh/devpod/cmd/machine"
 	"github.com/loft-sh/devpod/cmd/pro"
 	"github.com/loft-sh/devpod/cmd/provider"
+	"github.com/loft-sh/devpod/cmd/sshtemplate"
 	"github.com/loft-sh/devpod/cmd/use"
 	"github.com/loft-sh/devpod/pkg/client/clientimplementation"
 	"github.com/loft-sh/devpod/pkg/config"
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
 	"github.com/loft-sh/devpod/pkg/telemetry"
 	log2 "github.com/loft-sh/log"
 	"github.com/loft-sh/log/terminal"
+	"github.com/mattn/go-isatty"
+	"github.com/mgutz/ansi"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/ssh"
@@ -39,6 +44,17 @@ func NewRootCmd() *cobra.Command {
 		PersistentPreRunE: func(cobraCmd *cobra.Command, args []string) error {
 			telemetry.Collector.SetCLIData(cobraCmd, globalFlags)
 
+			switch globalFlags.Color {
+			case "auto":
+				ansi.DisableColors(!isatty.IsTerminal(os.Stderr.Fd()))
+			case "always":
+				ansi.DisableColors(false)
+			case "never":
+				ansi.DisableColors(true)
+			default:
+				return fmt.Errorf("unrecognized color mode %s, needs to be either auto, always or never", globalFlags.Color)
+			}
+
 			if globalFlags.LogOutput == "json" {
 				log2.Default.SetFormat(log2.JSONFormat)
 			} else if globalFlags.LogOutput == "raw" {
@@ -95,6 +111,11 @@ func Execute() {
 			os.Exit(sshExitErr.ExitStatus())
 		}
 
+		var signalErr *devssh.SignalError
+		if errors.As(err, &signalErr) {
+			os.Exit(signalErr.ExitCode())
+		}
+
 		//nolint:all
 		if execExitErr, ok := err.(*exec.ExitError); ok {
 			os.Exit(execExitErr.ExitCode())
@@ -128,6 +149,7 @@ func BuildRoot() *cobra.Command {
 	rootCmd.AddCommand(ide.NewIDECmd(globalFlags))
 	rootCmd.AddCommand(machine.NewMachineCmd(globalFlags))
 	rootCmd.AddCommand(context.NewContextCmd(globalFlags))
+	rootCmd.AddCommand(sshtemplate.NewSSHTemplateCmd(globalFlags))
 	rootCmd.AddCommand(pro.NewProCmd(globalFlags))
 	rootCmd.AddCommand(NewUpCmd(globalFlags))
 	rootCmd.AddCommand(NewDeleteCmd(globalFlags))
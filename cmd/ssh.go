@@ -1,46 +1,221 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/alessio/shellescape"
+	"github.com/joho/godotenv"
 	"github.com/loft-sh/devpod/cmd/flags"
 	"github.com/loft-sh/devpod/cmd/machine"
 	"github.com/loft-sh/devpod/pkg/agent"
 	client2 "github.com/loft-sh/devpod/pkg/client"
+	"github.com/loft-sh/devpod/pkg/command"
 	"github.com/loft-sh/devpod/pkg/config"
-	"github.com/loft-sh/devpod/pkg/port"
+	config2 "github.com/loft-sh/devpod/pkg/devcontainer/config"
+	"github.com/loft-sh/devpod/pkg/devcontainer/setup"
+	provider2 "github.com/loft-sh/devpod/pkg/provider"
 	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	helperssh "github.com/loft-sh/devpod/pkg/ssh/server"
+	"github.com/loft-sh/devpod/pkg/tracing"
 	"github.com/loft-sh/devpod/pkg/tunnel"
+	"github.com/loft-sh/devpod/pkg/version"
 	workspace2 "github.com/loft-sh/devpod/pkg/workspace"
 	"github.com/loft-sh/log"
+	"github.com/loft-sh/log/terminal"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/crypto/ssh"
 )
 
+const (
+	// transportAuto selects the transport based on the workspace
+	transportAuto = "auto"
+	// transportStdio tunnels ssh through the stdio of the provider command
+	transportStdio = "stdio"
+)
+
+const (
+	// logDestinationStderr writes the DevPod logs to stderr
+	logDestinationStderr = "stderr"
+	// logDestinationStdout writes the DevPod logs to stdout
+	logDestinationStdout = "stdout"
+)
+
+// idleDisconnectExitCode is the exit code of devpod ssh if the session was closed by --idle-disconnect
+const idleDisconnectExitCode = 124
+
+// remoteDockerSocket is the docker socket within the workspace that is forwarded with --forward-docker
+const remoteDockerSocket = "/var/run/docker.sock"
+
+// transports are the valid values of --transport
+var transports = []string{transportAuto, transportStdio}
+
 // SSHCmd holds the ssh cmd flags
 type SSHCmd struct {
 	*flags.GlobalFlags
 
 	ForwardPortsTimeout string
 	ForwardPorts        []string
+	ForwardsFile        string
+	ForwardDocker       bool
+	ForwardGPG          bool
 
 	Stdio           bool
+	RawAgent        bool
+	WSAddr          string
+	WSOrigins       []string
+	TunnelOnly      bool
+	TunnelTarget    string
+	NoStdin         bool
+	NoEcho          bool
+	IgnoreExit      bool
 	JumpContainer   bool
 	AgentForwarding bool
+	AuthSock        string
+	AgentKeys       []string
+
+	SyncDown []string
+	SyncUp   []string
+	SyncOnly bool
+
+	Daemon      bool
+	DaemonChild bool
+	Name        string
+	Attach      string
+	ListDaemons bool
+	Stop        string
+
+	StartServices    bool
+	Start            bool
+	BusyWarning      string
+	WaitTimeout      string
+	PollInterval     string
+	PollMaxInterval  string
+	PollJitter       float64
+	AutoInstallAgent bool
+	StrictVersion    bool
+
+	Proxy          bool
+	JSONErrors     bool
+	Transport      string
+	LogDestination string
+
+	Restricted         bool
+	RestrictedCommands []string
+
+	KeepAliveActivity    string
+	IdleTimeout          string
+	KeepContainerRunning string
+	IdleDisconnect       string
+	BannerTimeout        string
+	VerboseSSH           bool
+
+	WaitForIDE        bool
+	WaitForIDETimeout string
+
+	WaitMounts        bool
+	WaitMountPaths    []string
+	WaitMountsTimeout string
+
+	NonInteractive bool
+	Setup          string
+	ConnectRetries int
+	RetryOn        []string
+	Exec           bool
+	Preflight      bool
+	NoPreflight    bool
+	NoCaptiveCheck bool
 
-	StartServices bool
+	FromSnapshot string
+	Keep         bool
 
-	Proxy bool
+	History         bool
+	Since           string
+	Workspace       string
+	ProxyCommand    bool
+	PrintSSHCommand bool
+	Last            bool
+	Exact           bool
+	NoPicker        bool
 
-	Command string
-	User    string
+	MaxSessions int
+	MaxChannels int
+
+	ProviderOptions []string
+
+	Record      string
+	RecordStdin bool
+
+	OutputFile     string
+	ErrorFile      string
+	AppendOutput   bool
+	OutputMetadata bool
+
+	Commands        []string
+	CommandBase64   string
+	MapPaths        []string
+	Edit            string
+	EditWorkdir     string
+	ContinueOnError bool
+	Asserts         []string
+	AssertAll       bool
+	CPULimit        string
+	MemoryLimit     string
+	RCCommand       string
+	User            string
+	CredentialUsers []string
+	RuntimeDir      string
+	HelperPath      string
+	Env             []string
+	EnvFile         string
+	EnvPassthrough  bool
+	Unsafe          bool
+	Profile         string
+	Template        string
+	Tmux            bool
+	Sudo            bool
+	AckBanner       bool
+
+	AuditLog  string
+	CopyID    string
+	Cleanup   bool
+	Clipboard bool
+
+	RotateKey   bool
+	ReinjectKey bool
+
+	Diagnose bool
+	JSON     bool
+
+	Benchmark           bool
+	BenchmarkIterations int
+	BenchmarkSize       int
+
+	env            map[string]string
+	tmuxSession    string
+	audit          *devssh.AuditLog
+	bannerTimeout  time.Duration
+	idleDisconnect time.Duration
+	channelLimiter *devssh.ChannelLimiter
+	limits         devssh.ResourceLimits
+	gpgAgentSocket string
+	forwards       []devssh.Forward
+	syncs          []syncTask
+	workspace      string
 }
 
 // NewSSHCmd creates a new ssh command
@@ -51,37 +226,550 @@ func NewSSHCmd(flags *flags.GlobalFlags) *cobra.Command {
 	sshCmd := &cobra.Command{
 		Use:   "ssh",
 		Short: "Starts a new ssh session to a workspace",
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(c *cobra.Command, args []string) error {
 			ctx := context.Background()
+			if cmd.NonInteractive {
+				// every prompt checks for a terminal first and returns an error without one
+				terminal.IsTerminalIn = false
+			}
+			if cmd.JSON && !cmd.Diagnose {
+				return fmt.Errorf("--json can only be used together with --diagnose")
+			} else if cmd.Diagnose {
+				return cmd.diagnose(ctx, args)
+			}
+
+			err := config.CheckContext(cmd.Context)
+			if err != nil {
+				return err
+			}
+
 			devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
 			if err != nil {
 				return err
 			}
 
-			client, err := workspace2.GetWorkspace(devPodConfig, args, true, log.Default.ErrorStreamOnly())
+			// set the flags of the connection template that weren't given
+			if cmd.Template != "" {
+				template := devPodConfig.Current().SSHTemplates[cmd.Template]
+				if template == nil {
+					return fmt.Errorf("template %s doesn't exist, import it via 'devpod ssh-template import'", cmd.Template)
+				}
+
+				err = config.ApplySSHTemplate(c.LocalFlags(), template)
+				if err != nil {
+					return err
+				}
+			}
+
+			logger, closeLogger, err := cmd.logger()
+			if err != nil {
+				return err
+			}
+			defer closeLogger()
+
+			if cmd.ReinjectKey && !cmd.RotateKey {
+				return fmt.Errorf("--reinject-key can only be used together with --rotate-key")
+			} else if cmd.RotateKey {
+				if len(args) > 0 {
+					return fmt.Errorf("--rotate-key cannot be used together with a workspace argument")
+				}
+
+				return cmd.rotateKey(ctx, devPodConfig, logger)
+			}
+
+			if cmd.BannerTimeout != "" {
+				cmd.bannerTimeout, err = time.ParseDuration(cmd.BannerTimeout)
+				if err != nil {
+					return errors.Wrap(err, "parse banner timeout")
+				} else if cmd.bannerTimeout < 0 {
+					return fmt.Errorf("banner timeout cannot be negative")
+				}
+			}
+
+			if cmd.CommandBase64 != "" {
+				if len(cmd.Commands) > 0 {
+					return fmt.Errorf("--command-base64 cannot be used together with --command")
+				}
+
+				command, err := devssh.DecodeCommand(cmd.CommandBase64)
+				if err != nil {
+					return fmt.Errorf("parse --command-base64: %w", err)
+				}
+				cmd.Commands = []string{command}
+			}
+
+			// manage the named daemons
+			if cmd.ListDaemons || cmd.Stop != "" || cmd.Attach != "" {
+				if len(args) > 0 || cmd.Daemon {
+					return fmt.Errorf("--list-daemons, --stop and --attach cannot be used together with --daemon or a workspace argument")
+				}
+
+				return devssh.SessionExitError(cmd.manageDaemons(ctx, devPodConfig, logger), cmd.IgnoreExit && len(cmd.Commands) == 0)
+			} else if cmd.Name != "" && !cmd.Daemon {
+				return fmt.Errorf("--name can only be used together with --daemon")
+			} else if cmd.Daemon && len(cmd.Asserts) > 0 {
+				return fmt.Errorf("--assert cannot be used together with --daemon")
+			} else if cmd.Daemon && !cmd.DaemonChild {
+				return cmd.startDaemon(logger)
+			}
+
+			// parse a devpod:// connection url into the flags
+			if len(args) > 0 && devssh.IsConnectionURL(args[0]) {
+				args, err = cmd.applyConnectionURL(args)
+				if err != nil {
+					return err
+				}
+			}
+
+			if cmd.Workspace != "" {
+				if !cmd.History {
+					return fmt.Errorf("--workspace can only be used together with --history")
+				} else if len(args) > 0 {
+					return fmt.Errorf("--workspace cannot be used together with a workspace argument")
+				}
+
+				args = []string{cmd.Workspace}
+			}
+			if cmd.Since != "" && !cmd.History {
+				return fmt.Errorf("--since can only be used together with --history")
+			}
+			if cmd.History && len(args) == 0 && !cmd.Last {
+				return printConnectionHistory(devPodConfig, nil, cmd.Since, logger)
+			}
+
+			resolveOptions := workspace2.ResolveOptions{ChangeLastUsed: !cmd.History && !cmd.ProxyCommand && !cmd.PrintSSHCommand}
+			if cmd.Last {
+				if len(args) > 0 {
+					return fmt.Errorf("--last cannot be used together with a workspace argument")
+				}
+
+				resolveOptions.Resolver = workspace2.LastUsedResolver
+			} else if len(args) == 0 {
+				resolveOptions.Resolver = cmd.currentDirOrPickerResolver(ctx)
+			} else if !cmd.Exact {
+				resolveOptions.Resolver = workspace2.FuzzyResolver
+			}
+
+			client, err := workspace2.GetWorkspaceWithOptions(devPodConfig, args, resolveOptions, logger)
+			if err != nil {
+				return err
+			} else if cmd.History {
+				return printConnectionHistory(devPodConfig, []string{client.Workspace()}, cmd.Since, logger)
+			} else if cmd.ProxyCommand {
+				return cmd.printProxyCommand(client)
+			} else if cmd.PrintSSHCommand {
+				return cmd.printSSHCommand(devPodConfig, client)
+			}
+
+			// override provider options for this session only
+			err = workspace2.OverlayProviderOptions(devPodConfig, client.Provider(), cmd.ProviderOptions, logger)
 			if err != nil {
 				return err
 			}
 
-			return cmd.Run(ctx, devPodConfig, client, log.Default.ErrorStreamOnly())
+			// export spans of the connect phases if an OTLP endpoint is configured
+			ctx, flushTraces := tracing.Start(ctx, logger)
+			ctx, span := tracing.StartSpan(ctx, "devpod ssh", attribute.String("devpod.workspace", client.Workspace()), attribute.String("devpod.provider", client.Provider()))
+			err = cmd.Run(ctx, devPodConfig, client, logger)
+			tracing.EndSpan(span, err)
+			flushTraces()
+
+			// exit with the exit status of the remote shell or command
+			err = devssh.SessionExitError(err, cmd.IgnoreExit)
+			err = cmd.explainCaptivePortal(ctx, err, logger)
+			if err != nil && cmd.JSONErrors {
+				closeLogger()
+				os.Exit(printJSONError(os.Stderr, err))
+			} else if errors.Is(err, devssh.ErrIdleDisconnect) {
+				logger.Infof("Closed session after %s without input", cmd.idleDisconnect)
+				closeLogger()
+				os.Exit(idleDisconnectExitCode)
+			}
+
+			return err
 		},
 	}
 
 	sshCmd.Flags().StringArrayVarP(&cmd.ForwardPorts, "forward-ports", "L", []string{}, "Specifies that connections to the given TCP port or Unix socket on the local (client) host are to be forwarded to the given host and port, or Unix socket, on the remote side.")
+	sshCmd.Flags().StringVar(&cmd.ForwardsFile, "forwards-file", "", "A json file with an array of local, remote or dynamic forwards to set up, in addition to --forward-ports")
 	sshCmd.Flags().StringVar(&cmd.ForwardPortsTimeout, "forward-ports-timeout", "", "Specifies the timeout after which the command should terminate when the ports are unused.")
-	sshCmd.Flags().StringVar(&cmd.Command, "command", "", "The command to execute within the workspace")
-	sshCmd.Flags().StringVar(&cmd.User, "user", "", "The user of the workspace to use")
+	sshCmd.Flags().BoolVar(&cmd.ForwardDocker, "forward-docker", false, "If true will expose the docker daemon of the workspace over a local unix socket. Everyone with access to the socket has full control over the remote docker daemon")
+	sshCmd.Flags().StringVar(&cmd.Setup, "setup", "", "If true will install the dotfiles of the context option DOTFILES_URL in the workspace before the session starts. The setup runs only once per workspace, use --setup=force to run it again")
+	sshCmd.Flags().Lookup("setup").NoOptDefVal = "true"
+	sshCmd.Flags().StringVar(&cmd.KeepContainerRunning, "keep-container-running", "", "If true the container is not stopped due to inactivity after the session ends, until a session passes --keep-container-running=false")
+	sshCmd.Flags().Lookup("keep-container-running").NoOptDefVal = "true"
+	sshCmd.Flags().IntVar(&cmd.ConnectRetries, "connect-retries", 0, "The number of times to retry connecting to the workspace after a transient failure like a refused or reset connection. Authentication failures are never retried")
+	sshCmd.Flags().StringSliceVar(&cmd.RetryOn, "retry-on", []string{}, "Additional error substrings that make a failed connection retryable, e.g. 'connection closed,EOF'. Matched case-insensitively, requires --connect-retries")
+	sshCmd.Flags().BoolVar(&cmd.Exec, "exec", false, "If true will send signals like SIGINT and SIGTERM to the remote command instead of stopping DevPod and exit with the exit code of the remote command, including 128+n for a command killed by signal n, as if it was run directly")
+	sshCmd.Flags().BoolVar(&cmd.ForwardGPG, "forward-gpg", false, "If true will forward the local gpg agent into the workspace and configure git to sign with the local signing key")
+	sshCmd.Flags().StringArrayVar(&cmd.Commands, "command", []string{}, "The command to execute within the workspace. Can be specified multiple times to run several commands sequentially over the same connection")
+	sshCmd.Flags().StringVar(&cmd.CommandBase64, "command-base64", "", "The base64 encoded command to execute within the workspace, it is passed to the shell in the workspace without any quoting")
+	sshCmd.Flags().StringArrayVar(&cmd.MapPaths, "map-path", []string{}, "A mapping in the form localDir:remoteDir, occurrences of the local folder in --command are replaced with the remote one. Can be specified multiple times")
+	sshCmd.Flags().StringVar(&cmd.Edit, "edit", "", "If set will open the given file in the editor of the workspace, which is $VISUAL or $EDITOR of the workspace user, and exit when the editor is closed")
+	sshCmd.Flags().StringVar(&cmd.EditWorkdir, "edit-workdir", "", "The folder in the workspace a relative --edit path is resolved against. Defaults to the workspace folder")
+	sshCmd.Flags().BoolVar(&cmd.ContinueOnError, "continue-on-error", false, "If true will run all commands even if a previous command failed")
+	sshCmd.Flags().StringArrayVar(&cmd.Asserts, "assert", []string{}, "A command that needs to succeed in the workspace before the session or --command starts, can be specified multiple times and runs in order")
+	sshCmd.Flags().BoolVar(&cmd.AssertAll, "assert-all", false, "If true will run all --assert commands even if a previous one failed")
+	sshCmd.Flags().StringVar(&cmd.CPULimit, "cpu-limit", "", "The maximum number of cpus the --command can use, e.g. 0.5. Applied via cgroups if the workspace allows it, otherwise a warning is printed")
+	sshCmd.Flags().StringVar(&cmd.MemoryLimit, "memory-limit", "", "The maximum memory the --command can use, e.g. 512M or 2G. Applied via cgroups or ulimit if the workspace allows it, otherwise a warning is printed")
+	sshCmd.Flags().BoolVar(&cmd.Sudo, "sudo", false, "If true will run the commands with sudo or start a root shell via sudo for interactive sessions")
+	sshCmd.Flags().BoolVar(&cmd.Tmux, "tmux", false, "If true will run the interactive session within a tmux session of the workspace that is reattached on reconnect. Falls back to a plain shell if tmux is not installed")
+	sshCmd.Flags().BoolVar(&cmd.NonInteractive, "non-interactive", false, "If true will fail instead of prompting for input, e.g. to select a workspace or enter a provider option, which is useful for automation")
+	sshCmd.Flags().BoolVar(&cmd.NonInteractive, "batch", false, "Alias for --non-interactive")
+	sshCmd.Flags().BoolVar(&cmd.AckBanner, "ack-banner", false, "If true will acknowledge the connect banner of the workspace, so it is not shown again for the rest of the day")
+	sshCmd.Flags().BoolVar(&cmd.Diagnose, "diagnose", false, "If true will check every step of the connection to the workspace and report the result and timing of each step instead of starting a session")
+	sshCmd.Flags().BoolVar(&cmd.JSON, "json", false, "If true will print the --diagnose report as json")
+	sshCmd.Flags().BoolVar(&cmd.Benchmark, "benchmark", false, "If true will measure the latency, throughput and connect time of the workspace connection instead of starting a session")
+	sshCmd.Flags().IntVar(&cmd.BenchmarkIterations, "benchmark-iterations", 10, "The number of round trips to measure the latency with --benchmark")
+	sshCmd.Flags().IntVar(&cmd.BenchmarkSize, "benchmark-size", 10, "The payload in MB to measure the throughput with --benchmark")
+	sshCmd.Flags().BoolVar(&cmd.Clipboard, "clipboard", false, "If true will copy text that the remote terminal writes to the clipboard via OSC 52 escape sequences to the local clipboard, for terminals that don't support OSC 52 themselves")
+	sshCmd.Flags().BoolVar(&cmd.Cleanup, "cleanup", false, "If true will stop DevPod helper processes like credentials servers and container tunnels whose client is gone, e.g. after a broken connection, instead of starting a session")
+	sshCmd.Flags().StringVar(&cmd.CopyID, "copy-id", "", "If set will add the given public key file to the authorized_keys of the workspace user, which can be selected via --user, instead of starting a session. This grants persistent access")
+	sshCmd.Flags().BoolVar(&cmd.RotateKey, "rotate-key", false, "If true will replace the DevPod ssh key with a new one instead of starting a session. The old key is backed up to ~/.devpod/keys")
+	sshCmd.Flags().BoolVar(&cmd.ReinjectKey, "reinject-key", false, "If true, --rotate-key replaces the old key with the new one in the authorized_keys of every running workspace")
+	sshCmd.Flags().StringVar(&cmd.AuditLog, "audit-log", "", "If set will append a json record with the parameters and result of the connection to the given file")
+	sshCmd.Flags().StringVar(&cmd.RCCommand, "rc-command", "", "A command to execute via sh in a separate session before the interactive shell starts. Only the environment variables it exports carry over into the shell, aliases, functions and the working directory don't. Use . instead of source, sh might not be bash")
+	sshCmd.Flags().StringArrayVar(&cmd.Env, "env", []string{}, "An environment variable in the form KEY=VALUE to set in the session, overrides variables from --env-file")
+	sshCmd.Flags().StringVar(&cmd.EnvFile, "env-file", "", "A local dotenv file with environment variables to set in the session")
+	sshCmd.Flags().BoolVar(&cmd.EnvPassthrough, "env-passthrough-all", false, "If true will forward the whole local environment into the session, except host specific and unsafe variables and variables that look like secrets, e.g. AWS_*, GITHUB_TOKEN or *_PASSWORD. This leaks all other local secrets into the workspace, only use it for trusted workspaces")
+	sshCmd.Flags().BoolVar(&cmd.Unsafe, "unsafe", false, "If true, --env-passthrough-all also forwards variables like LD_PRELOAD that change what programs in the workspace execute")
+	sshCmd.Flags().StringVar(&cmd.Template, "template", "", "The name of a connection template whose flags are used for this session, flags on the command line take precedence")
+	sshCmd.Flags().StringVar(&cmd.Profile, "profile", "", "The profile from customizations.devpod.profiles of the devcontainer.json whose environment variables and rc command to use for the session")
+	sshCmd.Flags().StringVar(&cmd.User, "user", "", "The user of the workspace to use, defaults to the remote user of the dev container")
+	sshCmd.Flags().StringArrayVar(&cmd.CredentialUsers, "credential-user", []string{}, "A container user to configure the git and docker credential helpers for, can be specified multiple times. Defaults to --user")
+	sshCmd.Flags().StringVar(&cmd.HelperPath, "helper-path", "", "The absolute path of the devpod binary in the container the git and docker credential helpers should invoke. Defaults to "+agent.ContainerDevPodHelperLocation)
+	sshCmd.Flags().StringVar(&cmd.RuntimeDir, "runtime-dir", "", "The folder in the container for the credentials socket, which is created with permissions 0700 and owned by the container user. Defaults to ~/.devpod/run of the container user")
+	sshCmd.Flags().StringArrayVar(&cmd.ProviderOptions, "provider-option", []string{}, "Provider option in the form KEY=VALUE that overrides the configured provider option for this session only")
+	sshCmd.Flags().StringVar(&cmd.Record, "record", "", "If set will record the session in the asciinema v2 format to the given file, e.g. session.cast")
+	sshCmd.Flags().BoolVar(&cmd.RecordStdin, "record-stdin", false, "If true will also record the input of the session with --record, which might include secrets typed into the session")
+	sshCmd.Flags().StringVar(&cmd.OutputFile, "output-file", "", "If set will write the stdout of --command to the given local file instead of stdout")
+	sshCmd.Flags().StringVar(&cmd.ErrorFile, "error-file", "", "If set will write the stderr of --command to the given local file instead of stderr")
+	sshCmd.Flags().BoolVar(&cmd.AppendOutput, "append-output", false, "If true will append to --output-file and --error-file instead of truncating them")
+	sshCmd.Flags().BoolVar(&cmd.OutputMetadata, "output-metadata", false, "If true will add a footer with the exit code and the start and finish time to --output-file and --error-file")
 	sshCmd.Flags().BoolVar(&cmd.Proxy, "proxy", false, "If true will act as intermediate proxy for a proxy provider")
 	sshCmd.Flags().BoolVar(&cmd.AgentForwarding, "agent-forwarding", true, "If true forward the local ssh keys to the remote machine")
+	sshCmd.Flags().StringVar(&cmd.AuthSock, "auth-sock", "", "The socket of the local ssh agent to forward, overrides SSH_AUTH_SOCK")
+	sshCmd.Flags().StringSliceVar(&cmd.AgentKeys, "forward-agent-keys", []string{}, "If set only the keys of the local ssh agent with these SHA256 fingerprints are forwarded, e.g. SHA256:abc...")
+	sshCmd.Flags().StringArrayVar(&cmd.SyncDown, "sync-down", []string{}, "Copy a path from the workspace to the local machine before the session starts, e.g. /workspace/build:./build")
+	sshCmd.Flags().StringArrayVar(&cmd.SyncUp, "sync-up", []string{}, "Copy a local path to the workspace before the session starts, e.g. /workspace/data:./data")
+	sshCmd.Flags().BoolVar(&cmd.SyncOnly, "sync-only", false, "If true will exit after --sync-down and --sync-up instead of starting a session")
 	sshCmd.Flags().BoolVar(&cmd.Stdio, "stdio", false, "If true will tunnel connection through stdout and stdin")
+	sshCmd.Flags().StringVar(&cmd.WSAddr, "ws-addr", "", "If set will serve the session as terminal over a WebSocket on this localhost address, e.g. localhost:8022, instead of the local terminal")
+	sshCmd.Flags().StringArrayVar(&cmd.WSOrigins, "ws-origin", []string{}, "An origin of a web page that may connect to the terminal of --ws-addr, e.g. http://localhost:3000. By default browsers can only connect from the origin of --ws-addr itself")
+	sshCmd.Flags().BoolVar(&cmd.Daemon, "daemon", false, "If true will keep a named connection to the workspace open in the background, sessions attach to it via --attach")
+	sshCmd.Flags().StringVar(&cmd.Name, "name", "", "The name of the daemon started via --daemon")
+	sshCmd.Flags().StringVar(&cmd.Attach, "attach", "", "The name of a daemon to start the session on instead of connecting to a workspace")
+	sshCmd.Flags().BoolVar(&cmd.ListDaemons, "list-daemons", false, "If true will list the running daemons")
+	sshCmd.Flags().StringVar(&cmd.Stop, "stop", "", "The name of a daemon to stop")
+	sshCmd.Flags().BoolVar(&cmd.DaemonChild, "daemon-child", false, "If true will run as the background process of --daemon")
+	_ = sshCmd.Flags().MarkHidden("daemon-child")
+	sshCmd.Flags().BoolVar(&cmd.RawAgent, "raw-agent", false, fmt.Sprintf("If true will connect stdout and stdin directly to the agent in the workspace without an ssh session, for integrations that implement the agent protocol version %d", tunnel.RawAgentProtocolVersion))
+	sshCmd.Flags().BoolVar(&cmd.TunnelOnly, "tunnel-only", false, "If true will bridge stdout and stdin to the --tunnel-target within the workspace without running a shell")
+	sshCmd.Flags().StringVar(&cmd.TunnelTarget, "tunnel-target", "", "The address within the workspace to connect to in --tunnel-only mode, e.g. localhost:5432")
+	sshCmd.Flags().BoolVar(&cmd.NoStdin, "no-stdin", false, "If true will not forward stdin to the remote command, which is equivalent to redirecting it from /dev/null")
+	sshCmd.Flags().BoolVar(&cmd.IgnoreExit, "ignore-exit", false, "If true an interactive session always exits with 0 instead of the exit status of the remote shell")
+	sshCmd.Flags().BoolVar(&cmd.NoEcho, "no-echo", false, "If true the input of an interactive session is not echoed to the terminal, e.g. to enter secrets")
+	sshCmd.Flags().StringVar(&cmd.Transport, "transport", transportAuto, fmt.Sprintf("The transport used to connect to the workspace, one of: %s", strings.Join(transports, ", ")))
+	sshCmd.Flags().StringVar(&cmd.LogDestination, "log-destination", logDestinationStderr, "Where to write the DevPod logs to, either stderr, stdout or a file path. The output of the remote command is not affected")
+	sshCmd.Flags().BoolVar(&cmd.JSONErrors, "json-errors", false, "If true will print errors as json to stderr")
+	sshCmd.Flags().BoolVar(&cmd.Restricted, "restricted", false, "If true will start a restricted session that only allows the commands from --restricted-command or the workspace config. The ssh server in the workspace enforces the restriction for this session, but it is not a security boundary, anyone with regular access to the workspace can bypass it")
+	sshCmd.Flags().StringArrayVar(&cmd.RestrictedCommands, "restricted-command", []string{}, "A command that is allowed within a restricted session")
+	sshCmd.Flags().StringVar(&cmd.KeepAliveActivity, "keep-alive-activity", "", "If set, will mark the workspace as active in the given interval, e.g. 5m, to prevent the idle auto-stop")
+	sshCmd.Flags().BoolVar(&cmd.WaitForIDE, "wait-for-ide", false, "If enabled, waits until the IDE of the workspace is installed before connecting")
+	sshCmd.Flags().StringVar(&cmd.WaitForIDETimeout, "wait-for-ide-timeout", "5m", "The maximum time to wait for the IDE with --wait-for-ide")
+	sshCmd.Flags().BoolVar(&cmd.WaitMounts, "wait-mounts", false, "If enabled, waits until the mounts of the workspace exist in the container before connecting")
+	sshCmd.Flags().StringArrayVar(&cmd.WaitMountPaths, "wait-mount-path", []string{}, "A path within the container to wait for with --wait-mounts, defaults to the workspace folder and the bind mounts of the dev container")
+	sshCmd.Flags().StringVar(&cmd.WaitMountsTimeout, "wait-mounts-timeout", "1m", "The maximum time to wait for the mounts with --wait-mounts")
+	sshCmd.Flags().StringVar(&cmd.IdleTimeout, "idle-timeout", "", "If set, requests the workspace to stop after this inactivity duration once disconnected, e.g. 30m. A shorter timeout configured by the provider takes precedence")
+	sshCmd.Flags().StringVar(&cmd.BannerTimeout, "banner-timeout", "30s", "How long to wait for the ssh server in the workspace to complete the handshake, 0 waits forever")
+	sshCmd.Flags().BoolVar(&cmd.VerboseSSH, "verbose-ssh", false, "If true will log the banner of the ssh server in the workspace")
+	sshCmd.Flags().StringVar(&cmd.IdleDisconnect, "idle-disconnect", "", "If set, closes the interactive session after this duration without input, e.g. 30m, and exits with code 124. Commands run via --command are not affected")
+	sshCmd.Flags().IntVar(&cmd.MaxChannels, "max-channels", 0, "The maximum number of concurrent forwarded connections of this session, further connections wait for a free channel. 0 disables the limit")
+	sshCmd.Flags().IntVar(&cmd.MaxSessions, "max-sessions", 100, "The maximum number of active devpod ssh sessions, new sessions beyond that are refused. 0 disables the limit")
+	sshCmd.Flags().BoolVar(&cmd.ProxyCommand, "proxy-command", false, "If true will print the ProxyCommand an editor or ssh client should use to connect to the workspace instead of connecting")
+	sshCmd.Flags().BoolVar(&cmd.PrintSSHCommand, "print-ssh-command", false, "If true will print an equivalent ssh command for OpenSSH instead of connecting, e.g. to debug the connection with -vvv")
+	sshCmd.Flags().StringVar(&cmd.FromSnapshot, "from-snapshot", "", "If set will connect to a temporary copy of the workspace that is created from this provider snapshot and deleted on exit. Requires a provider that supports snapshots")
+	sshCmd.Flags().BoolVar(&cmd.Keep, "keep", false, "If true will keep the temporary workspace of --from-snapshot instead of deleting it on exit")
+	sshCmd.Flags().BoolVar(&cmd.Last, "last", false, "If true will connect to the most recently used workspace")
+	sshCmd.Flags().BoolVar(&cmd.NoPicker, "no-picker", false, "If true will fail instead of showing a list of workspaces to select from when no workspace is specified and the current directory isn't linked to one")
+	sshCmd.Flags().BoolVar(&cmd.Exact, "exact", false, "If true the workspace argument must be the full workspace name, otherwise a unique workspace starting with or containing it is used")
+	sshCmd.Flags().BoolVar(&cmd.History, "history", false, "If true will print the connection history of the workspace instead of connecting. Without a workspace the history of all workspaces is printed")
+	sshCmd.Flags().StringVar(&cmd.Since, "since", "", "Only prints history entries newer than this duration, e.g. 24h, or RFC3339 timestamp, e.g. 2024-01-02T15:04:05Z. Requires --history")
+	sshCmd.Flags().StringVar(&cmd.Workspace, "workspace", "", "Only prints the history of this workspace. Requires --history")
+	sshCmd.Flags().BoolVar(&cmd.Start, "start", false, "If true will start or create the workspace if it is stopped or doesn't exist. An interrupted creation is resumed by running the command again")
+	sshCmd.Flags().BoolVar(&cmd.AutoInstallAgent, "auto-install-agent", true, "If true will install the DevPod agent on the workspace host if it is missing or doesn't match the version of the CLI")
+	sshCmd.Flags().BoolVar(&cmd.Preflight, "preflight", true, "If true will validate the credentials and connectivity of the provider before starting the workspace, if the provider supports it")
+	sshCmd.Flags().BoolVar(&cmd.NoPreflight, "no-preflight", false, "If true will skip the provider pre-flight check, same as --preflight=false")
+	sshCmd.Flags().BoolVar(&cmd.NoCaptiveCheck, "no-captive-check", false, "If true will not probe for a captive portal to explain a failing connection")
+	sshCmd.Flags().BoolVar(&cmd.StrictVersion, "strict-version", false, "If true will fail if the version of the DevPod agent in the workspace doesn't exactly match the version of the CLI instead of printing a warning")
+	sshCmd.Flags().StringVar(&cmd.BusyWarning, "busy-warning", defaultBusyWarning.String(), "Prints a warning if the workspace stays busy longer than this duration while waiting for it, 0 disables the warning")
+	sshCmd.Flags().StringVar(&cmd.WaitTimeout, "wait-timeout", "0", "The maximum time to wait for the workspace to come up, including retries of a rate limited provider, 0 waits without limit")
+	sshCmd.Flags().StringVar(&cmd.PollInterval, "poll-interval", workspace2.DefaultPollInterval().Base.String(), "The interval of the first status polls while waiting for a busy workspace, afterwards the interval grows up to --poll-max-interval")
+	sshCmd.Flags().StringVar(&cmd.PollMaxInterval, "poll-max-interval", workspace2.DefaultPollInterval().Max.String(), "The maximum interval between status polls while waiting for a busy workspace")
+	sshCmd.Flags().Float64Var(&cmd.PollJitter, "poll-jitter", workspace2.DefaultPollInterval().Jitter, "The fraction every status poll interval is randomized by, e.g. 0.25 for ±25%, so many clients don't poll the provider in lockstep. 0 disables the jitter")
 	sshCmd.Flags().BoolVar(&cmd.StartServices, "start-services", true, "If false will not start any port-forwarding or git / docker credentials helper")
 	return sshCmd
 }
 
 // Run runs the command logic
 func (cmd *SSHCmd) Run(ctx context.Context, devPodConfig *config.Config, client client2.BaseWorkspaceClient, log log.Logger) error {
-	// add ssh keys to agent
-	if !cmd.Proxy && devPodConfig.ContextOption(config.ContextOptionSSHAgentForwarding) == "true" && devPodConfig.ContextOption(config.ContextOptionSSHAddPrivateKeys) == "true" {
+	if cmd.NoStdin && (cmd.Stdio || cmd.Proxy) {
+		return fmt.Errorf("--no-stdin cannot be used together with --stdio or --proxy")
+	}
+	if cmd.IgnoreExit && (len(cmd.Commands) > 0 || cmd.Edit != "" || cmd.Stdio || cmd.Proxy || cmd.TunnelOnly) {
+		return fmt.Errorf("--ignore-exit can only be used for interactive sessions")
+	}
+	if cmd.NoEcho && (cmd.NoStdin || cmd.Stdio || cmd.Proxy) {
+		return fmt.Errorf("--no-echo cannot be used together with --no-stdin, --stdio or --proxy")
+	}
+	if cmd.Transport != transportAuto && cmd.Transport != transportStdio {
+		return fmt.Errorf("unsupported transport %s, please use one of: %s", cmd.Transport, strings.Join(transports, ", "))
+	}
+	if cmd.RawAgent && (len(cmd.Commands) > 0 || cmd.Stdio || cmd.Proxy || cmd.TunnelOnly || len(cmd.ForwardPorts) > 0 || cmd.ForwardsFile != "" || cmd.Edit != "" || cmd.Sudo || cmd.Restricted) {
+		return fmt.Errorf("--raw-agent cannot be used together with --command, --stdio, --proxy, --tunnel-only, port forwarding, --edit, --sudo or --restricted")
+	}
+	if cmd.WSAddr != "" {
+		if len(cmd.Commands) > 1 || cmd.Stdio || cmd.Proxy || cmd.TunnelOnly || cmd.RawAgent || len(cmd.ForwardPorts) > 0 || cmd.ForwardsFile != "" || cmd.Record != "" {
+			return fmt.Errorf("--ws-addr cannot be used together with multiple commands, --stdio, --proxy, --tunnel-only, --raw-agent, port forwarding or --record")
+		}
+
+		address, err := devssh.ParseWebTerminalAddress(cmd.WSAddr)
+		if err != nil {
+			return err
+		}
+		cmd.WSAddr = address
+	} else if len(cmd.WSOrigins) > 0 {
+		return fmt.Errorf("--ws-origin can only be used together with --ws-addr")
+	}
+	if len(cmd.SyncDown) > 0 || len(cmd.SyncUp) > 0 {
+		if cmd.Stdio || cmd.Proxy || cmd.TunnelOnly || cmd.RawAgent || cmd.WSAddr != "" || cmd.DaemonChild || cmd.Restricted || len(cmd.ForwardPorts) > 0 || cmd.ForwardsFile != "" {
+			return fmt.Errorf("--sync-down and --sync-up cannot be used together with --stdio, --proxy, --tunnel-only, --raw-agent, --ws-addr, --daemon, --restricted or port forwarding")
+		}
+
+		err := cmd.parseSyncs()
+		if err != nil {
+			return err
+		}
+	} else if cmd.SyncOnly {
+		return fmt.Errorf("--sync-only can only be used together with --sync-down or --sync-up")
+	}
+	if cmd.TunnelOnly && cmd.TunnelTarget == "" {
+		return fmt.Errorf("--tunnel-target is required in --tunnel-only mode")
+	}
+	if cmd.Record != "" && (cmd.Stdio || cmd.Proxy || cmd.TunnelOnly || len(cmd.Commands) > 1) {
+		return fmt.Errorf("--record can only be used for a single interactive session or command")
+	}
+	if (len(cmd.Env) > 0 || cmd.EnvFile != "" || cmd.EnvPassthrough) && (cmd.Stdio || cmd.Proxy || cmd.TunnelOnly) {
+		return fmt.Errorf("--env, --env-file and --env-passthrough-all cannot be used together with --stdio, --proxy or --tunnel-only")
+	}
+	if cmd.Unsafe && !cmd.EnvPassthrough {
+		return fmt.Errorf("--unsafe can only be used together with --env-passthrough-all")
+	}
+	if len(cmd.Asserts) > 0 && (cmd.Stdio || cmd.Proxy || cmd.TunnelOnly) {
+		return fmt.Errorf("--assert cannot be used together with --stdio, --proxy or --tunnel-only")
+	} else if cmd.AssertAll && len(cmd.Asserts) == 0 {
+		return fmt.Errorf("--assert-all can only be used together with --assert")
+	}
+	if cmd.Profile != "" && (cmd.Stdio || cmd.Proxy || cmd.TunnelOnly) {
+		return fmt.Errorf("--profile cannot be used together with --stdio, --proxy or --tunnel-only")
+	}
+	env, err := cmd.sessionEnv(log)
+	if err != nil {
+		return err
+	}
+	cmd.env = env
+	if cmd.Benchmark && (cmd.Stdio || cmd.Proxy || cmd.TunnelOnly) {
+		return fmt.Errorf("--benchmark cannot be used together with --stdio, --proxy or --tunnel-only")
+	} else if cmd.Benchmark && (cmd.BenchmarkIterations <= 0 || cmd.BenchmarkSize <= 0) {
+		return fmt.Errorf("--benchmark-iterations and --benchmark-size need to be greater than zero")
+	}
+	if cmd.MaxChannels < 0 {
+		return fmt.Errorf("--max-channels cannot be negative")
+	}
+	cmd.channelLimiter = devssh.NewChannelLimiter(cmd.MaxChannels)
+	err = cmd.parseForwards()
+	if err != nil {
+		return err
+	}
+	if cmd.CopyID != "" && (cmd.Stdio || cmd.Proxy || cmd.TunnelOnly || cmd.Benchmark || len(cmd.Commands) > 0) {
+		return fmt.Errorf("--copy-id cannot be used together with --stdio, --proxy, --tunnel-only, --benchmark or --command")
+	}
+	if cmd.Clipboard && (cmd.Stdio || cmd.Proxy || cmd.TunnelOnly) {
+		return fmt.Errorf("--clipboard cannot be used together with --stdio, --proxy or --tunnel-only")
+	}
+	if cmd.Cleanup && (cmd.Stdio || cmd.Proxy || cmd.TunnelOnly || cmd.Benchmark || cmd.CopyID != "" || len(cmd.Commands) > 0) {
+		return fmt.Errorf("--cleanup cannot be used together with --stdio, --proxy, --tunnel-only, --benchmark, --copy-id or --command")
+	}
+	if len(cmd.MapPaths) > 0 {
+		if len(cmd.Commands) == 0 {
+			return fmt.Errorf("--map-path can only be used together with --command")
+		}
+
+		err := cmd.mapPaths(log)
+		if err != nil {
+			return err
+		}
+	}
+	if cmd.Edit != "" && (len(cmd.Commands) > 0 || cmd.Stdio || cmd.Proxy || cmd.TunnelOnly || cmd.Restricted || cmd.Tmux || cmd.Benchmark || cmd.CopyID != "" || cmd.Cleanup) {
+		return fmt.Errorf("--edit cannot be used together with --command, --stdio, --proxy, --tunnel-only, --restricted, --tmux, --benchmark, --copy-id or --cleanup")
+	} else if cmd.EditWorkdir != "" && cmd.Edit == "" {
+		return fmt.Errorf("--edit-workdir can only be used together with --edit")
+	}
+	if cmd.Tmux {
+		if len(cmd.Commands) > 0 || cmd.RCCommand != "" || cmd.Stdio || cmd.Proxy || cmd.TunnelOnly || cmd.Restricted {
+			return fmt.Errorf("--tmux can only be used for interactive sessions without --rc-command or --restricted")
+		}
+
+		cmd.tmuxSession = "devpod-" + client.Workspace()
+	}
+	cmd.workspace = client.Workspace()
+	if cmd.Sudo && (cmd.Stdio || cmd.Proxy || cmd.TunnelOnly || cmd.Restricted || cmd.Tmux || cmd.Benchmark || cmd.CopyID != "" || cmd.Cleanup) {
+		return fmt.Errorf("--sudo cannot be used together with --stdio, --proxy, --tunnel-only, --restricted, --tmux, --benchmark, --copy-id or --cleanup")
+	}
+	if len(cmd.CredentialUsers) > 0 && (cmd.Proxy || cmd.Restricted || !cmd.StartServices) {
+		return fmt.Errorf("--credential-user cannot be used together with --proxy, --restricted or --start-services=false")
+	}
+	if cmd.RuntimeDir != "" && (cmd.Proxy || cmd.Restricted || !cmd.StartServices) {
+		return fmt.Errorf("--runtime-dir cannot be used together with --proxy, --restricted or --start-services=false")
+	} else if cmd.RuntimeDir != "" && len(cmd.CredentialUsers) > 1 {
+		return fmt.Errorf("--runtime-dir can only be used with a single credential user")
+	}
+	if cmd.HelperPath != "" && (cmd.Proxy || cmd.Restricted || !cmd.StartServices) {
+		return fmt.Errorf("--helper-path cannot be used together with --proxy, --restricted or --start-services=false")
+	} else if cmd.HelperPath != "" && !path.IsAbs(cmd.HelperPath) {
+		return fmt.Errorf("--helper-path %s needs to be an absolute path in the container", cmd.HelperPath)
+	}
+	if cmd.ForwardDocker && (cmd.Proxy || cmd.Restricted) {
+		return fmt.Errorf("--forward-docker cannot be used together with --proxy or --restricted")
+	}
+	if cmd.KeepContainerRunning != "" {
+		if cmd.KeepContainerRunning != "true" && cmd.KeepContainerRunning != "false" {
+			return fmt.Errorf("invalid --keep-container-running value %s, please use true or false", cmd.KeepContainerRunning)
+		} else if cmd.Proxy {
+			return fmt.Errorf("--keep-container-running cannot be used together with --proxy")
+		}
+	}
+	if cmd.Setup != "" {
+		if cmd.Setup != "true" && cmd.Setup != "false" && cmd.Setup != "force" {
+			return fmt.Errorf("invalid --setup value %s, please use true, false or force", cmd.Setup)
+		} else if cmd.Setup != "false" && (cmd.Proxy || cmd.Stdio || cmd.TunnelOnly || cmd.Restricted) {
+			return fmt.Errorf("--setup cannot be used together with --proxy, --stdio, --tunnel-only or --restricted")
+		}
+	}
+	if cmd.ConnectRetries < 0 {
+		return fmt.Errorf("--connect-retries cannot be negative")
+	} else if len(cmd.RetryOn) > 0 && cmd.ConnectRetries == 0 {
+		return fmt.Errorf("--retry-on can only be used together with --connect-retries")
+	}
+	if cmd.Exec {
+		if runtime.GOOS == "windows" {
+			return fmt.Errorf("--exec is not supported on Windows")
+		} else if cmd.Proxy || cmd.Stdio || cmd.TunnelOnly || len(cmd.Commands) > 1 {
+			return fmt.Errorf("--exec cannot be used together with --proxy, --stdio, --tunnel-only or multiple --command")
+		}
+	}
+	if cmd.ForwardGPG {
+		if cmd.Proxy || cmd.Restricted || cmd.Stdio || cmd.TunnelOnly || len(cmd.Commands) > 1 {
+			return fmt.Errorf("--forward-gpg cannot be used together with --proxy, --restricted, --stdio, --tunnel-only or multiple --command")
+		}
+
+		var err error
+		cmd.gpgAgentSocket, err = devssh.LocalGPGAgentSocket()
+		if err != nil {
+			return err
+		}
+	}
+	if (cmd.OutputFile != "" || cmd.ErrorFile != "") && (len(cmd.Commands) == 0 || cmd.Stdio || cmd.Proxy || cmd.TunnelOnly) {
+		return fmt.Errorf("--output-file and --error-file can only be used together with --command")
+	}
+	if cmd.CPULimit != "" || cmd.MemoryLimit != "" {
+		if len(cmd.Commands) == 0 || cmd.Stdio || cmd.Proxy || cmd.TunnelOnly || cmd.Restricted {
+			return fmt.Errorf("--cpu-limit and --memory-limit can only be used together with --command and without --restricted")
+		}
+		if cmd.CPULimit != "" {
+			cmd.limits.CPUs, err = devssh.ParseCPUs(cmd.CPULimit)
+			if err != nil {
+				return err
+			}
+		}
+		if cmd.MemoryLimit != "" {
+			cmd.limits.Memory, err = devssh.ParseMemory(cmd.MemoryLimit)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if cmd.IdleTimeout != "" {
+		idleTimeout, err := time.ParseDuration(cmd.IdleTimeout)
+		if err != nil {
+			return errors.Wrap(err, "parse idle timeout")
+		} else if idleTimeout <= 0 {
+			return fmt.Errorf("idle timeout needs to be greater than zero")
+		}
+	}
+	if cmd.IdleDisconnect != "" {
+		cmd.idleDisconnect, err = time.ParseDuration(cmd.IdleDisconnect)
+		if err != nil {
+			return errors.Wrap(err, "parse idle disconnect")
+		} else if cmd.idleDisconnect <= 0 {
+			return fmt.Errorf("idle disconnect needs to be greater than zero")
+		}
+	}
+
+	if cmd.Keep && cmd.FromSnapshot == "" {
+		return fmt.Errorf("--keep can only be used together with --from-snapshot")
+	}
+	if cmd.FromSnapshot != "" {
+		if cmd.Stdio || cmd.Proxy {
+			return fmt.Errorf("--from-snapshot cannot be used together with --stdio or --proxy")
+		}
+
+		snapshotClient, err := workspace2.CloneFromSnapshot(ctx, devPodConfig, client, cmd.FromSnapshot, log)
+		if err != nil {
+			return errors.Wrap(err, "create workspace from snapshot")
+		}
+		if cmd.Keep {
+			defer log.Infof("Kept workspace '%s' created from snapshot '%s', delete it via 'devpod delete %s'", snapshotClient.Workspace(), cmd.FromSnapshot, snapshotClient.Workspace())
+		} else {
+			defer func() {
+				log.Infof("Deleting workspace '%s' created from snapshot '%s'...", snapshotClient.Workspace(), cmd.FromSnapshot)
+				err := snapshotClient.Delete(context.Background(), client2.DeleteOptions{Force: true})
+				if err != nil {
+					log.Errorf("Error deleting workspace '%s', delete it via 'devpod delete %s': %v", snapshotClient.Workspace(), snapshotClient.Workspace(), err)
+				}
+			}()
+		}
+
+		client = snapshotClient
+	}
+
+	// use the given ssh agent instead of SSH_AUTH_SOCK
+	if cmd.AuthSock != "" {
+		err := checkAuthSock(cmd.AuthSock, cmd.AgentForwarding)
+		if err != nil {
+			return err
+		}
+
+		_ = os.Setenv("SSH_AUTH_SOCK", cmd.AuthSock)
+	}
+
+	// only expose the selected keys of the agent
+	if len(cmd.AgentKeys) > 0 {
+		if !cmd.AgentForwarding {
+			return fmt.Errorf("--forward-agent-keys requires --agent-forwarding")
+		}
+
+		agentKeys, err := devssh.ParseAgentKeyFingerprints(cmd.AgentKeys)
+		if err != nil {
+			return fmt.Errorf("parse --forward-agent-keys: %w", err)
+		}
+		cmd.AgentKeys = agentKeys
+	}
+
+	// add ssh keys to agent, ssh-add might ask for the passphrase of a key
+	if cmd.NonInteractive {
+		log.Debug("Skip adding ssh keys to agent in non-interactive mode")
+	} else if !cmd.Proxy && devPodConfig.ContextOption(config.ContextOptionSSHAgentForwarding) == "true" && devPodConfig.ContextOption(config.ContextOptionSSHAddPrivateKeys) == "true" {
 		log.Debug("Adding ssh keys to agent, disable via 'devpod context set-options -o SSH_ADD_PRIVATE_KEYS=false'")
 		err := devssh.AddPrivateKeysToAgent(ctx, log)
 		if err != nil {
@@ -89,15 +777,113 @@ func (cmd *SSHCmd) Run(ctx context.Context, devPodConfig *config.Config, client
 		}
 	}
 
-	// get user
+	// get user, if none is configured we use the remote user of the dev container
 	if cmd.User == "" {
 		var err error
-		cmd.User, err = devssh.GetUser(client.Workspace())
+		cmd.User, err = devssh.GetConfiguredUser(client.Workspace())
 		if err != nil {
 			return err
 		}
 	}
 
+	// resolve allowed commands
+	if cmd.Restricted {
+		if len(cmd.RestrictedCommands) == 0 {
+			cmd.RestrictedCommands = client.WorkspaceConfig().SSH.RestrictedCommands
+		}
+		if len(cmd.RestrictedCommands) == 0 {
+			return fmt.Errorf("no allowed commands for restricted session, please specify them via --restricted-command or the workspace config")
+		}
+	}
+
+	// check for leaked sessions
+	unregisterSession, err := cmd.registerSession(client, log)
+	if err != nil {
+		return err
+	}
+	defer unregisterSession()
+
+	// connect to the workspace
+	start := time.Now()
+	if cmd.AuditLog != "" {
+		cmd.audit = devssh.NewAuditLog(cmd.AuditLog, client.Workspace(), cmd.User)
+	}
+	err = cmd.connect(ctx, devPodConfig, client, log)
+	recordConnectionHistory(client, cmd.User, start, err, log)
+	if cmd.audit != nil {
+		auditErr := cmd.audit.Write(err)
+		if auditErr != nil {
+			log.Errorf("Error writing audit log: %v", auditErr)
+		}
+	}
+	return err
+}
+
+// applyConnectionURL applies a devpod:// connection url to the flags and returns the args with the
+// url replaced by the workspace. Commands of the url run before the ones from --command and
+// variables from --env take precedence over the ones from the url.
+func (cmd *SSHCmd) applyConnectionURL(args []string) ([]string, error) {
+	connectionURL, err := devssh.ParseConnectionURL(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if connectionURL.User != "" {
+		if cmd.User != "" && cmd.User != connectionURL.User {
+			return nil, fmt.Errorf("user %s of the connection url conflicts with --user %s", connectionURL.User, cmd.User)
+		}
+
+		cmd.User = connectionURL.User
+	}
+	cmd.Commands = append(connectionURL.Commands, cmd.Commands...)
+	cmd.Env = append(connectionURL.Env, cmd.Env...)
+	return append([]string{connectionURL.Workspace}, args[1:]...), nil
+}
+
+// checkAuthSock verifies that the --auth-sock exists and is a socket
+func checkAuthSock(authSock string, agentForwarding bool) error {
+	if !agentForwarding {
+		return fmt.Errorf("--auth-sock cannot be used together with --agent-forwarding=false")
+	}
+
+	stat, err := os.Stat(authSock)
+	if err != nil {
+		return fmt.Errorf("ssh agent socket %s: %w", authSock, err)
+	} else if runtime.GOOS != "windows" && stat.Mode()&os.ModeSocket == 0 {
+		// windows agents usually listen on named pipes
+		return fmt.Errorf("ssh agent socket %s is not a socket", authSock)
+	}
+
+	return nil
+}
+
+// registerSession registers this session and refuses it if there are already more
+// than --max-sessions active sessions
+func (cmd *SSHCmd) registerSession(client client2.BaseWorkspaceClient, log log.Logger) (func(), error) {
+	if cmd.MaxSessions > 0 {
+		activeSessions, err := provider2.CountActiveSessions(client.Context())
+		if err != nil {
+			log.Debugf("Error counting active sessions: %v", err)
+		} else if activeSessions >= cmd.MaxSessions {
+			return nil, fmt.Errorf("there are already %d active devpod ssh sessions, which might be leaked background tunnels. Please close unused sessions or raise the limit via --max-sessions", activeSessions)
+		} else if activeSessions >= cmd.MaxSessions*8/10 {
+			log.Warnf("There are %d active devpod ssh sessions, new sessions will be refused at %d", activeSessions, cmd.MaxSessions)
+		}
+	}
+
+	unregisterSession, err := provider2.RegisterSession(client.Context(), client.Workspace())
+	if err != nil {
+		log.Debugf("Error registering session: %v", err)
+		return func() {}, nil
+	}
+
+	return unregisterSession, nil
+}
+
+func (cmd *SSHCmd) connect(ctx context.Context, devPodConfig *config.Config, client client2.BaseWorkspaceClient, log log.Logger) error {
+	// all workspaces currently use the stdio transport
+	log.Debugf("Using %s transport (requested %s)", transportStdio, cmd.Transport)
+
 	// check if regular workspace client
 	workspaceClient, ok := client.(client2.WorkspaceClient)
 	if ok {
@@ -115,6 +901,10 @@ func (cmd *SSHCmd) Run(ctx context.Context, devPodConfig *config.Config, client
 
 func (cmd *SSHCmd) startProxyTunnel(ctx context.Context, devPodConfig *config.Config, client client2.ProxyClient, log log.Logger) error {
 	log.Debugf("Start proxy tunnel")
+	if cmd.User == "" {
+		cmd.User = "root"
+	}
+
 	return tunnel.NewTunnel(
 		ctx,
 		func(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
@@ -129,37 +919,155 @@ func (cmd *SSHCmd) startProxyTunnel(ctx context.Context, devPodConfig *config.Co
 	)
 }
 
-func startWait(ctx context.Context, client client2.WorkspaceClient, create bool, log log.Logger) error {
+// defaultBusyWarning is the default time a workspace can stay busy before a warning is printed
+const defaultBusyWarning = 5 * time.Minute
+
+// defaultRateLimitTimeout caps the retries of a rate limited provider without a wait timeout
+const defaultRateLimitTimeout = 10 * time.Minute
+
+// startWait waits until the workspace is running and creates or starts it if create is true. If the
+// workspace stays busy longer than busyWarning, a warning is printed, 0 disables the warning. The
+// status is polled with the given interval. If the provider reports a retryable error, e.g. a
+// rate limit, the status or start is retried. waitTimeout caps the whole wait, 0 waits without
+// limit and only caps the retries at defaultRateLimitTimeout.
+func startWait(ctx context.Context, client client2.WorkspaceClient, create, preflight bool, busyWarning time.Duration, poll workspace2.PollInterval, waitTimeout time.Duration, log log.Logger) error {
+	// allow to abort a long running creation via ctrl-c, rerunning will continue
+	// with the workspace in whatever state the provider left it
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	started := time.Now()
+	retryTimeout := waitTimeout
+	if retryTimeout <= 0 {
+		retryTimeout = defaultRateLimitTimeout
+	}
+	retries := 0
+	retryRateLimited := func(err error) error {
+		var retryable *client2.RetryableError
+		if !errors.As(err, &retryable) {
+			return err
+		}
+
+		delay := retryable.RetryAfter
+		if delay <= 0 {
+			delay = rateLimitBackoff(retries)
+		}
+		if time.Since(started)+delay > retryTimeout {
+			return fmt.Errorf("provider is still rate limited after %s: %w", time.Since(started).Round(time.Second), err)
+		}
+
+		retries++
+		log.Warnf("Provider is rate limited, retrying in %s", delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		return nil
+	}
+
 	startWaiting := time.Now()
-	for {
+	busySince := time.Now()
+	nextBusyWarning := busyWarning
+	reporter, _ := client.(client2.ProgressReporter)
+	lastProgress := client2.StatusProgress{Percent: -1}
+	step := 0
+
+	// validate the provider credentials before the workspace is started or created, expired
+	// credentials otherwise fail deep within the start. A running workspace doesn't need it.
+	runPreflight := func() error {
+		if !preflight {
+			return nil
+		}
+
+		preflight = false
+		return client.Preflight(ctx)
+	}
+	for polls := 0; ; polls++ {
+		_, statusSpan := tracing.StartSpan(ctx, "status")
 		instanceStatus, err := client.Status(ctx, client2.StatusOptions{})
+		statusSpan.SetAttributes(attribute.String("devpod.status", string(instanceStatus)))
+		tracing.EndSpan(statusSpan, err)
 		if err != nil {
-			return err
-		} else if instanceStatus == client2.StatusBusy {
-			if time.Since(startWaiting) > time.Second*10 {
-				log.Infof("Waiting for workspace to come up...")
-				log.Debugf("Got status %s, expected: Running", instanceStatus)
-				startWaiting = time.Now()
+			err = retryRateLimited(err)
+			if err == nil {
+				continue
 			}
 
-			time.Sleep(time.Second * 2)
-			continue
+			return canceledOr(ctx, err)
+		} else if instanceStatus == client2.StatusBusy {
+			// show the phases of providers that report them
+			progress := client2.StatusProgress{Percent: -1}
+			if reporter != nil {
+				progress = reporter.StatusProgress()
+			}
+			if progress.Phase != "" {
+				if progress.Phase != lastProgress.Phase {
+					step++
+				}
+				if progress != lastProgress {
+					log.Info(formatProgress(step, progress))
+					lastProgress = progress
+					startWaiting = time.Now()
+				}
+			}
+
+			if time.Since(startWaiting) > time.Second*10 {
+				log.Infof("Waiting for workspace to come up...")
+				log.Debugf("Got status %s, expected: Running", instanceStatus)
+				startWaiting = time.Now()
+			}
+			if busyWarning > 0 && time.Since(busySince) > nextBusyWarning {
+				log.Warnf("Workspace has been busy for %s, the provider may be stuck. Check the workspace with 'devpod status %s' and the provider", time.Since(busySince).Round(time.Second), client.Workspace())
+				nextBusyWarning += busyWarning
+			}
+			if waitTimeout > 0 && time.Since(started) > waitTimeout {
+				return fmt.Errorf("workspace didn't come up within %s, check it with 'devpod status %s'", waitTimeout, client.Workspace())
+			}
+
+			select {
+			case <-ctx.Done():
+				return canceledOr(ctx, ctx.Err())
+			case <-time.After(poll.Next(polls)):
+			}
+			continue
 		} else if instanceStatus == client2.StatusStopped {
 			if create {
+				err = runPreflight()
+				if err != nil {
+					return err
+				}
+
 				// start environment
+				log.Infof("Starting workspace...")
+				_, startSpan := tracing.StartSpan(ctx, "start")
 				err = client.Start(ctx, client2.StartOptions{})
+				tracing.EndSpan(startSpan, err)
 				if err != nil {
-					return errors.Wrap(err, "start workspace")
+					err = retryRateLimited(err)
+					if err == nil {
+						continue
+					}
+
+					return canceledOr(ctx, errors.Wrap(err, "start workspace"))
 				}
 			} else {
 				return fmt.Errorf("DevPod workspace is stopped")
 			}
 		} else if instanceStatus == client2.StatusNotFound {
 			if create {
+				err = runPreflight()
+				if err != nil {
+					return err
+				}
+
 				// create environment
+				log.Infof("Creating workspace...")
+				_, createSpan := tracing.StartSpan(ctx, "create")
 				err = client.Create(ctx, client2.CreateOptions{})
+				tracing.EndSpan(createSpan, err)
 				if err != nil {
-					return err
+					return canceledOr(ctx, err)
 				}
 			} else {
 				return fmt.Errorf("DevPod workspace wasn't found")
@@ -170,6 +1078,39 @@ func startWait(ctx context.Context, client client2.WorkspaceClient, create bool,
 	}
 }
 
+// rateLimitBackoff returns the wait before the given retry of a rate limited provider that
+// didn't say how long to wait
+func rateLimitBackoff(retry int) time.Duration {
+	delay := 5 * time.Second
+	for i := 0; i < retry && delay < time.Minute; i++ {
+		delay *= 2
+	}
+	if delay > time.Minute {
+		delay = time.Minute
+	}
+
+	return delay
+}
+
+// formatProgress renders a phase reported by the provider as a step of the workspace start
+func formatProgress(step int, progress client2.StatusProgress) string {
+	if progress.Percent >= 0 {
+		return fmt.Sprintf("Waiting for workspace to come up [%d] %s (%d%%)", step, progress.Phase, progress.Percent)
+	}
+
+	return fmt.Sprintf("Waiting for workspace to come up [%d] %s", step, progress.Phase)
+}
+
+// canceledOr returns a hint about how to resume if the context was canceled and
+// the given error otherwise
+func canceledOr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("canceled starting the workspace, run the command again to resume: %w", err)
+	}
+
+	return err
+}
+
 func (cmd *SSHCmd) jumpContainer(ctx context.Context, devPodConfig *config.Config, client client2.WorkspaceClient, log log.Logger) error {
 	// lock the workspace as long as we init the connection
 	unlockOnce := sync.Once{}
@@ -179,62 +1120,508 @@ func (cmd *SSHCmd) jumpContainer(ctx context.Context, devPodConfig *config.Confi
 	}
 	defer unlockOnce.Do(client.Unlock)
 
+	// show the connect banner
+	if len(cmd.Commands) == 0 && cmd.Edit == "" && len(cmd.forwards) == 0 && !cmd.Stdio && !cmd.Proxy && !cmd.TunnelOnly && !cmd.RawAgent && !cmd.Daemon {
+		cmd.printBanner(client, log)
+	}
+
 	// start the workspace
-	err = startWait(ctx, client, false, log)
+	busyWarning, err := time.ParseDuration(cmd.BusyWarning)
+	if err != nil {
+		return errors.Wrap(err, "parse busy warning")
+	}
+	waitTimeout, err := time.ParseDuration(cmd.WaitTimeout)
+	if err != nil {
+		return errors.Wrap(err, "parse wait timeout")
+	}
+	poll := workspace2.DefaultPollInterval()
+	poll.Base, err = time.ParseDuration(cmd.PollInterval)
+	if err != nil {
+		return errors.Wrap(err, "parse poll interval")
+	}
+	poll.Max, err = time.ParseDuration(cmd.PollMaxInterval)
+	if err != nil {
+		return errors.Wrap(err, "parse max poll interval")
+	}
+	poll.Jitter = cmd.PollJitter
+	err = poll.Validate()
 	if err != nil {
 		return err
 	}
+	err = startWait(ctx, client, cmd.Start || cmd.FromSnapshot != "", cmd.Preflight && !cmd.NoPreflight, busyWarning, poll, waitTimeout, log)
+	if err != nil {
+		return err
+	}
+
+	// bridge stdio to the agent without an ssh session
+	if cmd.RawAgent {
+		unlockOnce.Do(client.Unlock)
+		return tunnel.NewContainerTunnel(client, cmd.Proxy, cmd.AutoInstallAgent, log).RunRaw(ctx, cmd.User, os.Stdin, os.Stdout)
+	}
 
 	// tunnel to container
-	return tunnel.NewContainerTunnel(client, cmd.Proxy, log).Run(ctx, func(ctx context.Context, containerClient *ssh.Client) error {
+	connectStart := time.Now()
+	traceCtx := ctx
+	tunnelCtx, tunnelSpan := tracing.StartSpan(traceCtx, "tunnel")
+	connected := false
+	handler := func(ctx context.Context, containerClient *ssh.Client) error {
 		// we have a connection to the container, make sure others can connect as well
+		connected = true
 		unlockOnce.Do(client.Unlock)
+		tunnelSpan.End()
+
+		// the agent is ready once the workspace is prepared for the session
+		_, agentReadySpan := tracing.StartSpan(traceCtx, "agent-ready")
+		defer agentReadySpan.End()
+
+		// compare the agent version with the cli version
+		err := cmd.checkAgentVersion(ctx, containerClient, client, log)
+		if err != nil {
+			return err
+		}
+
+		if cmd.Benchmark {
+			return cmd.runBenchmark(ctx, containerClient, time.Since(connectStart))
+		} else if cmd.Cleanup {
+			return cleanupProcesses(ctx, client, containerClient, log)
+		}
+
+		// resolve the remote user of the dev container
+		if cmd.User == "" {
+			cmd.User = getRemoteUser(ctx, containerClient, log)
+		}
+
+		// add the public key instead of starting a session
+		if cmd.CopyID != "" {
+			return cmd.copyID(ctx, containerClient, log)
+		}
+
+		// forward the docker socket
+		if cmd.ForwardDocker {
+			stopForwarding, err := forwardDocker(ctx, containerClient, client.Workspace(), cmd.channelLimiter, log)
+			if err != nil {
+				return err
+			}
+			defer stopForwarding()
+		}
+
+		// wait until the workspace is mounted
+		if cmd.WaitMounts {
+			err := cmd.waitForMounts(ctx, containerClient, log)
+			if err != nil {
+				return err
+			}
+		}
+
+		// wait until the ide is installed
+		ideName := client.WorkspaceConfig().IDE.Name
+		if cmd.WaitForIDE && ideName != "" && ideName != string(config.IDENone) {
+			err := cmd.waitForIDE(ctx, containerClient, log)
+			if err != nil {
+				return err
+			}
+		}
+
+		// install the dotfiles once
+		if cmd.Setup == "true" || cmd.Setup == "force" {
+			if devPodConfig.ContextOption(config.ContextOptionDotfilesURL) == "" {
+				log.Warnf("No dotfiles to set up, configure them via 'devpod context set-options -o %s=<repository>'", config.ContextOptionDotfilesURL)
+			} else {
+				err := setupDotfiles("", "", cmd.Setup == "force", client, devPodConfig, log)
+				if err != nil {
+					return errors.Wrap(err, "set up dotfiles")
+				}
+			}
+		}
+
+		// make the local gpg keys usable for signing
+		if cmd.ForwardGPG {
+			err := cmd.setupGPG(ctx, containerClient, log)
+			if err != nil {
+				return err
+			}
+		}
+
+		// load the environment of the profile
+		if cmd.Profile != "" {
+			err := cmd.applyProfile(ctx, containerClient, log)
+			if err != nil {
+				return err
+			}
+		}
+
+		// open the file in the editor instead of a shell
+		if cmd.Edit != "" {
+			workdir := cmd.EditWorkdir
+			if workdir == "" {
+				workdir = getWorkspaceFolder(ctx, containerClient, log)
+			}
+
+			cmd.Commands = []string{devssh.EditorCommand(cmd.Edit, workdir)}
+		}
+
+		// run the commands as root
+		if cmd.Sudo {
+			err := cmd.applySudo(ctx, containerClient, log)
+			if err != nil {
+				return err
+			}
+		}
 
 		// start ssh tunnel
-		return cmd.startTunnel(ctx, devPodConfig, containerClient, client.WorkspaceConfig().IDE.Name, log)
+		agentReadySpan.End()
+		return cmd.startTunnel(ctx, devPodConfig, containerClient, ideName, log)
+	}
+	for attempt := 1; ; attempt++ {
+		err = tunnel.NewContainerTunnel(client, cmd.Proxy, cmd.AutoInstallAgent, log).Run(tunnelCtx, handler)
+		if err == nil || connected || attempt > cmd.ConnectRetries {
+			break
+		}
+
+		// only retry transient failures of the connection setup
+		rule, retry := devssh.RetryRule(err, cmd.RetryOn)
+		if !retry {
+			break
+		}
+		log.Infof("Retrying connection (%d/%d), the error matched the retry rule '%s': %v", attempt, cmd.ConnectRetries, rule, err)
+		select {
+		case <-ctx.Done():
+			tracing.EndSpan(tunnelSpan, err)
+			return err
+		case <-time.After(time.Second * time.Duration(attempt)):
+		}
+	}
+	tracing.EndSpan(tunnelSpan, err)
+	return err
+}
+
+// applyProfile adds the environment variables and rc command of the --profile from the devcontainer.json
+// to the session. Variables from --env and --env-file take precedence over the ones of the profile.
+func (cmd *SSHCmd) applyProfile(ctx context.Context, containerClient *ssh.Client, log log.Logger) error {
+	result, err := getContainerResult(ctx, containerClient)
+	if err != nil {
+		return err
+	}
+
+	profiles := config2.GetDevPodProfiles(result.MergedConfig)
+	profile, ok := profiles[cmd.Profile]
+	if !ok {
+		names := []string{}
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("profile %s doesn't exist, the devcontainer.json defines no profiles in customizations.devpod.profiles", cmd.Profile)
+		}
+
+		return fmt.Errorf("profile %s doesn't exist, available profiles: %s", cmd.Profile, strings.Join(names, ", "))
+	}
+
+	for k, v := range profile.RemoteEnv {
+		if _, ok := cmd.env[k]; !ok {
+			cmd.env[k] = v
+		}
+	}
+	if profile.RCCommand != "" {
+		if cmd.tmuxSession != "" {
+			log.Warnf("The rc command of profile %s is not supported together with --tmux and is skipped", cmd.Profile)
+		} else if cmd.RCCommand != "" {
+			cmd.RCCommand = profile.RCCommand + "\n" + cmd.RCCommand
+		} else {
+			cmd.RCCommand = profile.RCCommand
+		}
+	}
+
+	log.Debugf("Using profile %s", cmd.Profile)
+	return nil
+}
+
+// cleanupProcesses stops the DevPod helper processes in the container and on the workspace host
+// whose client is gone and reports them
+func cleanupProcesses(ctx context.Context, client client2.WorkspaceClient, containerClient *ssh.Client, log log.Logger) error {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	err := devssh.Run(ctx, containerClient, fmt.Sprintf("'%s' agent cleanup-processes", agent.ContainerDevPodHelperLocation), nil, stdout, stderr)
+	if err != nil {
+		return fmt.Errorf("clean up processes in the container: %w", command.WrapCommandError(stderr.Bytes(), err))
+	}
+	stopped := reportStoppedProcesses("container", stdout.String(), log)
+
+	// container tunnels run on the workspace host
+	stdout.Reset()
+	stderr.Reset()
+	err = client.Command(ctx, client2.CommandOptions{
+		Command: fmt.Sprintf("'%s' agent cleanup-processes", client.AgentPath()),
+		Stdout:  stdout,
+		Stderr:  stderr,
 	})
+	if err != nil {
+		log.Warnf("Error cleaning up processes on the workspace host: %v", command.WrapCommandError(stderr.Bytes(), err))
+	} else {
+		stopped += reportStoppedProcesses("workspace host", stdout.String(), log)
+	}
+
+	if stopped == 0 {
+		log.Infof("No stale DevPod processes found")
+	}
+	return nil
 }
 
-func (cmd *SSHCmd) forwardPorts(ctx context.Context, containerClient *ssh.Client, log log.Logger) error {
-	timeout := time.Duration(0)
-	if cmd.ForwardPortsTimeout != "" {
-		var err error
-		timeout, err = time.ParseDuration(cmd.ForwardPortsTimeout)
+// reportStoppedProcesses logs the processes printed by the cleanup-processes agent command
+func reportStoppedProcesses(location, out string, log log.Logger) int {
+	stopped := 0
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		pid, processCommand, found := strings.Cut(line, " ")
+		if !found {
+			continue
+		}
+
+		log.Donef("Stopped process %s in the %s: %s", pid, location, processCommand)
+		stopped++
+	}
+
+	return stopped
+}
+
+// applySudo checks that the user can use sudo in the container and wraps the commands with sudo.
+// Without commands, a root login shell is started.
+func (cmd *SSHCmd) applySudo(ctx context.Context, containerClient *ssh.Client, log log.Logger) error {
+	if cmd.User == "" || cmd.User == "root" {
+		log.Infof("Already connected as root, ignoring --sudo")
+		return nil
+	}
+
+	exitErr := &ssh.ExitError{}
+	err := runAsUser(ctx, containerClient, cmd.User, "command -v sudo >/dev/null 2>&1 || exit 3; sudo -n true >/dev/null 2>&1 || exit 4", nil, io.Discard)
+	if errors.As(err, &exitErr) && exitErr.ExitStatus() == 3 {
+		return fmt.Errorf("sudo is not installed in the workspace, install it or connect with --user root")
+	} else if errors.As(err, &exitErr) && exitErr.ExitStatus() == 4 {
+		if cmd.NonInteractive {
+			return fmt.Errorf("sudo requires a password for user %s, configure passwordless sudo or connect with --user root", cmd.User)
+		}
+
+		log.Warnf("sudo requires a password for user %s and will ask for it", cmd.User)
+	} else if err != nil {
+		return errors.Wrap(err, "check sudo")
+	}
+
+	if len(cmd.Commands) == 0 {
+		cmd.Commands = []string{"sudo -i"}
+		return nil
+	}
+	for i, command := range cmd.Commands {
+		cmd.Commands[i] = shellescape.QuoteCommand([]string{"sudo", "--", "sh", "-c", command})
+	}
+
+	return nil
+}
+
+// runAsUser runs the command in the container as the given user
+func runAsUser(ctx context.Context, containerClient *ssh.Client, user, command string, stdin io.Reader, stdout io.Writer) error {
+	stderr := &bytes.Buffer{}
+	if user != "" && user != "root" {
+		command = fmt.Sprintf("su -c %s %s", shellescape.Quote(command), shellescape.Quote(user))
+	}
+
+	err := devssh.Run(ctx, containerClient, command, stdin, stdout, stderr)
+	if err != nil {
+		return fmt.Errorf("%s%w", stderr.String(), err)
+	}
+	return nil
+}
+
+// checkAgentVersion warns if the version of the agent within the container is not compatible
+// with the version of the CLI. With --strict-version, any difference is an error. Once the agent
+// has the version of the CLI, the check is skipped on later connects, as only this CLI version
+// replaces the agent and installs its own version.
+func (cmd *SSHCmd) checkAgentVersion(ctx context.Context, containerClient *ssh.Client, client client2.BaseWorkspaceClient, log log.Logger) error {
+	if version.GetVersion() == version.DevVersion {
+		return nil
+	}
+
+	workspace := client.Workspace()
+	cachedVersion, err := provider2.LoadAgentVersion(client.Context(), workspace)
+	if err != nil {
+		log.Debugf("Error loading devpod agent version: %v", err)
+	} else if cachedVersion == version.GetVersion() {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	err = devssh.Run(ctx, containerClient, fmt.Sprintf("'%s' version", agent.ContainerDevPodHelperLocation), nil, buf, buf)
+	if err != nil {
+		if cmd.StrictVersion {
+			return fmt.Errorf("retrieve devpod agent version: %s%w", buf.String(), err)
+		}
+
+		log.Debugf("Error retrieving devpod agent version: %s%v", buf.String(), err)
+		return nil
+	}
+
+	agentVersion := strings.TrimSpace(buf.String())
+	if agentVersion == version.GetVersion() {
+		err = provider2.SaveAgentVersion(client.Context(), workspace, agentVersion)
 		if err != nil {
-			return fmt.Errorf("parse forward ports timeout: %w", err)
+			log.Debugf("Error saving devpod agent version: %v", err)
 		}
 
-		log.Infof("Using port forwarding timeout of %s", cmd.ForwardPortsTimeout)
+		return nil
+	} else if cmd.StrictVersion {
+		return fmt.Errorf("devpod agent in the workspace has version %s, but --strict-version requires version %s, please run 'devpod up %s' to update it", agentVersion, version.GetVersion(), workspace)
+	} else if !version.Compatible(agentVersion, version.GetVersion()) {
+		log.Warnf("DevPod agent in the workspace has version %s, which might not be compatible with the CLI version %s. Please run 'devpod up %s' to update it", agentVersion, version.GetVersion(), workspace)
+	} else {
+		log.Debugf("DevPod agent in the workspace has version %s, CLI has version %s", agentVersion, version.GetVersion())
 	}
 
-	errChan := make(chan error, len(cmd.ForwardPorts))
-	for _, portMapping := range cmd.ForwardPorts {
-		mapping, err := port.ParsePortSpec(portMapping)
+	return nil
+}
+
+// tmuxCommand returns a command that attaches to the given tmux session or creates it and
+// falls back to a login shell if tmux is not installed
+func tmuxCommand(session string) string {
+	return fmt.Sprintf(`if command -v tmux >/dev/null 2>&1; then exec tmux new-session -A -s '%s'; else echo "warning: tmux is not installed in the workspace, falling back to a plain shell" >&2; exec "${SHELL:-sh}" -l; fi`, session)
+}
+
+// clientOptions returns the handshake options for the ssh connections to the workspace
+func (cmd *SSHCmd) clientOptions(log log.Logger) devssh.ClientOptions {
+	options := devssh.ClientOptions{
+		Audit:         cmd.audit,
+		BannerTimeout: cmd.bannerTimeout,
+	}
+	if cmd.VerboseSSH {
+		options.BannerCallback = func(message string) error {
+			log.Infof("SSH banner: %s", strings.TrimSpace(message))
+			return nil
+		}
+	}
+
+	return options
+}
+
+// mapPaths rewrites the local folders of --map-path to the remote ones in the commands
+func (cmd *SSHCmd) mapPaths(log log.Logger) error {
+	mappings := []devssh.PathMapping{}
+	for _, mapPath := range cmd.MapPaths {
+		mapping, err := devssh.ParsePathMapping(mapPath)
 		if err != nil {
-			return fmt.Errorf("parse port mapping: %w", err)
+			return err
 		}
 
-		// start the forwarding
-		log.Infof("Forwarding local %s/%s to remote %s/%s", mapping.Host.Protocol, mapping.Host.Address, mapping.Container.Protocol, mapping.Container.Address)
-		go func(portMapping string) {
-			err := devssh.PortForward(ctx, containerClient, mapping.Host.Protocol, mapping.Host.Address, mapping.Container.Protocol, mapping.Container.Address, timeout, log)
-			if err != nil {
-				errChan <- fmt.Errorf("error forwarding %s: %w", portMapping, err)
+		mappings = append(mappings, mapping)
+	}
+
+	for i, command := range cmd.Commands {
+		newCommand, rewrites := devssh.RewritePaths(command, mappings)
+		for _, rewrite := range rewrites {
+			log.Debugf("Mapped path %s to %s", rewrite.From, rewrite.To)
+		}
+
+		cmd.Commands[i] = newCommand
+	}
+
+	return nil
+}
+
+// currentDirOrPickerResolver resolves the workspace of the current directory like
+// workspace2.CurrentDirResolver. If the directory isn't linked to a workspace, the user can pick
+// one on a terminal unless --no-picker is set.
+func (cmd *SSHCmd) currentDirOrPickerResolver(ctx context.Context) workspace2.Resolver {
+	return func(devPodConfig *config.Config, args []string, log log.Logger) (string, error) {
+		workspaceID, err := workspace2.CurrentDirResolver(devPodConfig, args, log)
+		if err == nil || cmd.NoPicker || !terminal.IsTerminalIn {
+			return workspaceID, err
+		}
+
+		log.Debugf("Showing workspace picker: %v", err)
+		return workspace2.PickWorkspace(ctx, devPodConfig, log)
+	}
+}
+
+// sessionEnv returns the environment variables from --env-passthrough-all, --env-file and --env, where --env takes precedence
+// over --env-file and both over the passed through local environment
+func (cmd *SSHCmd) sessionEnv(log log.Logger) (map[string]string, error) {
+	env := map[string]string{}
+	if cmd.EnvFile != "" {
+		var err error
+		env, err = godotenv.Read(cmd.EnvFile)
+		if err != nil {
+			return nil, fmt.Errorf("parse env file %s: %w", cmd.EnvFile, err)
+		}
+	}
+
+	for _, e := range cmd.Env {
+		k, v, found := strings.Cut(e, "=")
+		if !found || k == "" {
+			return nil, fmt.Errorf("invalid environment variable %s, expected format KEY=VALUE", e)
+		}
+
+		env[k] = v
+	}
+
+	if cmd.EnvPassthrough {
+		passthrough, names := devssh.PassthroughEnv(os.Environ(), cmd.Unsafe)
+		for k, v := range passthrough {
+			if _, ok := env[k]; !ok {
+				env[k] = v
 			}
-		}(portMapping)
+		}
+		log.Warnf("Forwarding %d local environment variables into the session: %s", len(names), strings.Join(names, ", "))
 	}
 
-	return <-errChan
+	return env, nil
 }
 
-func (cmd *SSHCmd) startTunnel(ctx context.Context, devPodConfig *config.Config, containerClient *ssh.Client, ideName string, log log.Logger) error {
+// logger returns the logger for the DevPod logs as configured by --log-destination and a func to close it
+func (cmd *SSHCmd) logger() (log.Logger, func(), error) {
+	switch cmd.LogDestination {
+	case logDestinationStderr, "":
+		return log.Default.ErrorStreamOnly(), func() {}, nil
+	case logDestinationStdout:
+		if cmd.Stdio || cmd.Proxy || cmd.TunnelOnly || cmd.RawAgent {
+			return nil, nil, fmt.Errorf("--log-destination stdout cannot be used together with --stdio, --proxy, --tunnel-only or --raw-agent")
+		}
+
+		return log.NewStreamLogger(os.Stdout, os.Stdout, log.Default.GetLevel()), func() {}, nil
+	}
+
+	logFile, err := os.OpenFile(cmd.LogDestination, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "open log output")
+	}
+
+	return log.NewStreamLoggerWithFormat(logFile, logFile, log.Default.GetLevel(), log.TimeFormat), func() {
+		_ = logFile.Close()
+	}, nil
+}
+
+func (cmd *SSHCmd) startTunnel(ctx context.Context, devPodConfig *config.Config, containerClient *ssh.Client, ideName string, log log.Logger) (err error) {
+	// check if we should only bridge stdio to the target
+	if cmd.TunnelOnly {
+		log.Debugf("Tunnel stdio to %s", cmd.TunnelTarget)
+		return devssh.StdioForward(ctx, containerClient, "tcp", cmd.TunnelTarget, os.Stdin, os.Stdout)
+	}
+
 	// check if we should forward ports
-	if len(cmd.ForwardPorts) > 0 {
+	if len(cmd.forwards) > 0 {
 		return cmd.forwardPorts(ctx, containerClient, log)
 	}
 
+	// keep the workspace active
+	if cmd.KeepAliveActivity != "" {
+		interval, err := time.ParseDuration(cmd.KeepAliveActivity)
+		if err != nil {
+			return fmt.Errorf("parse keep alive activity interval: %w", err)
+		} else if interval <= 0 {
+			return fmt.Errorf("keep alive activity interval needs to be greater than zero")
+		}
+
+		go keepAliveActivity(ctx, containerClient, interval, log)
+	}
+
 	// start port-forwarding etc.
-	if !cmd.Proxy && cmd.StartServices {
+	if !cmd.Proxy && cmd.StartServices && !cmd.Restricted {
 		go cmd.startServices(ctx, devPodConfig, containerClient, ideName, log)
 	}
 
@@ -247,34 +1634,308 @@ func (cmd *SSHCmd) startTunnel(ctx context.Context, devPodConfig *config.Config,
 	if cmd.Debug {
 		command += " --debug"
 	}
+	if cmd.IdleTimeout != "" {
+		command += " --idle-timeout " + shellescape.Quote(cmd.IdleTimeout)
+	}
+	if cmd.KeepContainerRunning != "" {
+		command += " --keep-running " + cmd.KeepContainerRunning
+	}
+	if cmd.Restricted {
+		restrictedCommands, err := helperssh.EncodeRestrictedCommands(cmd.RestrictedCommands)
+		if err != nil {
+			return err
+		}
+
+		command += " --restricted-commands " + restrictedCommands
+	}
 	if cmd.User != "" && cmd.User != "root" {
-		command = fmt.Sprintf("su -c \"%s\" '%s'", command, cmd.User)
+		command = fmt.Sprintf("su -c %s %s", shellescape.Quote(command), shellescape.Quote(cmd.User))
 	}
 	if cmd.Proxy || cmd.Stdio {
 		return devssh.Run(ctx, containerClient, command, os.Stdin, os.Stdout, writer)
+	} else if cmd.WSAddr != "" {
+		return cmd.serveWebTerminal(ctx, containerClient, command, writer, log)
+	} else if cmd.DaemonChild {
+		return cmd.serveDaemon(ctx, containerClient, command, writer, log)
+	}
+
+	// copy files before the session starts
+	if len(cmd.syncs) > 0 {
+		err = cmd.syncFiles(ctx, containerClient, command, writer, log)
+		if err != nil {
+			return err
+		} else if cmd.SyncOnly {
+			return nil
+		}
+	}
+
+	// check the workspace before the session starts
+	if len(cmd.Asserts) > 0 {
+		err = cmd.runAssertions(ctx, containerClient, command, writer, log)
+		if err != nil {
+			return err
+		}
+	}
+
+	// write the command output to files
+	stdout, stderr, closeOutput, err := cmd.openOutputFiles(os.Stdout, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := closeOutput(commandExitCode(err))
+		if closeErr != nil {
+			log.Errorf("Error closing output file: %v", closeErr)
+		}
+	}()
+
+	if len(cmd.Commands) > 1 {
+		err = cmd.runCommands(ctx, containerClient, command, stdout, stderr, writer, log)
+		return err
+	}
+
+	sessionCommand := ""
+	idleDisconnect := cmd.idleDisconnect
+	if len(cmd.Commands) == 1 {
+		sessionCommand = cmd.limits.WrapCommand(cmd.Commands[0])
+		idleDisconnect = 0
+	} else if cmd.tmuxSession != "" {
+		sessionCommand = tmuxCommand(cmd.tmuxSession)
+	}
+
+	// record the session
+	var recorder *devssh.Recorder
+	if cmd.Record != "" {
+		recordFile, err := os.Create(cmd.Record)
+		if err != nil {
+			return errors.Wrap(err, "create recording")
+		}
+
+		recorder = devssh.NewRecorder(recordFile, cmd.RecordStdin)
+		defer func() {
+			_ = recorder.Close()
+			log.Infof("Recorded session to %s", cmd.Record)
+		}()
+	}
+	if cmd.ErrorFile == "" && cmd.LogDestination == logDestinationStderr {
+		stderr = writer
 	}
 
-	return machine.StartSSHSession(ctx, cmd.User, cmd.Command, !cmd.Proxy && cmd.AgentForwarding && devPodConfig.ContextOption(config.ContextOptionSSHAgentForwarding) == "true", func(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	// set the local clipboard from the remote terminal
+	var onCopy func(content []byte)
+	if cmd.Clipboard {
+		onCopy = func(content []byte) {
+			go func() {
+				err := devssh.WriteClipboard(content)
+				if err != nil {
+					log.Debugf("Error writing clipboard: %v", err)
+				}
+			}()
+		}
+	}
+	err = machine.StartSSHSession(ctx, machine.SessionOptions{
+		User:            cmd.User,
+		Command:         sessionCommand,
+		RCCommand:       cmd.RCCommand,
+		Env:             cmd.env,
+		AgentForwarding: !cmd.Proxy && !cmd.Restricted && cmd.AgentForwarding && devPodConfig.ContextOption(config.ContextOptionSSHAgentForwarding) == "true",
+		AgentKeys:       cmd.AgentKeys,
+		NoStdin:         cmd.NoStdin,
+		NoEcho:          cmd.NoEcho,
+		IdleDisconnect:  idleDisconnect,
+		Recorder:        recorder,
+		ClientOptions:   cmd.clientOptions(log),
+		OnCopy:          onCopy,
+		GPGAgentSocket:  cmd.gpgAgentSocket,
+		ForwardSignals:  cmd.Exec,
+	}, func(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 		return devssh.Run(ctx, containerClient, command, stdin, stdout, stderr)
-	}, writer)
+	}, stdout, stderr, writer)
+	return err
+}
+
+// getContainerResult reads the setup result of the dev container
+func getContainerResult(ctx context.Context, containerClient *ssh.Client) (*config2.Result, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	err := devssh.Run(ctx, containerClient, "cat "+setup.ResultLocation, nil, stdout, stderr)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve container result: %s%w", stderr.String(), err)
+	}
+
+	result := &config2.Result{}
+	err = json.Unmarshal(stdout.Bytes(), result)
+	if err != nil {
+		return nil, fmt.Errorf("parse container result: %w", err)
+	}
+
+	return result, nil
+}
+
+// getWorkspaceFolder returns the workspace folder of the dev container from the setup result or
+// an empty string if the result can't be read
+func getWorkspaceFolder(ctx context.Context, containerClient *ssh.Client, log log.Logger) string {
+	result, err := getContainerResult(ctx, containerClient)
+	if err != nil || result.SubstitutionContext == nil {
+		log.Debugf("Error reading container result, falling back to the home folder: %v", err)
+		return ""
+	}
+
+	return result.SubstitutionContext.ContainerWorkspaceFolder
+}
+
+// getRemoteUser returns the remote user of the dev container from the setup result and
+// falls back to root if the result can't be read
+func getRemoteUser(ctx context.Context, containerClient *ssh.Client, log log.Logger) string {
+	result, err := getContainerResult(ctx, containerClient)
+	if err != nil {
+		log.Debugf("Error reading container result, falling back to root: %v", err)
+		return "root"
+	}
+
+	user := config2.GetRemoteUser(result)
+	log.Debugf("Using remote user %s of the dev container", user)
+	return user
+}
+
+// waitForMounts waits until the expected mount paths exist in the container
+func (cmd *SSHCmd) waitForMounts(ctx context.Context, containerClient *ssh.Client, log log.Logger) error {
+	timeout, err := time.ParseDuration(cmd.WaitMountsTimeout)
+	if err != nil {
+		return errors.Wrap(err, "parse wait mounts timeout")
+	}
+
+	paths := cmd.WaitMountPaths
+	if len(paths) == 0 {
+		result, err := getContainerResult(ctx, containerClient)
+		if err != nil {
+			return err
+		} else if result.SubstitutionContext != nil && result.MergedConfig != nil {
+			for _, mount := range config2.GetMounts(result) {
+				if mount.Target != "" {
+					paths = append(paths, mount.Target)
+				}
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	checks := []string{}
+	for _, path := range paths {
+		checks = append(checks, "test -e "+shellescape.Quote(path))
+	}
+
+	log.Debugf("Wait for mounts %s", strings.Join(paths, ", "))
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		buf := &bytes.Buffer{}
+		err = devssh.Run(timeoutCtx, containerClient, strings.Join(checks, " && "), nil, buf, buf)
+		if err == nil {
+			log.Debugf("Mounts are ready")
+			return nil
+		}
+
+		select {
+		case <-timeoutCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return fmt.Errorf("timed out after %s waiting for the mounts %s", timeout, strings.Join(paths, ", "))
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// waitForIDE waits until the agent signals that the IDE was installed in the container
+func (cmd *SSHCmd) waitForIDE(ctx context.Context, containerClient *ssh.Client, log log.Logger) error {
+	timeout, err := time.ParseDuration(cmd.WaitForIDETimeout)
+	if err != nil {
+		return errors.Wrap(err, "parse wait for ide timeout")
+	}
+
+	log.Debugf("Wait for IDE to be ready")
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		buf := &bytes.Buffer{}
+		err = devssh.Run(timeoutCtx, containerClient, fmt.Sprintf("test -f '%s'", agent.ContainerIDEReadyFile), nil, buf, buf)
+		if err == nil {
+			log.Debugf("IDE is ready")
+			return nil
+		}
+
+		select {
+		case <-timeoutCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return fmt.Errorf("timed out after %s waiting for the IDE to be installed", timeout)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func keepAliveActivity(ctx context.Context, containerClient *ssh.Client, interval time.Duration, log log.Logger) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+			buf := &bytes.Buffer{}
+			err := devssh.Run(ctx, containerClient, fmt.Sprintf("touch '%s'", agent.ContainerActivityFile), nil, buf, buf)
+			if err != nil {
+				log.Debugf("Error sending keep alive activity: %s%v", buf.String(), err)
+				continue
+			}
+
+			log.Debugf("Sent keep alive activity")
+		}
+	}
 }
 
 func (cmd *SSHCmd) startServices(ctx context.Context, devPodConfig *config.Config, containerClient *ssh.Client, ideName string, log log.Logger) {
 	if cmd.User != "" {
+		credentialUsers := cmd.CredentialUsers
+		if len(credentialUsers) == 0 {
+			credentialUsers = []string{cmd.User}
+		}
+
 		gitCredentials := ideName != string(config.IDEVSCode)
 		err := tunnel.RunInContainer(
 			ctx,
 			devPodConfig,
 			containerClient,
-			cmd.User,
+			credentialUsers,
+			cmd.RuntimeDir,
+			cmd.HelperPath,
 			false,
 			gitCredentials,
 			true,
 			nil,
 			log,
 		)
-		if err != nil {
-			log.Debugf("Error running credential server: %v", err)
+		if err != nil && ctx.Err() == nil {
+			log.Warnf("Credential forwarding is unavailable, git and docker will not use your local credentials in this session: %v", err)
+			cleanupCredentialHelpers(ctx, containerClient, credentialUsers, log)
 		}
 	}
 }
+
+// cleanupCredentialHelpers removes partially configured credential helpers, so
+// git fails fast instead of hanging on a helper that never answers
+func cleanupCredentialHelpers(ctx context.Context, containerClient *ssh.Client, users []string, log log.Logger) {
+	buf := &bytes.Buffer{}
+	command := fmt.Sprintf("'%s' agent container credentials-server --cleanup", agent.ContainerDevPodHelperLocation)
+	for _, user := range users {
+		command += fmt.Sprintf(" --user '%s'", user)
+	}
+	err := devssh.Run(ctx, containerClient, command, nil, buf, buf)
+	if err != nil {
+		log.Debugf("Error cleaning up credential helpers: %s%v", buf.String(), err)
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/log"
+	"github.com/loft-sh/log/table"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// runBenchmark measures the connection to the container and prints a summary
+func (cmd *SSHCmd) runBenchmark(ctx context.Context, containerClient *ssh.Client, connectTime time.Duration) error {
+	// round trip latency
+	var total, min, max time.Duration
+	for i := 0; i < cmd.BenchmarkIterations; i++ {
+		start := time.Now()
+		err := devssh.Run(ctx, containerClient, "true", nil, io.Discard, io.Discard)
+		if err != nil {
+			return errors.Wrap(err, "measure latency")
+		}
+
+		latency := time.Since(start)
+		total += latency
+		if i == 0 || latency < min {
+			min = latency
+		}
+		if latency > max {
+			max = latency
+		}
+	}
+
+	// download throughput
+	size := int64(cmd.BenchmarkSize) * 1024 * 1024
+	counter := &countingWriter{}
+	start := time.Now()
+	err := devssh.Run(ctx, containerClient, fmt.Sprintf("head -c %d /dev/zero", size), nil, counter, io.Discard)
+	if err != nil {
+		return errors.Wrap(err, "measure download throughput")
+	} else if counter.n != size {
+		return fmt.Errorf("measure download throughput: received %d of %d bytes", counter.n, size)
+	}
+	download := time.Since(start)
+
+	// upload throughput
+	start = time.Now()
+	err = devssh.Run(ctx, containerClient, "cat > /dev/null", io.LimitReader(zeroReader{}, size), io.Discard, io.Discard)
+	if err != nil {
+		return errors.Wrap(err, "measure upload throughput")
+	}
+	upload := time.Since(start)
+
+	table.PrintTable(log.Default, []string{
+		"Benchmark",
+		"Result",
+	}, [][]string{
+		{"Connect", connectTime.Round(time.Millisecond).String()},
+		{fmt.Sprintf("Latency (%d round trips)", cmd.BenchmarkIterations), fmt.Sprintf("avg %s, min %s, max %s", (total / time.Duration(cmd.BenchmarkIterations)).Round(time.Microsecond), min.Round(time.Microsecond), max.Round(time.Microsecond))},
+		{fmt.Sprintf("Download (%d MB)", cmd.BenchmarkSize), throughput(size, download)},
+		{fmt.Sprintf("Upload (%d MB)", cmd.BenchmarkSize), throughput(size, upload)},
+	})
+	return nil
+}
+
+func throughput(size int64, duration time.Duration) string {
+	return fmt.Sprintf("%.2f MB/s in %s", float64(size)/1024/1024/duration.Seconds(), duration.Round(time.Millisecond))
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+
+	return len(p), nil
+}
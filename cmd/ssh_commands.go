@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// newContainerSSHClient starts the ssh server in the container via serverCommand and returns a
+// client that is connected to it as the session user. The returned func closes the client and
+// stops the server.
+func (cmd *SSHCmd) newContainerSSHClient(ctx context.Context, containerClient *ssh.Client, serverCommand string, serverStderr io.Writer, log log.Logger) (*ssh.Client, func(), error) {
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stdinReader, stdinWriter, err := os.Pipe()
+	if err != nil {
+		_ = stdoutReader.Close()
+		_ = stdoutWriter.Close()
+		return nil, nil, err
+	}
+
+	serverCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		err := devssh.Run(serverCtx, containerClient, serverCommand, stdinReader, stdoutWriter, serverStderr)
+		if err != nil && serverCtx.Err() == nil {
+			log.Debugf("Error running ssh server: %v", err)
+		}
+	}()
+	stop := func() {
+		cancel()
+		_ = stdoutWriter.Close()
+		_ = stdinWriter.Close()
+	}
+
+	sshClient, err := devssh.StdioClientWithOptions(stdoutReader, stdinWriter, cmd.User, cmd.clientOptions(log))
+	if err != nil {
+		stop()
+		return nil, nil, err
+	}
+
+	return sshClient, func() {
+		_ = sshClient.Close()
+		stop()
+	}, nil
+}
+
+// runCommands runs all commands sequentially on a single connection to the ssh server
+func (cmd *SSHCmd) runCommands(ctx context.Context, containerClient *ssh.Client, serverCommand string, stdout, stderr, serverStderr io.Writer, log log.Logger) error {
+	sshClient, closeClient, err := cmd.newContainerSSHClient(ctx, containerClient, serverCommand, serverStderr, log)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	exitCodes := []int{}
+	failed := 0
+	for i, command := range cmd.Commands {
+		fmt.Fprintf(stdout, "==> [%d/%d] %s\n", i+1, len(cmd.Commands), command)
+		err = devssh.RunWithEnv(ctx, sshClient, cmd.limits.WrapCommand(command), cmd.env, nil, stdout, stderr)
+		exitCode := commandExitCode(err)
+		if _, ok := err.(*ssh.ExitError); err != nil && !ok {
+			fmt.Fprintf(stderr, "%v\n", err)
+		}
+
+		exitCodes = append(exitCodes, exitCode)
+		if exitCode != 0 {
+			failed++
+			if !cmd.ContinueOnError {
+				break
+			}
+		}
+	}
+
+	// print summary
+	fmt.Fprintf(stdout, "==> Summary\n")
+	for i, command := range cmd.Commands {
+		if i >= len(exitCodes) {
+			fmt.Fprintf(stdout, "skipped   %s\n", command)
+		} else {
+			fmt.Fprintf(stdout, "exit %-4d %s\n", exitCodes[i], command)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d commands failed", failed, len(cmd.Commands))
+	}
+
+	return nil
+}
+
+// runAssertions runs the --assert commands sequentially on a single connection to the ssh server
+// and returns an error with the failed assertions and their output
+func (cmd *SSHCmd) runAssertions(ctx context.Context, containerClient *ssh.Client, serverCommand string, serverStderr io.Writer, log log.Logger) error {
+	sshClient, closeClient, err := cmd.newContainerSSHClient(ctx, containerClient, serverCommand, serverStderr, log)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	failed := []string{}
+	for i, assertion := range cmd.Asserts {
+		output := &bytes.Buffer{}
+		err = devssh.RunWithEnv(ctx, sshClient, assertion, cmd.env, nil, output, output)
+		if err == nil {
+			log.Donef("Assertion [%d/%d] passed: %s", i+1, len(cmd.Asserts), assertion)
+			continue
+		} else if _, ok := err.(*ssh.ExitError); !ok {
+			return fmt.Errorf("run assertion %s: %w", assertion, err)
+		}
+
+		report := fmt.Sprintf("Assertion [%d/%d] failed with exit code %d: %s", i+1, len(cmd.Asserts), commandExitCode(err), assertion)
+		if output.Len() > 0 {
+			report += "\n" + strings.TrimRight(output.String(), "\n")
+		}
+		log.Error(report)
+		failed = append(failed, assertion)
+		if !cmd.AssertAll {
+			break
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d assertions failed: %s", len(failed), len(cmd.Asserts), strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// openOutputFiles opens the --output-file and --error-file if set and otherwise returns stdout
+// and stderr. The returned close func needs to be called with the exit code of the command.
+func (cmd *SSHCmd) openOutputFiles(stdout, stderr io.Writer) (io.Writer, io.Writer, func(exitCode int) error, error) {
+	var outputFile, errorFile *devssh.OutputFile
+	closeFiles := func(exitCode int) error {
+		var err error
+		for _, file := range []*devssh.OutputFile{outputFile, errorFile} {
+			if file == nil {
+				continue
+			}
+
+			closeErr := file.Close(exitCode)
+			if closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+
+		return err
+	}
+
+	var err error
+	if cmd.OutputFile != "" {
+		outputFile, err = devssh.NewOutputFile(cmd.OutputFile, cmd.AppendOutput, cmd.OutputMetadata)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		stdout = outputFile
+	}
+	if cmd.ErrorFile != "" && cmd.ErrorFile == cmd.OutputFile {
+		stderr = outputFile
+	} else if cmd.ErrorFile != "" {
+		errorFile, err = devssh.NewOutputFile(cmd.ErrorFile, cmd.AppendOutput, cmd.OutputMetadata)
+		if err != nil {
+			_ = closeFiles(1)
+			return nil, nil, nil, err
+		}
+
+		stderr = errorFile
+	}
+
+	return stdout, stderr, closeFiles, nil
+}
+
+// commandExitCode returns the exit code of a remote command from its error
+func commandExitCode(err error) int {
+	if err == nil {
+		return 0
+	} else if sshExitErr, ok := err.(*ssh.ExitError); ok {
+		return sshExitErr.ExitStatus()
+	}
+
+	return 1
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/loft-sh/devpod/cmd/machine"
+	"github.com/loft-sh/devpod/pkg/config"
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/log"
+	"github.com/loft-sh/log/table"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// startDaemon starts the connection of --daemon as background process and waits until it is ready
+func (cmd *SSHCmd) startDaemon(log log.Logger) error {
+	if cmd.Name == "" {
+		return fmt.Errorf("--daemon requires a --name")
+	} else if len(cmd.Commands) > 0 || cmd.Stdio || cmd.Proxy || cmd.TunnelOnly || cmd.RawAgent || cmd.WSAddr != "" || len(cmd.ForwardPorts) > 0 || cmd.ForwardsFile != "" || cmd.Edit != "" || cmd.Tmux {
+		return fmt.Errorf("--daemon cannot be used together with --command, --stdio, --proxy, --tunnel-only, --raw-agent, --ws-addr, port forwarding, --edit or --tmux")
+	}
+	err := devssh.ValidateDaemonName(cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	dir, err := devssh.DaemonsDir()
+	if err != nil {
+		return err
+	}
+	_, err = devssh.LoadDaemon(dir, cmd.Name)
+	if err == nil {
+		return fmt.Errorf("daemon %s is already running, stop it with 'devpod ssh --stop %s'", cmd.Name, cmd.Name)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	logFile, err := os.Create(devssh.DaemonLogFile(dir, cmd.Name))
+	if err != nil {
+		return errors.Wrap(err, "create daemon log")
+	}
+	defer logFile.Close()
+
+	daemonCmd := exec.Command(executable, append(os.Args[1:], "--daemon-child")...)
+	daemonCmd.Stdout = logFile
+	daemonCmd.Stderr = logFile
+	devssh.DetachProcess(daemonCmd)
+	err = daemonCmd.Start()
+	if err != nil {
+		return errors.Wrap(err, "start daemon")
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- daemonCmd.Wait()
+	}()
+
+	// the daemon writes its pid file once it is connected
+	log.Infof("Waiting for daemon %s to connect...", cmd.Name)
+	for {
+		select {
+		case err := <-exited:
+			return fmt.Errorf("daemon %s exited, check its log at %s: %v", cmd.Name, devssh.DaemonLogFile(dir, cmd.Name), err)
+		case <-time.After(500 * time.Millisecond):
+		}
+
+		daemon, err := devssh.LoadDaemon(dir, cmd.Name)
+		if err == nil {
+			log.Donef("Started daemon %s for workspace %s, attach to it with 'devpod ssh --attach %s'", daemon.Name, daemon.Workspace, daemon.Name)
+			return nil
+		}
+	}
+}
+
+// serveDaemon serves the ssh server of the workspace on the socket of the daemon until it is
+// stopped
+func (cmd *SSHCmd) serveDaemon(ctx context.Context, containerClient *ssh.Client, command string, writer io.Writer, log log.Logger) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dir, err := devssh.DaemonsDir()
+	if err != nil {
+		return err
+	}
+	socket := devssh.DaemonSocket(dir, cmd.Name)
+	_ = os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return errors.Wrap(err, "listen on daemon socket")
+	}
+	defer listener.Close()
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	err = devssh.SaveDaemon(dir, &devssh.TunnelDaemon{
+		Name:      cmd.Name,
+		Workspace: cmd.workspace,
+		User:      cmd.User,
+		PID:       os.Getpid(),
+		Socket:    socket,
+		Started:   time.Now(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "save daemon")
+	}
+	defer devssh.RemoveDaemon(dir, cmd.Name)
+
+	log.Infof("Daemon %s is ready", cmd.Name)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Infof("Stopped daemon %s", cmd.Name)
+				return nil
+			}
+
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+
+			log.Debugf("Session attached to daemon %s", cmd.Name)
+			err := devssh.Run(ctx, containerClient, command, conn, conn, writer)
+			if err != nil && ctx.Err() == nil {
+				log.Debugf("Error running ssh server for attached session: %v", err)
+			}
+		}()
+	}
+}
+
+// manageDaemons lists or stops the daemons or attaches a session to one of them
+func (cmd *SSHCmd) manageDaemons(ctx context.Context, devPodConfig *config.Config, log log.Logger) error {
+	dir, err := devssh.DaemonsDir()
+	if err != nil {
+		return err
+	}
+
+	if cmd.ListDaemons {
+		daemons, err := devssh.ListDaemons(dir)
+		if err != nil {
+			return err
+		}
+
+		tableEntries := [][]string{}
+		for _, daemon := range daemons {
+			tableEntries = append(tableEntries, []string{
+				daemon.Name,
+				daemon.Workspace,
+				daemon.User,
+				strconv.Itoa(daemon.PID),
+				daemon.Started.Format(time.RFC3339),
+			})
+		}
+		table.PrintTable(log, []string{
+			"Name",
+			"Workspace",
+			"User",
+			"PID",
+			"Started",
+		}, tableEntries)
+		return nil
+	} else if cmd.Stop != "" {
+		daemon, err := devssh.LoadDaemon(dir, cmd.Stop)
+		if err != nil {
+			return err
+		}
+
+		err = devssh.StopDaemon(daemon)
+		if err != nil {
+			return errors.Wrapf(err, "stop daemon %s", daemon.Name)
+		}
+
+		log.Donef("Stopped daemon %s", daemon.Name)
+		return nil
+	}
+
+	daemon, err := devssh.LoadDaemon(dir, cmd.Attach)
+	if err != nil {
+		return err
+	} else if len(cmd.Commands) > 1 {
+		return fmt.Errorf("--attach can only run a single --command")
+	}
+	env, err := cmd.sessionEnv(log)
+	if err != nil {
+		return err
+	}
+	sessionCommand := ""
+	if len(cmd.Commands) == 1 {
+		sessionCommand = cmd.Commands[0]
+	}
+
+	writer := log.ErrorStreamOnly().Writer(logrus.InfoLevel, false)
+	defer writer.Close()
+
+	return machine.StartSSHSession(ctx, machine.SessionOptions{
+		User:            daemon.User,
+		Command:         sessionCommand,
+		RCCommand:       cmd.RCCommand,
+		Env:             env,
+		AgentForwarding: cmd.AgentForwarding && devPodConfig.ContextOption(config.ContextOptionSSHAgentForwarding) == "true",
+		AgentKeys:       cmd.AgentKeys,
+		NoStdin:         cmd.NoStdin,
+		NoEcho:          cmd.NoEcho,
+		ClientOptions:   cmd.clientOptions(log),
+	}, func(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+		conn, err := net.Dial("unix", daemon.Socket)
+		if err != nil {
+			return errors.Wrapf(err, "connect to daemon %s", daemon.Name)
+		}
+		defer conn.Close()
+
+		go func() {
+			_, _ = io.Copy(conn, stdin)
+		}()
+		_, err = io.Copy(stdout, conn)
+		return err
+	}, os.Stdout, os.Stderr, writer)
+}
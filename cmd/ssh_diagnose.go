@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/loft-sh/devpod/pkg/agent"
+	client2 "github.com/loft-sh/devpod/pkg/client"
+	"github.com/loft-sh/devpod/pkg/command"
+	"github.com/loft-sh/devpod/pkg/config"
+	provider2 "github.com/loft-sh/devpod/pkg/provider"
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/devpod/pkg/tunnel"
+	workspace2 "github.com/loft-sh/devpod/pkg/workspace"
+	"github.com/loft-sh/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// diagnoseStep is the result of a single step of --diagnose
+type diagnoseStep struct {
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+	Guidance string `json:"guidance,omitempty"`
+}
+
+// diagnoseReport is the result of --diagnose
+type diagnoseReport struct {
+	Workspace string          `json:"workspace,omitempty"`
+	Passed    bool            `json:"passed"`
+	Steps     []*diagnoseStep `json:"steps"`
+}
+
+// diagnose checks the steps of a connection one after another and stops at the first failure
+func (cmd *SSHCmd) diagnose(ctx context.Context, args []string) error {
+	logger := log.Default.ErrorStreamOnly()
+	report := &diagnoseReport{}
+	record := func(name string, start time.Time, err error, guidance string) bool {
+		step := &diagnoseStep{Name: name, Passed: err == nil, Duration: time.Since(start).Round(time.Millisecond).String()}
+		if err != nil {
+			step.Error = err.Error()
+			step.Guidance = guidance
+		}
+
+		report.Steps = append(report.Steps, step)
+		return err == nil
+	}
+	check := func(name, guidance string, run func() error) bool {
+		start := time.Now()
+		return record(name, start, run(), guidance)
+	}
+
+	// run the steps
+	var client client2.WorkspaceClient
+	func() {
+		ok := check("config", "Check the context and the workspace via 'devpod context list' and 'devpod list'", func() error {
+			err := config.CheckContext(cmd.Context)
+			if err != nil {
+				return err
+			}
+
+			devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
+			if err != nil {
+				return err
+			}
+
+			baseClient, err := workspace2.GetWorkspaceWithOptions(devPodConfig, args, workspace2.ResolveOptions{}, logger)
+			if err != nil {
+				return err
+			}
+
+			report.Workspace = baseClient.Workspace()
+			workspaceClient, isWorkspaceClient := baseClient.(client2.WorkspaceClient)
+			if !isWorkspaceClient {
+				return fmt.Errorf("provider '%s' is a proxy provider, which --diagnose doesn't support", baseClient.Provider())
+			}
+
+			client = workspaceClient
+			return nil
+		})
+		if !ok {
+			return
+		}
+
+		ok = check("provider", fmt.Sprintf("Log in to the provider again and check its options via 'devpod provider set-options %s'", client.Provider()), func() error {
+			return client.Preflight(ctx)
+		})
+		if !ok {
+			return
+		}
+
+		ok = check("status", fmt.Sprintf("Start the workspace via 'devpod up %s' and check 'devpod status %s' if it stays busy", client.Workspace(), client.Workspace()), func() error {
+			instanceStatus, err := client.Status(ctx, client2.StatusOptions{})
+			if err != nil {
+				return err
+			} else if instanceStatus != client2.StatusRunning {
+				return fmt.Errorf("workspace is %s", strings.ToLower(string(instanceStatus)))
+			}
+
+			return nil
+		})
+		if !ok {
+			return
+		}
+
+		ok = check("agent-info", fmt.Sprintf("Refresh the workspace config via 'devpod up %s'", client.Workspace()), func() error {
+			_, _, err := client.AgentInfoContext(ctx, provider2.CLIOptions{})
+			return err
+		})
+		if !ok {
+			return
+		}
+
+		ok = check("tunnel", fmt.Sprintf("The provider couldn't run the DevPod agent on the workspace host, run 'devpod up %s' to reinstall it", client.Workspace()), func() error {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			err := client.Command(ctx, client2.CommandOptions{
+				Command: fmt.Sprintf("'%s' version", client.AgentPath()),
+				Stdout:  stdout,
+				Stderr:  stderr,
+			})
+			if err != nil {
+				return command.WrapCommandError(stderr.Bytes(), err)
+			}
+
+			return nil
+		})
+		if !ok {
+			return
+		}
+
+		// the handler is called once the ssh handshake with the agent succeeded
+		handshakeStart := time.Now()
+		handshakeDone := false
+		err := tunnel.NewContainerTunnel(client, false, false, logger).Run(ctx, func(ctx context.Context, containerClient *ssh.Client) error {
+			handshakeDone = record("handshake", handshakeStart, nil, "")
+
+			check("agent-ready", fmt.Sprintf("The dev container isn't set up, rebuild it via 'devpod up %s --recreate'", client.Workspace()), func() error {
+				buf := &bytes.Buffer{}
+				err := devssh.Run(ctx, containerClient, fmt.Sprintf("'%s' version", agent.ContainerDevPodHelperLocation), nil, buf, buf)
+				if err != nil {
+					return fmt.Errorf("run devpod agent in the container: %s%w", buf.String(), err)
+				}
+
+				_, err = getContainerResult(ctx, containerClient)
+				return err
+			})
+			return nil
+		})
+		if !handshakeDone {
+			if err == nil {
+				err = fmt.Errorf("connection closed before the handshake")
+			}
+			record("handshake", handshakeStart, err, fmt.Sprintf("Check the workspace host and the dev container via 'devpod ssh %s --debug'", client.Workspace()))
+		} else if err != nil {
+			logger.Debugf("Error closing the tunnel: %v", err)
+		}
+	}()
+
+	// print the report
+	report.Passed = true
+	for _, step := range report.Steps {
+		report.Passed = report.Passed && step.Passed
+	}
+	if cmd.JSON {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(out))
+	} else {
+		for _, step := range report.Steps {
+			result := "PASS"
+			if !step.Passed {
+				result = "FAIL"
+			}
+
+			fmt.Printf("%s  %-12s %8s\n", result, step.Name, step.Duration)
+			if !step.Passed {
+				fmt.Printf("      %s\n      %s\n", step.Error, step.Guidance)
+			}
+		}
+	}
+	if !report.Passed {
+		return fmt.Errorf("diagnose failed at step %s", report.Steps[len(report.Steps)-1].Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+
+	devpodhttp "github.com/loft-sh/devpod/pkg/http"
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/log"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+type jsonError struct {
+	Type     string           `json:"type"`
+	Message  string           `json:"message"`
+	Cause    string           `json:"cause,omitempty"`
+	Chain    []jsonErrorCause `json:"chain,omitempty"`
+	ExitCode int              `json:"exitCode"`
+}
+
+type jsonErrorCause struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// exitCode returns the exit code of devpod ssh for the error, which is the one of the remote
+// shell or command if the session ended with it
+func exitCode(err error) int {
+	var sshExitErr *ssh.ExitError
+	var execExitErr *exec.ExitError
+	var signalErr *devssh.SignalError
+	if err == nil {
+		return 0
+	} else if errors.As(err, &sshExitErr) {
+		return sshExitErr.ExitStatus()
+	} else if errors.As(err, &signalErr) {
+		return signalErr.ExitCode()
+	} else if errors.As(err, &execExitErr) {
+		return execExitErr.ExitCode()
+	} else if errors.Is(err, devssh.ErrIdleDisconnect) {
+		return idleDisconnectExitCode
+	}
+
+	return 1
+}
+
+// printJSONError writes the error with its flattened cause chain as a single json
+// line to the given writer and returns the exit code to use
+func printJSONError(writer io.Writer, err error) int {
+	retErr := jsonError{
+		Message:  err.Error(),
+		ExitCode: exitCode(err),
+	}
+
+	for cause := err; cause != nil; cause = errors.Unwrap(cause) {
+		retErr.Type = fmt.Sprintf("%T", cause)
+		retErr.Cause = cause.Error()
+
+		// skip wrappers that only add a stack trace
+		if len(retErr.Chain) > 0 && retErr.Chain[len(retErr.Chain)-1].Message == retErr.Cause {
+			retErr.Chain[len(retErr.Chain)-1].Type = retErr.Type
+			continue
+		}
+		retErr.Chain = append(retErr.Chain, jsonErrorCause{
+			Type:    retErr.Type,
+			Message: retErr.Cause,
+		})
+	}
+
+	out, marshalErr := json.Marshal(retErr)
+	if marshalErr != nil {
+		out = []byte(fmt.Sprintf(`{"type":"marshal","message":%q,"exitCode":%d}`, err.Error(), retErr.ExitCode))
+	}
+
+	_, _ = fmt.Fprintln(writer, string(out))
+	return retErr.ExitCode
+}
+
+// explainCaptivePortal probes for a captive portal after a failed connection and explains the
+// error with the portal. Working connections never send the probe and a failed one is delayed
+// at most by its short timeout.
+func (cmd *SSHCmd) explainCaptivePortal(ctx context.Context, err error, log log.Logger) error {
+	// the session itself worked or the user stopped it
+	if cmd.NoCaptiveCheck || devssh.ConnectionError(err) == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, devpodhttp.CaptivePortalTimeout)
+	defer cancel()
+
+	portal, probeErr := devpodhttp.ProbeCaptivePortal(probeCtx, devpodhttp.CaptivePortalProbeURL)
+	if probeErr != nil {
+		log.Debugf("Error probing for a captive portal: %v", probeErr)
+	} else if portal != nil {
+		return fmt.Errorf("%s: %w", portal.Error(), err)
+	}
+
+	return err
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/loft-sh/devpod/pkg/port"
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/log"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+func (cmd *SSHCmd) forwardPorts(ctx context.Context, containerClient *ssh.Client, log log.Logger) error {
+	timeout := time.Duration(0)
+	if cmd.ForwardPortsTimeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(cmd.ForwardPortsTimeout)
+		if err != nil {
+			return fmt.Errorf("parse forward ports timeout: %w", err)
+		}
+
+		log.Infof("Using port forwarding timeout of %s", cmd.ForwardPortsTimeout)
+	}
+
+	errChan := make(chan error, len(cmd.forwards))
+	for _, forward := range cmd.forwards {
+		// start the forwarding
+		log.Infof("Forwarding %s", forward)
+		go func(forward devssh.Forward) {
+			var err error
+			switch forward.Type {
+			case devssh.ForwardTypeRemote:
+				err = devssh.ReversePortForward(ctx, containerClient, cmd.channelLimiter, forward.Bind.Address, forward.Target.Protocol, forward.Target.Address, log)
+			case devssh.ForwardTypeDynamic:
+				err = devssh.DynamicPortForward(ctx, containerClient, cmd.channelLimiter, forward.Bind.Protocol, forward.Bind.Address, log)
+			default:
+				err = devssh.PortForwardWithLimiter(ctx, containerClient, cmd.channelLimiter, forward.Bind.Protocol, forward.Bind.Address, forward.Target.Protocol, forward.Target.Address, timeout, log)
+			}
+			if err != nil {
+				errChan <- fmt.Errorf("error forwarding %s: %w", forward, err)
+			}
+		}(forward)
+	}
+
+	return <-errChan
+}
+
+// parseForwards merges the forwards of --forward-ports and --forwards-file
+func (cmd *SSHCmd) parseForwards() error {
+	cmd.forwards = []devssh.Forward{}
+	for _, portMapping := range cmd.ForwardPorts {
+		mapping, err := port.ParsePortSpec(portMapping)
+		if err != nil {
+			return fmt.Errorf("parse port mapping: %w", err)
+		}
+
+		cmd.forwards = append(cmd.forwards, devssh.Forward{Type: devssh.ForwardTypeLocal, Bind: mapping.Host, Target: mapping.Container})
+	}
+
+	if cmd.ForwardsFile != "" {
+		forwards, err := devssh.LoadForwardsFile(cmd.ForwardsFile)
+		if err != nil {
+			return err
+		}
+
+		cmd.forwards = append(cmd.forwards, forwards...)
+	}
+
+	return nil
+}
+
+// forwardDocker exposes the docker socket of the container over a local unix socket and
+// returns a func that stops the forwarding and removes the socket
+func forwardDocker(ctx context.Context, containerClient *ssh.Client, workspace string, limiter *devssh.ChannelLimiter, log log.Logger) (func(), error) {
+	buf := &bytes.Buffer{}
+	err := devssh.Run(ctx, containerClient, fmt.Sprintf("test -S '%s'", remoteDockerSocket), nil, buf, buf)
+	if err != nil {
+		return nil, fmt.Errorf("no docker socket found at %s in the workspace", remoteDockerSocket)
+	}
+
+	// only the current user should be able to access the socket
+	socketDir := filepath.Join(os.TempDir(), "devpod-"+workspace)
+	err = os.MkdirAll(socketDir, 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "create docker socket dir")
+	}
+	err = os.Chmod(socketDir, 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "create docker socket dir")
+	}
+
+	// remove a stale socket of a previous session
+	socketPath := filepath.Join(socketDir, "docker.sock")
+	_ = os.Remove(socketPath)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		err := devssh.PortForwardWithLimiter(cancelCtx, containerClient, limiter, "unix", socketPath, "unix", remoteDockerSocket, 0, log)
+		if err != nil && cancelCtx.Err() == nil {
+			log.Errorf("Error forwarding docker socket: %v", err)
+		}
+	}()
+
+	log.Warnf("Forwarding the docker daemon of the workspace, everyone with access to %s has full control over it", socketPath)
+	log.Infof("Use it via: export DOCKER_HOST=unix://%s", socketPath)
+	return func() {
+		cancel()
+		<-done
+		_ = os.Remove(socketPath)
+	}, nil
+}
+
+// setupGPG imports the local public keys into the keyring of the workspace user, as gpg needs them
+// to use the secret keys of the forwarded agent, and configures the local signing key for git
+func (cmd *SSHCmd) setupGPG(ctx context.Context, containerClient *ssh.Client, log log.Logger) error {
+	err := runAsUser(ctx, containerClient, cmd.User, "command -v gpg", nil, io.Discard)
+	if err != nil {
+		return fmt.Errorf("--forward-gpg requires gpg in the workspace")
+	}
+
+	// export only the signing key if there is one
+	signingKey := ""
+	out, err := exec.Command("git", "config", "--get", "user.signingkey").Output()
+	if err == nil {
+		signingKey = strings.TrimSpace(string(out))
+	}
+	exportArgs := []string{"--export"}
+	if signingKey != "" {
+		exportArgs = append(exportArgs, signingKey)
+	}
+	publicKeys, err := exec.Command("gpg", exportArgs...).Output()
+	if err != nil {
+		return fmt.Errorf("export gpg public keys: %w", err)
+	} else if len(publicKeys) == 0 {
+		return fmt.Errorf("no gpg public keys found to forward")
+	}
+
+	err = runAsUser(ctx, containerClient, cmd.User, "gpg --batch --quiet --import", bytes.NewReader(publicKeys), io.Discard)
+	if err != nil {
+		return errors.Wrap(err, "import gpg public keys")
+	}
+
+	// configure git to sign like locally
+	if signingKey != "" {
+		gitConfig := fmt.Sprintf("git config --global user.signingkey '%s'", signingKey)
+		out, err = exec.Command("git", "config", "--get", "commit.gpgsign").Output()
+		if err == nil && strings.TrimSpace(string(out)) == "true" {
+			gitConfig += " && git config --global commit.gpgsign true"
+		}
+
+		err = runAsUser(ctx, containerClient, cmd.User, "if command -v git >/dev/null; then "+gitConfig+"; fi", nil, io.Discard)
+		if err != nil {
+			return errors.Wrap(err, "configure git signing")
+		}
+	}
+
+	log.Debugf("Forwarding gpg agent %s", cmd.gpgAgentSocket)
+	return nil
+}
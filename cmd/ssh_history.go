@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	client2 "github.com/loft-sh/devpod/pkg/client"
+	"github.com/loft-sh/devpod/pkg/config"
+	provider2 "github.com/loft-sh/devpod/pkg/provider"
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/devpod/pkg/types"
+	workspace2 "github.com/loft-sh/devpod/pkg/workspace"
+	"github.com/loft-sh/log"
+	"github.com/loft-sh/log/table"
+)
+
+func recordConnectionHistory(client client2.BaseWorkspaceClient, user string, start time.Time, err error, log log.Logger) {
+	entry := provider2.ConnectionHistoryEntry{
+		Timestamp: types.NewTime(start),
+		User:      user,
+		Duration:  time.Since(start).Round(time.Second).String(),
+		Success:   devssh.ConnectionError(err) == nil,
+	}
+	if entry.Success {
+		entry.ExitCode = exitCode(err)
+	} else {
+		entry.Error = err.Error()
+	}
+
+	historyErr := provider2.AppendConnectionHistory(client.Context(), client.Workspace(), entry)
+	if historyErr != nil {
+		log.Debugf("Error writing connection history: %v", historyErr)
+	}
+}
+
+// printConnectionHistory prints the connection history of the given workspaces, or of all
+// workspaces in the context if none are given, newest entry first
+func printConnectionHistory(devPodConfig *config.Config, workspaceIDs []string, since string, logger log.Logger) error {
+	sinceTime, err := parseSince(since, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if len(workspaceIDs) == 0 {
+		workspaces, err := workspace2.ListWorkspaces(devPodConfig, logger)
+		if err != nil {
+			return err
+		}
+
+		for _, workspace := range workspaces {
+			workspaceIDs = append(workspaceIDs, workspace.ID)
+		}
+	}
+
+	type historyEntry struct {
+		workspace string
+		provider2.ConnectionHistoryEntry
+	}
+	entries := []historyEntry{}
+	for _, workspaceID := range workspaceIDs {
+		history, err := provider2.LoadConnectionHistory(devPodConfig.DefaultContext, workspaceID)
+		if err != nil {
+			return fmt.Errorf("load connection history of workspace %s: %w", workspaceID, err)
+		}
+
+		for _, entry := range history {
+			if !entry.Timestamp.Time.Before(sinceTime) {
+				entries = append(entries, historyEntry{workspace: workspaceID, ConnectionHistoryEntry: entry})
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Time.After(entries[j].Timestamp.Time)
+	})
+
+	tableEntries := [][]string{}
+	for _, entry := range entries {
+		status := "Success"
+		if !entry.Success {
+			status = "Failed: " + entry.Error
+		} else if entry.ExitCode != 0 {
+			status = fmt.Sprintf("Success (exit code %d)", entry.ExitCode)
+		}
+
+		tableEntries = append(tableEntries, []string{
+			entry.workspace,
+			entry.Timestamp.Time.Format(time.RFC3339),
+			entry.User,
+			entry.Duration,
+			status,
+		})
+	}
+	table.PrintTable(log.Default, []string{
+		"Workspace",
+		"Connected",
+		"User",
+		"Duration",
+		"Status",
+	}, tableEntries)
+	return nil
+}
+
+// parseSince parses a relative duration or an absolute RFC3339 timestamp, an empty value
+// returns the zero time
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if since == "" {
+		return time.Time{}, nil
+	}
+
+	duration, err := time.ParseDuration(since)
+	if err == nil {
+		if duration < 0 {
+			return time.Time{}, fmt.Errorf("--since %s needs to be a positive duration", since)
+		}
+
+		return now.Add(-duration), nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse --since %s: expected a duration like 24h or a RFC3339 timestamp like 2024-01-02T15:04:05Z", since)
+	}
+
+	return timestamp, nil
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	client2 "github.com/loft-sh/devpod/pkg/client"
+	"github.com/loft-sh/devpod/pkg/config"
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/devpod/pkg/tunnel"
+	workspace2 "github.com/loft-sh/devpod/pkg/workspace"
+	"github.com/loft-sh/log"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// copyID appends the public key from --copy-id to the authorized_keys of the workspace user,
+// similar to ssh-copy-id. Keys that are already authorized are not added again.
+func (cmd *SSHCmd) copyID(ctx context.Context, containerClient *ssh.Client, log log.Logger) error {
+	out, err := os.ReadFile(cmd.CopyID)
+	if err != nil {
+		return errors.Wrap(err, "read public key")
+	}
+	key, comment, _, _, err := ssh.ParseAuthorizedKey(out)
+	if err != nil {
+		return fmt.Errorf("parse public key %s: %w", cmd.CopyID, err)
+	}
+
+	// check if the key is already authorized
+	authorizedKeys := &bytes.Buffer{}
+	err = runAsUser(ctx, containerClient, cmd.User, "cat ~/.ssh/authorized_keys 2>/dev/null || true", nil, authorizedKeys)
+	if err != nil {
+		return errors.Wrap(err, "read authorized keys")
+	} else if devssh.HasAuthorizedKey(authorizedKeys.Bytes(), key) {
+		log.Infof("Key %s is already authorized for user %s", cmd.CopyID, cmd.User)
+		return nil
+	}
+
+	entry := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+	if comment != "" {
+		entry += " " + comment
+	}
+	entry += "\n"
+	if authorizedKeys.Len() > 0 && !bytes.HasSuffix(authorizedKeys.Bytes(), []byte("\n")) {
+		entry = "\n" + entry
+	}
+
+	err = runAsUser(ctx, containerClient, cmd.User, "mkdir -p ~/.ssh && chmod 700 ~/.ssh && cat >> ~/.ssh/authorized_keys && chmod 600 ~/.ssh/authorized_keys", strings.NewReader(entry), io.Discard)
+	if err != nil {
+		return errors.Wrap(err, "add authorized key")
+	}
+
+	log.Donef("Added key %s to the authorized keys of user %s", cmd.CopyID, cmd.User)
+	log.Warnf("The key grants persistent access to the workspace as user %s until it is removed from ~/.ssh/authorized_keys", cmd.User)
+	return nil
+}
+
+// rotateKey replaces the DevPod key pair and backs up the old one. With --reinject-key, the new
+// public key replaces the old one in the authorized_keys of every running workspace.
+func (cmd *SSHCmd) rotateKey(ctx context.Context, devPodConfig *config.Config, log log.Logger) error {
+	var oldKey ssh.PublicKey
+	oldPublicKey, err := os.ReadFile(filepath.Join(devssh.GetDevPodKeysDir(), devssh.DevPodSSHPublicKeyFile))
+	if err == nil {
+		oldKey, _, _, _, _ = ssh.ParseAuthorizedKey(oldPublicKey)
+	}
+
+	backupDir, err := devssh.RotateDevPodKey()
+	if err != nil {
+		return errors.Wrap(err, "rotate key")
+	} else if backupDir != "" {
+		log.Donef("Rotated the DevPod key, the old key was backed up to %s", backupDir)
+	} else {
+		log.Donef("Generated a new DevPod key")
+	}
+	if !cmd.ReinjectKey {
+		log.Warnf("Workspaces that authorize the old key are unreachable with it until they are provisioned again, use --reinject-key to update running workspaces")
+		return nil
+	}
+
+	newPublicKey, err := os.ReadFile(filepath.Join(devssh.GetDevPodKeysDir(), devssh.DevPodSSHPublicKeyFile))
+	if err != nil {
+		return errors.Wrap(err, "read public ssh key")
+	}
+	newKey, _, _, _, err := ssh.ParseAuthorizedKey(newPublicKey)
+	if err != nil {
+		return errors.Wrap(err, "parse public ssh key")
+	}
+
+	workspaces, err := workspace2.ListWorkspaces(devPodConfig, log)
+	if err != nil {
+		return err
+	}
+
+	skipped := []string{}
+	for _, workspace := range workspaces {
+		err = reinjectKey(ctx, devPodConfig, workspace.ID, oldKey, newKey, log)
+		if err != nil {
+			log.Warnf("Couldn't update the key in workspace %s: %v", workspace.ID, err)
+			skipped = append(skipped, workspace.ID)
+			continue
+		}
+
+		log.Donef("Updated the key in workspace %s", workspace.ID)
+	}
+	if len(skipped) > 0 {
+		log.Warnf("Workspaces %s are unreachable with the old key until they are provisioned again", strings.Join(skipped, ", "))
+	}
+
+	return nil
+}
+
+// reinjectKey replaces oldKey with newKey in the authorized_keys of the workspace user if the
+// workspace is running
+func reinjectKey(ctx context.Context, devPodConfig *config.Config, workspaceID string, oldKey, newKey ssh.PublicKey, log log.Logger) error {
+	baseClient, err := workspace2.GetWorkspace(devPodConfig, []string{workspaceID}, false, log)
+	if err != nil {
+		return err
+	}
+	workspaceClient, ok := baseClient.(client2.WorkspaceClient)
+	if !ok {
+		return fmt.Errorf("workspace is not reachable via ssh")
+	}
+
+	instanceStatus, err := workspaceClient.Status(ctx, client2.StatusOptions{})
+	if err != nil {
+		return err
+	} else if instanceStatus != client2.StatusRunning {
+		return fmt.Errorf("workspace is %s", strings.ToLower(string(instanceStatus)))
+	}
+
+	user, err := devssh.GetConfiguredUser(workspaceID)
+	if err != nil {
+		return err
+	}
+
+	return tunnel.NewContainerTunnel(workspaceClient, false, false, log).Run(ctx, func(ctx context.Context, containerClient *ssh.Client) error {
+		if user == "" {
+			user = getRemoteUser(ctx, containerClient, log)
+		}
+
+		authorizedKeys := &bytes.Buffer{}
+		err := runAsUser(ctx, containerClient, user, "cat ~/.ssh/authorized_keys 2>/dev/null || true", nil, authorizedKeys)
+		if err != nil {
+			return errors.Wrap(err, "read authorized keys")
+		}
+
+		newAuthorizedKeys := devssh.ReplaceAuthorizedKey(authorizedKeys.Bytes(), oldKey, newKey)
+		err = runAsUser(ctx, containerClient, user, "mkdir -p ~/.ssh && chmod 700 ~/.ssh && cat > ~/.ssh/authorized_keys.devpod && chmod 600 ~/.ssh/authorized_keys.devpod && mv ~/.ssh/authorized_keys.devpod ~/.ssh/authorized_keys", bytes.NewReader(newAuthorizedKeys), io.Discard)
+		if err != nil {
+			return errors.Wrap(err, "write authorized keys")
+		}
+
+		return nil
+	})
+}
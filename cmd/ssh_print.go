@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alessio/shellescape"
+	client2 "github.com/loft-sh/devpod/pkg/client"
+	"github.com/loft-sh/devpod/pkg/config"
+	provider2 "github.com/loft-sh/devpod/pkg/provider"
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/log"
+)
+
+func (cmd *SSHCmd) printProxyCommand(client client2.BaseWorkspaceClient) error {
+	user := cmd.User
+	if user == "" {
+		var err error
+		user, err = devssh.GetUser(client.Workspace())
+		if err != nil {
+			return err
+		}
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(devssh.ProxyCommand(execPath, client.Context(), user, client.Workspace()))
+	return nil
+}
+
+// printSSHCommand prints a plain ssh command with the user, key, agent forwarding, forwards and
+// command of this invocation that connects through the ProxyCommand
+func (cmd *SSHCmd) printSSHCommand(devPodConfig *config.Config, client client2.BaseWorkspaceClient) error {
+	if len(cmd.Commands) > 1 {
+		return fmt.Errorf("--print-ssh-command supports a single --command only")
+	}
+
+	user := cmd.User
+	if user == "" {
+		var err error
+		user, err = devssh.GetUser(client.Workspace())
+		if err != nil {
+			return err
+		}
+	}
+
+	err := cmd.parseForwards()
+	if err != nil {
+		return err
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	options := devssh.OpenSSHCommandOptions{
+		ProxyCommand:    devssh.ProxyCommand(execPath, client.Context(), user, client.Workspace()),
+		Host:            client.Workspace() + ".devpod",
+		User:            user,
+		AgentForwarding: cmd.AgentForwarding && devPodConfig.ContextOption(config.ContextOptionSSHAgentForwarding) == "true",
+		Forwards:        cmd.forwards,
+	}
+	if len(cmd.Commands) == 1 {
+		options.Command = cmd.Commands[0]
+	}
+
+	// the key is only used if it exists, like in the ssh config
+	identityFile := filepath.Join(devssh.GetDevPodKeysDir(), devssh.DevPodSSHPrivateKeyFile)
+	_, err = os.Stat(identityFile)
+	if err == nil {
+		options.IdentityFile = identityFile
+	}
+
+	fmt.Println(shellescape.QuoteCommand(devssh.OpenSSHCommand(options)))
+	return nil
+}
+
+// printBanner prints the connect banner of the workspace unless it was acknowledged today
+func (cmd *SSHCmd) printBanner(client client2.WorkspaceClient, log log.Logger) {
+	banner := strings.TrimSpace(client.WorkspaceConfig().SSH.Banner)
+	if banner == "" {
+		return
+	}
+
+	acknowledged, err := provider2.BannerAcknowledged(client.Context(), client.Workspace(), banner)
+	if err != nil {
+		log.Debugf("Error reading banner acknowledgement: %v", err)
+	} else if !acknowledged {
+		fmt.Fprintf(os.Stderr, "%s\n\n", banner)
+	}
+
+	if cmd.AckBanner {
+		err = provider2.AcknowledgeBanner(client.Context(), client.Workspace(), banner)
+		if err != nil {
+			log.Debugf("Error acknowledging banner: %v", err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/log"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// syncTask is a copy of --sync-down or --sync-up
+type syncTask struct {
+	spec devssh.SyncSpec
+	up   bool
+}
+
+// parseSyncs parses --sync-down and --sync-up, downloads run before uploads
+func (cmd *SSHCmd) parseSyncs() error {
+	cmd.syncs = []syncTask{}
+	for _, value := range cmd.SyncDown {
+		spec, err := devssh.ParseSyncSpec(value)
+		if err != nil {
+			return fmt.Errorf("parse --sync-down: %w", err)
+		}
+
+		cmd.syncs = append(cmd.syncs, syncTask{spec: spec})
+	}
+	for _, value := range cmd.SyncUp {
+		spec, err := devssh.ParseSyncSpec(value)
+		if err != nil {
+			return fmt.Errorf("parse --sync-up: %w", err)
+		}
+
+		cmd.syncs = append(cmd.syncs, syncTask{spec: spec, up: true})
+	}
+
+	return nil
+}
+
+// syncFiles copies the files of --sync-down and --sync-up over sftp as the session user
+func (cmd *SSHCmd) syncFiles(ctx context.Context, containerClient *ssh.Client, command string, writer io.Writer, log log.Logger) error {
+	sshClient, closeClient, err := cmd.newContainerSSHClient(ctx, containerClient, command, writer, log)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	sftpClient, err := sftp.NewClient(sshClient)
+	if err != nil {
+		return errors.Wrap(err, "start sftp")
+	}
+	defer sftpClient.Close()
+
+	for _, task := range cmd.syncs {
+		var stats devssh.SyncStats
+		if task.up {
+			log.Infof("Syncing %s to %s in the workspace", task.spec.Local, task.spec.Remote)
+			stats, err = devssh.SyncUp(sftpClient, task.spec, log)
+		} else {
+			log.Infof("Syncing %s in the workspace to %s", task.spec.Remote, task.spec.Local)
+			stats, err = devssh.SyncDown(sftpClient, task.spec, log)
+		}
+		if err != nil {
+			return fmt.Errorf("sync %s: %w", task.spec.Remote, err)
+		}
+
+		log.Donef("Synced %s", stats)
+	}
+
+	return nil
+}
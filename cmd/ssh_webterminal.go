@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/log"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// serveWebTerminal serves the session to a web terminal on --ws-addr, it can be opened once with
+// the printed url and token
+func (cmd *SSHCmd) serveWebTerminal(ctx context.Context, containerClient *ssh.Client, command string, writer io.Writer, log log.Logger) error {
+	listener, err := net.Listen("tcp", cmd.WSAddr)
+	if err != nil {
+		return errors.Wrap(err, "listen for web terminal")
+	}
+	defer listener.Close()
+
+	token := make([]byte, 32)
+	_, err = rand.Read(token)
+	if err != nil {
+		return err
+	}
+
+	// start the ssh server in the container and a session as the user
+	sshClient, closeClient, err := cmd.newContainerSSHClient(ctx, containerClient, command, writer, log)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return devssh.ChannelError(err)
+	}
+	defer session.Close()
+
+	err = devssh.SetEnv(session, cmd.env)
+	if err != nil {
+		return err
+	}
+
+	sessionCommand := ""
+	if len(cmd.Commands) == 1 {
+		sessionCommand = cmd.limits.WrapCommand(cmd.Commands[0])
+	} else if cmd.tmuxSession != "" {
+		sessionCommand = tmuxCommand(cmd.tmuxSession)
+	}
+
+	fmt.Fprintf(os.Stdout, "ws://%s/\n%s\n", listener.Addr().String(), hex.EncodeToString(token))
+	log.Infof("Serving the terminal over WebSocket on %s, the token above can be used once as subprotocol next to %s", listener.Addr().String(), devssh.WebTerminalProtocol)
+	return devssh.ServeWebTerminal(ctx, listener, hex.EncodeToString(token), cmd.WSOrigins, session, sessionCommand, log)
+}
@@ -0,0 +1,54 @@
+package sshtemplate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/loft-sh/devpod/cmd/flags"
+	"github.com/loft-sh/devpod/pkg/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// DeleteCmd holds the delete cmd flags
+type DeleteCmd struct {
+	flags.GlobalFlags
+}
+
+// NewDeleteCmd creates a new command
+func NewDeleteCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &DeleteCmd{
+		GlobalFlags: *flags,
+	}
+	deleteCmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete a connection template",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("please specify the template to delete")
+			}
+
+			return cmd.Run(context.Background(), args[0])
+		},
+	}
+
+	return deleteCmd
+}
+
+// Run runs the command logic
+func (cmd *DeleteCmd) Run(ctx context.Context, name string) error {
+	devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
+	if err != nil {
+		return err
+	} else if devPodConfig.Current().SSHTemplates[name] == nil {
+		return fmt.Errorf("template %s doesn't exist", name)
+	}
+
+	delete(devPodConfig.Current().SSHTemplates, name)
+	err = config.SaveConfig(devPodConfig)
+	if err != nil {
+		return errors.Wrap(err, "save config")
+	}
+
+	return nil
+}
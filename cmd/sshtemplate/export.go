@@ -0,0 +1,64 @@
+package sshtemplate
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ghodss/yaml"
+	"github.com/loft-sh/devpod/cmd/flags"
+	"github.com/loft-sh/devpod/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// ExportCmd holds the export cmd flags
+type ExportCmd struct {
+	flags.GlobalFlags
+
+	Output string
+}
+
+// NewExportCmd creates a new command
+func NewExportCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &ExportCmd{
+		GlobalFlags: *flags,
+	}
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a connection template as yaml, e.g. to share it via a repository",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("please specify the template to export")
+			}
+
+			return cmd.Run(context.Background(), args[0])
+		},
+	}
+
+	exportCmd.Flags().StringVar(&cmd.Output, "output", "", "The file to write the template to, defaults to stdout")
+	return exportCmd
+}
+
+// Run runs the command logic
+func (cmd *ExportCmd) Run(ctx context.Context, name string) error {
+	devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
+	if err != nil {
+		return err
+	}
+
+	template := devPodConfig.Current().SSHTemplates[name]
+	if template == nil {
+		return fmt.Errorf("template %s doesn't exist", name)
+	}
+
+	out, err := yaml.Marshal(&config.SSHTemplateFile{Name: name, SSHTemplate: *template})
+	if err != nil {
+		return err
+	}
+	if cmd.Output == "" {
+		fmt.Print(string(out))
+		return nil
+	}
+
+	return os.WriteFile(cmd.Output, out, 0644)
+}
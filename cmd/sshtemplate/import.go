@@ -0,0 +1,73 @@
+package sshtemplate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/loft-sh/devpod/cmd/flags"
+	"github.com/loft-sh/devpod/pkg/config"
+	"github.com/loft-sh/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// ImportCmd holds the import cmd flags
+type ImportCmd struct {
+	flags.GlobalFlags
+
+	Name string
+}
+
+// NewImportCmd creates a new command
+func NewImportCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &ImportCmd{
+		GlobalFlags: *flags,
+	}
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import a connection template from a file, an existing template with the same name is replaced",
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("please specify the template file to import")
+			}
+
+			return cmd.Run(context.Background(), args[0])
+		},
+	}
+
+	importCmd.Flags().StringVar(&cmd.Name, "name", "", "The name to import the template as, defaults to the name in the file")
+	return importCmd
+}
+
+// Run runs the command logic
+func (cmd *ImportCmd) Run(ctx context.Context, path string) error {
+	devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
+	if err != nil {
+		return err
+	}
+
+	templateFile, err := config.LoadSSHTemplateFile(path)
+	if err != nil {
+		return err
+	}
+	if cmd.Name != "" {
+		err = config.ValidateSSHTemplateName(cmd.Name)
+		if err != nil {
+			return err
+		}
+
+		templateFile.Name = cmd.Name
+	}
+
+	if devPodConfig.Current().SSHTemplates == nil {
+		devPodConfig.Current().SSHTemplates = map[string]*config.SSHTemplate{}
+	}
+	devPodConfig.Current().SSHTemplates[templateFile.Name] = &templateFile.SSHTemplate
+	err = config.SaveConfig(devPodConfig)
+	if err != nil {
+		return errors.Wrap(err, "save config")
+	}
+
+	log.Default.Donef("Imported template %s, use it via 'devpod ssh --template %s'", templateFile.Name, templateFile.Name)
+	return nil
+}
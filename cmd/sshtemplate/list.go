@@ -0,0 +1,67 @@
+package sshtemplate
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/loft-sh/devpod/cmd/flags"
+	"github.com/loft-sh/devpod/pkg/config"
+	"github.com/loft-sh/log"
+	"github.com/loft-sh/log/table"
+	"github.com/spf13/cobra"
+)
+
+// ListCmd holds the list cmd flags
+type ListCmd struct {
+	flags.GlobalFlags
+}
+
+// NewListCmd creates a new command
+func NewListCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &ListCmd{
+		GlobalFlags: *flags,
+	}
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the connection templates of the context",
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return cmd.Run(context.Background())
+		},
+	}
+
+	return listCmd
+}
+
+// Run runs the command logic
+func (cmd *ListCmd) Run(ctx context.Context) error {
+	devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
+	if err != nil {
+		return err
+	}
+
+	tableEntries := [][]string{}
+	for _, name := range devPodConfig.Current().SSHTemplateNames() {
+		template := devPodConfig.Current().SSHTemplates[name]
+		flagNames := []string{}
+		for flagName := range template.Flags {
+			flagNames = append(flagNames, "--"+flagName)
+		}
+		sort.Strings(flagNames)
+
+		tableEntries = append(tableEntries, []string{
+			name,
+			template.Description,
+			strings.Join(flagNames, " "),
+		})
+	}
+
+	table.PrintTable(log.Default, []string{
+		"Name",
+		"Description",
+		"Flags",
+	}, tableEntries)
+	return nil
+}
@@ -0,0 +1,20 @@
+package sshtemplate
+
+import (
+	"github.com/loft-sh/devpod/cmd/flags"
+	"github.com/spf13/cobra"
+)
+
+// NewSSHTemplateCmd returns a new command
+func NewSSHTemplateCmd(flags *flags.GlobalFlags) *cobra.Command {
+	sshTemplateCmd := &cobra.Command{
+		Use:   "ssh-template",
+		Short: "DevPod connection template commands",
+	}
+
+	sshTemplateCmd.AddCommand(NewListCmd(flags))
+	sshTemplateCmd.AddCommand(NewImportCmd(flags))
+	sshTemplateCmd.AddCommand(NewExportCmd(flags))
+	sshTemplateCmd.AddCommand(NewDeleteCmd(flags))
+	return sshTemplateCmd
+}
@@ -47,10 +47,13 @@ type UpCmd struct {
 
 	ProviderOptions []string
 
-	ConfigureSSH bool
-	OpenIDE      bool
+	ConfigureSSH      bool
+	SSHIdentitiesOnly bool
+	SSHTag            bool
+	OpenIDE           bool
 
 	SSHConfigPath string
+	SSHHostAlias  string
 
 	DotfilesSource string
 	DotfilesScript string
@@ -82,6 +85,8 @@ func NewUpCmd(flags *flags.GlobalFlags) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			cmd.ForwardAllowlist = devPodConfig.ContextOption(config.ContextOptionSSHForwardAllowlist)
+			cmd.ForwardDenylist = devPodConfig.ContextOption(config.ContextOptionSSHForwardDenylist)
 
 			var source *provider2.WorkspaceSource
 			if cmd.Source != "" {
@@ -115,6 +120,9 @@ func NewUpCmd(flags *flags.GlobalFlags) *cobra.Command {
 	}
 
 	upCmd.Flags().BoolVar(&cmd.ConfigureSSH, "configure-ssh", true, "If true will configure the ssh config to include the DevPod workspace")
+	upCmd.Flags().StringVar(&cmd.SSHHostAlias, "host-alias", "", "An additional ssh host alias for the workspace in the ssh config, e.g. my-project. The alias must not be used by another host")
+	upCmd.Flags().BoolVar(&cmd.SSHIdentitiesOnly, "ssh-identities-only", true, "If true will only allow the DevPod key for the workspace in the ssh config, disable this if you manage the identities yourself")
+	upCmd.Flags().BoolVar(&cmd.SSHTag, "ssh-tag", true, "If true will tag the workspace host in the ssh config with 'Tag devpod', so it can be matched via 'Match tagged devpod'. Only used with OpenSSH 9.4 or newer")
 	upCmd.Flags().StringVar(&cmd.SSHConfigPath, "ssh-config", "", "The path to the ssh config to modify, if empty will use ~/.ssh/config")
 	upCmd.Flags().StringVar(&cmd.DotfilesSource, "dotfiles", "", "The path or url to the dotfiles to use in the container")
 	upCmd.Flags().StringVar(&cmd.DotfilesScript, "dotfiles-script", "", "The path in dotfiles directory to use to install the dotfiles, if empty will try to guess")
@@ -164,16 +172,20 @@ func (cmd *UpCmd) Run(
 
 	// configure container ssh
 	if cmd.ConfigureSSH {
-		err = configureSSH(client, cmd.SSHConfigPath, user)
+		err = configureSSH(client, cmd.SSHConfigPath, user, cmd.SSHHostAlias, cmd.SSHIdentitiesOnly, cmd.SSHTag)
 		if err != nil {
 			return err
 		}
 
-		log.Infof("Run 'ssh %s.devpod' to ssh into the devcontainer", client.Workspace())
+		sshHost := client.Workspace() + ".devpod"
+		if cmd.SSHHostAlias != "" {
+			sshHost = cmd.SSHHostAlias
+		}
+		log.Infof("Run 'ssh %s' to ssh into the devcontainer", sshHost)
 	}
 
 	// setup dotfiles in the container
-	err = setupDotfiles(cmd.DotfilesSource, cmd.DotfilesScript, client, devPodConfig, log)
+	err = setupDotfiles(cmd.DotfilesSource, cmd.DotfilesScript, false, client, devPodConfig, log)
 	if err != nil {
 		return err
 	}
@@ -325,6 +337,7 @@ func (cmd *UpCmd) devPodUpProxy(
 		true,
 		client.WorkspaceConfig(),
 		log,
+		tunnelserver.WithForwardPolicy(tunnelserver.ParseForwardPolicy(cmd.ForwardAllowlist, cmd.ForwardDenylist)),
 	)
 	if err != nil {
 		return nil, errors.Wrap(err, "run tunnel machine")
@@ -339,7 +352,7 @@ func (cmd *UpCmd) devPodUpMachine(
 	client client2.WorkspaceClient,
 	log log.Logger,
 ) (*config2.Result, error) {
-	err := startWait(ctx, client, true, log)
+	err := startWait(ctx, client, true, false, defaultBusyWarning, workspace2.DefaultPollInterval(), 0, log)
 	if err != nil {
 		return nil, err
 	}
@@ -443,6 +456,7 @@ func (cmd *UpCmd) devPodUpMachine(
 			client.AgentInjectDockerCredentials(),
 			client.WorkspaceConfig(),
 			log,
+			tunnelserver.WithForwardPolicy(tunnelserver.ParseForwardPolicy(cmd.ForwardAllowlist, cmd.ForwardDenylist)),
 		)
 		if err != nil {
 			return nil, errors.Wrap(err, "run tunnel machine")
@@ -656,7 +670,9 @@ func startBrowserTunnel(
 				ctx,
 				devPodConfig,
 				containerClient,
-				user,
+				[]string{user},
+				"",
+				"",
 				forwardPorts,
 				true,
 				true,
@@ -678,12 +694,15 @@ func startBrowserTunnel(
 	return nil
 }
 
-func configureSSH(client client2.BaseWorkspaceClient, configPath, user string) error {
+func configureSSH(client client2.BaseWorkspaceClient, configPath, user, hostAlias string, identitiesOnly, tag bool) error {
 	err := devssh.ConfigureSSHConfig(
 		configPath,
 		client.Context(),
 		client.Workspace(),
 		user,
+		hostAlias,
+		identitiesOnly,
+		tag,
 		log.Default,
 	)
 	if err != nil {
@@ -740,6 +759,7 @@ func createSSHCommand(
 
 func setupDotfiles(
 	dotfiles, script string,
+	force bool,
 	client client2.BaseWorkspaceClient,
 	devPodConfig *config.Config,
 	log log.Logger,
@@ -786,6 +806,10 @@ func setupDotfiles(
 		agentArguments = append(agentArguments, dotfilesScript)
 	}
 
+	if force {
+		agentArguments = append(agentArguments, "--force")
+	}
+
 	remoteUser, err := devssh.GetUser(client.Workspace())
 	if err != nil {
 		remoteUser = "root"
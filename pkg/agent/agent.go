@@ -30,6 +30,15 @@ const RemoteDevPodHelperLocation = "/tmp/devpod"
 
 const ContainerActivityFile = "/tmp/devpod.activity"
 
+// ContainerIdleTimeoutFile holds the inactivity timeout requested by the last ssh session
+const ContainerIdleTimeoutFile = "/tmp/devpod.idle-timeout"
+
+// ContainerKeepRunningFile exists as long as a session requested to keep the container running
+const ContainerKeepRunningFile = "/tmp/devpod.keep-running"
+
+// ContainerIDEReadyFile is created as soon as the IDE was installed in the container
+const ContainerIDEReadyFile = "/tmp/devpod.ide-ready"
+
 const defaultAgentDownloadURL = "https://github.com/loft-sh/devpod/releases/download/"
 
 const EnvDevPodAgentURL = "DEVPOD_AGENT_URL"
@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/loft-sh/devpod/pkg/dockercredentials"
+	"github.com/loft-sh/devpod/pkg/gitcredentials"
+	perrors "github.com/pkg/errors"
+)
+
+const (
+	GitCredentialProviderName    = "git"
+	DockerCredentialProviderName = "docker"
+)
+
+// CredentialProvider answers credential requests that are forwarded from
+// the workspace to the host. Providers other than git and docker are enabled by
+// name, the workspace requests them via the Credentials call of the tunnel.
+type CredentialProvider interface {
+	// Name returns the unique name of the credential provider
+	Name() string
+
+	// Handle answers the given json encoded request with a json encoded response
+	Handle(ctx context.Context, request string) (string, error)
+}
+
+// CredentialTargeter can optionally be implemented by a CredentialProvider to
+// name the target of a request, e.g. the git host or docker registry
+type CredentialTargeter interface {
+	// Target returns the target of the given json encoded request
+	Target(request string) string
+}
+
+var (
+	credentialProvidersLock sync.Mutex
+	credentialProviders     = map[string]CredentialProvider{}
+)
+
+func init() {
+	RegisterCredentialProvider(&gitCredentialProvider{})
+	RegisterCredentialProvider(&dockerCredentialProvider{})
+}
+
+// RegisterCredentialProvider registers a credential provider, an existing
+// provider with the same name is replaced
+func RegisterCredentialProvider(provider CredentialProvider) {
+	credentialProvidersLock.Lock()
+	defer credentialProvidersLock.Unlock()
+
+	credentialProviders[provider.Name()] = provider
+}
+
+// GetCredentialProvider returns the registered credential provider with the given name
+func GetCredentialProvider(name string) (CredentialProvider, error) {
+	credentialProvidersLock.Lock()
+	defer credentialProvidersLock.Unlock()
+
+	provider, ok := credentialProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("credential provider %s is not registered", name)
+	}
+
+	return provider, nil
+}
+
+// CredentialProviderNames returns the names of all registered credential providers
+func CredentialProviderNames() []string {
+	credentialProvidersLock.Lock()
+	defer credentialProvidersLock.Unlock()
+
+	names := []string{}
+	for name := range credentialProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+type gitCredentialProvider struct{}
+
+func (g *gitCredentialProvider) Name() string {
+	return GitCredentialProviderName
+}
+
+func (g *gitCredentialProvider) Target(request string) string {
+	credentials := &gitcredentials.GitCredentials{}
+	err := json.Unmarshal([]byte(request), credentials)
+	if err != nil {
+		return ""
+	}
+
+	return credentials.Host
+}
+
+func (g *gitCredentialProvider) Handle(ctx context.Context, request string) (string, error) {
+	credentials := &gitcredentials.GitCredentials{}
+	err := json.Unmarshal([]byte(request), credentials)
+	if err != nil {
+		return "", perrors.Wrap(err, "decode git credentials request")
+	}
+
+	response, err := gitcredentials.GetCredentials(credentials)
+	if err != nil {
+		return "", perrors.Wrap(err, "get git response")
+	}
+
+	out, err := json.Marshal(response)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+type dockerCredentialProvider struct{}
+
+func (d *dockerCredentialProvider) Name() string {
+	return DockerCredentialProviderName
+}
+
+func (d *dockerCredentialProvider) Target(request string) string {
+	dockerRequest := &dockercredentials.Request{}
+	err := json.Unmarshal([]byte(request), dockerRequest)
+	if err != nil {
+		return ""
+	}
+
+	// only use the registry host as target
+	serverURL := dockerRequest.ServerURL
+	if parsedURL, err := url.Parse(serverURL); err == nil && parsedURL.Host != "" {
+		return parsedURL.Host
+	}
+
+	return strings.Split(serverURL, "/")[0]
+}
+
+func (d *dockerCredentialProvider) Handle(ctx context.Context, request string) (string, error) {
+	dockerRequest := &dockercredentials.Request{}
+	err := json.Unmarshal([]byte(request), dockerRequest)
+	if err != nil {
+		return "", err
+	}
+
+	// check if list or get
+	if dockerRequest.ServerURL != "" {
+		credentials, err := dockercredentials.GetAuthConfig(dockerRequest.ServerURL)
+		if err != nil {
+			return "", err
+		}
+
+		out, err := json.Marshal(credentials)
+		if err != nil {
+			return "", err
+		}
+
+		return string(out), nil
+	}
+
+	// do a list
+	listResponse, err := dockercredentials.ListCredentials()
+	if err != nil {
+		return "", err
+	}
+
+	out, err := json.Marshal(listResponse)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
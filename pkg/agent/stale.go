@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// staleMarkers are the arguments that identify DevPod helper processes which only make sense
+// while a client is connected
+var staleMarkers = [][]string{
+	{"agent", "container", "credentials-server"},
+	{"agent", "container-tunnel"},
+}
+
+// sshServerMarker identifies the DevPod ssh server that serves a connected client
+var sshServerMarker = []string{"helper", "ssh-server"}
+
+// StaleProcess is a DevPod helper process whose client is gone
+type StaleProcess struct {
+	// PID is the process id
+	PID int
+
+	// Command is the command line of the process
+	Command string
+}
+
+// FindStaleProcesses returns the credentials servers and container tunnels in the given proc
+// folder, usually /proc, that no DevPod ssh server is an ancestor of anymore. This happens if the
+// connection of the client broke and the process was reparented.
+func FindStaleProcesses(procDir string) ([]StaleProcess, error) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, err
+	}
+
+	args := map[int][]string{}
+	parents := map[int]int{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		// processes might exit while we read them
+		cmdline, err := os.ReadFile(filepath.Join(procDir, entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		stat, err := os.ReadFile(filepath.Join(procDir, entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		ppid, err := parsePPID(string(stat))
+		if err != nil {
+			continue
+		}
+
+		args[pid] = strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+		parents[pid] = ppid
+	}
+
+	stale := []StaleProcess{}
+	for pid, processArgs := range args {
+		if !isStaleCandidate(processArgs) {
+			continue
+		}
+
+		// check if a ssh server is still serving the process
+		served := false
+		visited := map[int]bool{}
+		for parent := parents[pid]; parent > 1 && !visited[parent]; parent = parents[parent] {
+			visited[parent] = true
+			if containsArgs(args[parent], sshServerMarker) {
+				served = true
+				break
+			}
+		}
+		if !served {
+			stale = append(stale, StaleProcess{PID: pid, Command: strings.Join(processArgs, " ")})
+		}
+	}
+
+	return stale, nil
+}
+
+func isStaleCandidate(args []string) bool {
+	for _, marker := range staleMarkers {
+		if containsArgs(args, marker) {
+			// the cleanup mode of the credentials server only runs shortly
+			for _, arg := range args {
+				if arg == "--cleanup" {
+					return false
+				}
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsArgs returns true if the marker is a consecutive part of the args
+func containsArgs(args []string, marker []string) bool {
+	for i := 0; i+len(marker) <= len(args); i++ {
+		found := true
+		for j := range marker {
+			if args[i+j] != marker[j] {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parsePPID parses the parent pid from /proc/<pid>/stat, the command name in parentheses
+// might contain spaces and parentheses itself
+func parsePPID(stat string) (int, error) {
+	index := strings.LastIndex(stat, ")")
+	if index < 0 {
+		return 0, fmt.Errorf("unexpected stat format")
+	}
+
+	fields := strings.Fields(stat[index+1:])
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected stat format")
+	}
+
+	return strconv.Atoi(fields[1])
+}
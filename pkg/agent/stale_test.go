@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestFindStaleProcesses(t *testing.T) {
+	procDir := t.TempDir()
+	writeProcess := func(pid, ppid int, args ...string) {
+		dir := filepath.Join(procDir, fmt.Sprint(pid))
+		assert.NilError(t, os.MkdirAll(dir, 0755))
+		assert.NilError(t, os.WriteFile(filepath.Join(dir, "cmdline"), []byte(strings.Join(args, "\x00")+"\x00"), 0644))
+		assert.NilError(t, os.WriteFile(filepath.Join(dir, "stat"), []byte(fmt.Sprintf("%d (my (proc)) S %d 1 1", pid, ppid)), 0644))
+	}
+
+	writeProcess(1, 0, "/sbin/init")
+	writeProcess(10, 1, "/usr/local/bin/devpod", "helper", "ssh-server", "--stdio")
+	writeProcess(11, 10, "sh", "-c", "devpod agent container credentials-server")
+	writeProcess(12, 11, "/usr/local/bin/devpod", "agent", "container", "credentials-server", "--user", "root")
+	writeProcess(20, 1, "/usr/local/bin/devpod", "agent", "container", "credentials-server", "--user", "vscode")
+	writeProcess(21, 1, "/usr/local/bin/devpod", "agent", "container-tunnel", "--workspace-info", "abc")
+	writeProcess(22, 1, "/usr/local/bin/devpod", "agent", "container", "credentials-server", "--cleanup")
+	writeProcess(23, 1, "bash")
+
+	stale, err := FindStaleProcesses(procDir)
+	assert.NilError(t, err)
+	pids := map[int]bool{}
+	for _, process := range stale {
+		pids[process.PID] = true
+	}
+	assert.DeepEqual(t, pids, map[int]bool{20: true, 21: true})
+}
@@ -28,6 +28,7 @@ type TunnelClient interface {
 	DockerCredentials(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Message, error)
 	GitCredentials(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Message, error)
 	GitUser(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Message, error)
+	Credentials(ctx context.Context, in *CredentialsRequest, opts ...grpc.CallOption) (*Message, error)
 	ForwardPort(ctx context.Context, in *ForwardPortRequest, opts ...grpc.CallOption) (*ForwardPortResponse, error)
 	StopForwardPort(ctx context.Context, in *StopForwardPortRequest, opts ...grpc.CallOption) (*StopForwardPortResponse, error)
 	StreamGitClone(ctx context.Context, in *Empty, opts ...grpc.CallOption) (Tunnel_StreamGitCloneClient, error)
@@ -97,6 +98,15 @@ func (c *tunnelClient) GitUser(ctx context.Context, in *Empty, opts ...grpc.Call
 	return out, nil
 }
 
+func (c *tunnelClient) Credentials(ctx context.Context, in *CredentialsRequest, opts ...grpc.CallOption) (*Message, error) {
+	out := new(Message)
+	err := c.cc.Invoke(ctx, "/tunnel.Tunnel/Credentials", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *tunnelClient) ForwardPort(ctx context.Context, in *ForwardPortRequest, opts ...grpc.CallOption) (*ForwardPortResponse, error) {
 	out := new(ForwardPortResponse)
 	err := c.cc.Invoke(ctx, "/tunnel.Tunnel/ForwardPort", in, out, opts...)
@@ -221,6 +231,7 @@ type TunnelServer interface {
 	DockerCredentials(context.Context, *Message) (*Message, error)
 	GitCredentials(context.Context, *Message) (*Message, error)
 	GitUser(context.Context, *Empty) (*Message, error)
+	Credentials(context.Context, *CredentialsRequest) (*Message, error)
 	ForwardPort(context.Context, *ForwardPortRequest) (*ForwardPortResponse, error)
 	StopForwardPort(context.Context, *StopForwardPortRequest) (*StopForwardPortResponse, error)
 	StreamGitClone(*Empty, Tunnel_StreamGitCloneServer) error
@@ -251,6 +262,9 @@ func (UnimplementedTunnelServer) GitCredentials(context.Context, *Message) (*Mes
 func (UnimplementedTunnelServer) GitUser(context.Context, *Empty) (*Message, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GitUser not implemented")
 }
+func (UnimplementedTunnelServer) Credentials(context.Context, *CredentialsRequest) (*Message, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Credentials not implemented")
+}
 func (UnimplementedTunnelServer) ForwardPort(context.Context, *ForwardPortRequest) (*ForwardPortResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ForwardPort not implemented")
 }
@@ -387,6 +401,24 @@ func _Tunnel_GitUser_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Tunnel_Credentials_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CredentialsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TunnelServer).Credentials(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tunnel.Tunnel/Credentials",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TunnelServer).Credentials(ctx, req.(*CredentialsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Tunnel_ForwardPort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ForwardPortRequest)
 	if err := dec(in); err != nil {
@@ -517,6 +549,10 @@ var Tunnel_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GitUser",
 			Handler:    _Tunnel_GitUser_Handler,
 		},
+		{
+			MethodName: "Credentials",
+			Handler:    _Tunnel_Credentials_Handler,
+		},
 		{
 			MethodName: "ForwardPort",
 			Handler:    _Tunnel_ForwardPort_Handler,
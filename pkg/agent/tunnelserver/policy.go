@@ -0,0 +1,70 @@
+package tunnelserver
+
+import (
+	"path"
+	"strings"
+)
+
+const (
+	ForwardKindGitUser = "git-user"
+)
+
+// ForwardPolicy controls which host data is allowed to cross into the workspace.
+// Patterns are globs that are matched against the kind of data, e.g. git-user or
+// git-credentials, as well as against kind/target, e.g. git-credentials/github.com.
+// Deny patterns take precedence, an empty allow list allows everything.
+type ForwardPolicy struct {
+	Allow []string
+	Deny  []string
+}
+
+// ParseForwardPolicy creates a new policy from comma separated pattern lists
+func ParseForwardPolicy(allow, deny string) *ForwardPolicy {
+	return &ForwardPolicy{
+		Allow: splitPatterns(allow),
+		Deny:  splitPatterns(deny),
+	}
+}
+
+// Allowed returns if data of the given kind and target may be forwarded
+func (p *ForwardPolicy) Allowed(kind, target string) bool {
+	if p == nil {
+		return true
+	}
+
+	name := kind
+	if target != "" {
+		name = kind + "/" + target
+	}
+
+	if matchesAny(p.Deny, kind, name) {
+		return false
+	}
+
+	return len(p.Allow) == 0 || matchesAny(p.Allow, kind, name)
+}
+
+func matchesAny(patterns []string, names ...string) bool {
+	for _, pattern := range patterns {
+		for _, name := range names {
+			matched, err := path.Match(pattern, name)
+			if err == nil && matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func splitPatterns(patterns string) []string {
+	retPatterns := []string{}
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" {
+			retPatterns = append(retPatterns, pattern)
+		}
+	}
+
+	return retPatterns
+}
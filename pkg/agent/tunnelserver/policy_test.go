@@ -0,0 +1,31 @@
+package tunnelserver
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestForwardPolicy(t *testing.T) {
+	var nilPolicy *ForwardPolicy
+	assert.Assert(t, nilPolicy.Allowed("git-credentials", "github.com"))
+
+	policy := ParseForwardPolicy("", "")
+	assert.Assert(t, policy.Allowed(ForwardKindGitUser, ""))
+
+	policy = ParseForwardPolicy("", "docker-credentials, git-credentials/*.internal")
+	assert.Assert(t, !policy.Allowed("docker-credentials", "ghcr.io"))
+	assert.Assert(t, !policy.Allowed("git-credentials", "git.internal"))
+	assert.Assert(t, policy.Allowed("git-credentials", "github.com"))
+	assert.Assert(t, policy.Allowed(ForwardKindGitUser, ""))
+
+	policy = ParseForwardPolicy("git-user,git-credentials/github.com", "")
+	assert.Assert(t, policy.Allowed(ForwardKindGitUser, ""))
+	assert.Assert(t, policy.Allowed("git-credentials", "github.com"))
+	assert.Assert(t, !policy.Allowed("git-credentials", "gitlab.com"))
+	assert.Assert(t, !policy.Allowed("docker-credentials", ""))
+
+	policy = ParseForwardPolicy("git-credentials", "git-credentials/gitlab.com")
+	assert.Assert(t, policy.Allowed("git-credentials", "github.com"))
+	assert.Assert(t, !policy.Allowed("git-credentials", "gitlab.com"))
+}
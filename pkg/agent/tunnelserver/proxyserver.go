@@ -64,6 +64,10 @@ func (t *proxyServer) GitCredentials(ctx context.Context, message *tunnel.Messag
 	return t.client.GitCredentials(ctx, message)
 }
 
+func (t *proxyServer) Credentials(ctx context.Context, request *tunnel.CredentialsRequest) (*tunnel.Message, error) {
+	return t.client.Credentials(ctx, request)
+}
+
 func (t *proxyServer) SendResult(ctx context.Context, result *tunnel.Message) (*tunnel.Empty, error) {
 	parsedResult := &config.Result{}
 	err := json.Unmarshal([]byte(result.Message), parsedResult)
@@ -9,9 +9,9 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/loft-sh/devpod/pkg/agent"
 	"github.com/loft-sh/devpod/pkg/agent/tunnel"
 	"github.com/loft-sh/devpod/pkg/devcontainer/config"
-	"github.com/loft-sh/devpod/pkg/dockercredentials"
 	"github.com/loft-sh/devpod/pkg/extract"
 	"github.com/loft-sh/devpod/pkg/git"
 	"github.com/loft-sh/devpod/pkg/gitcredentials"
@@ -19,55 +19,130 @@ import (
 	provider2 "github.com/loft-sh/devpod/pkg/provider"
 	"github.com/loft-sh/devpod/pkg/stdio"
 	"github.com/loft-sh/log"
-	perrors "github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
 
-func RunServicesServer(ctx context.Context, reader io.Reader, writer io.WriteCloser, allowGitCredentials, allowDockerCredentials bool, forwarder netstat.Forwarder, log log.Logger) error {
+func RunServicesServer(ctx context.Context, reader io.Reader, writer io.WriteCloser, allowGitCredentials, allowDockerCredentials bool, forwarder netstat.Forwarder, log log.Logger, options ...Option) error {
 	tunnelServ := &tunnelServer{
-		forwarder:              forwarder,
-		allowGitCredentials:    allowGitCredentials,
-		allowDockerCredentials: allowDockerCredentials,
-		log:                    log,
+		forwarder:           forwarder,
+		credentialProviders: enabledCredentialProviders(defaultCredentialProviders(allowGitCredentials, allowDockerCredentials), log),
+		log:                 log,
+	}
+	for _, o := range options {
+		o(tunnelServ)
 	}
 
 	return tunnelServ.Run(ctx, reader, writer)
 }
 
-func RunUpServer(ctx context.Context, reader io.Reader, writer io.WriteCloser, allowGitCredentials, allowDockerCredentials bool, workspace *provider2.Workspace, log log.Logger) (*config.Result, error) {
+func RunUpServer(ctx context.Context, reader io.Reader, writer io.WriteCloser, allowGitCredentials, allowDockerCredentials bool, workspace *provider2.Workspace, log log.Logger, options ...Option) (*config.Result, error) {
 	tunnelServ := &tunnelServer{
-		workspace:              workspace,
-		allowGitCredentials:    allowGitCredentials,
-		allowDockerCredentials: allowDockerCredentials,
-		log:                    log,
+		workspace:           workspace,
+		credentialProviders: enabledCredentialProviders(defaultCredentialProviders(allowGitCredentials, allowDockerCredentials), log),
+		log:                 log,
+	}
+	for _, o := range options {
+		o(tunnelServ)
 	}
 
 	return tunnelServ.RunWithResult(ctx, reader, writer)
 }
 
-func RunSetupServer(ctx context.Context, reader io.Reader, writer io.WriteCloser, allowDockerCredentials bool, mounts []*config.Mount, log log.Logger) (*config.Result, error) {
+func RunSetupServer(ctx context.Context, reader io.Reader, writer io.WriteCloser, allowDockerCredentials bool, mounts []*config.Mount, log log.Logger, options ...Option) (*config.Result, error) {
 	tunnelServ := &tunnelServer{
-		mounts:                 mounts,
-		allowDockerCredentials: allowDockerCredentials,
-		log:                    log,
+		mounts:              mounts,
+		credentialProviders: enabledCredentialProviders(defaultCredentialProviders(false, allowDockerCredentials), log),
+		log:                 log,
+	}
+	for _, o := range options {
+		o(tunnelServ)
 	}
 
 	return tunnelServ.RunWithResult(ctx, reader, writer)
 }
 
+type Option func(*tunnelServer)
+
+// WithForwardPolicy restricts the host data that is forwarded into the workspace
+func WithForwardPolicy(policy *ForwardPolicy) Option {
+	return func(t *tunnelServer) {
+		t.policy = policy
+	}
+}
+
+// WithCredentialProvider enables the given credential provider, an enabled provider
+// with the same name is replaced
+func WithCredentialProvider(provider agent.CredentialProvider) Option {
+	return func(t *tunnelServer) {
+		t.credentialProviders[provider.Name()] = provider
+	}
+}
+
+// WithCredentialProviders enables the registered credential providers with the given names, the
+// agent requests their credentials by name
+func WithCredentialProviders(names ...string) Option {
+	return func(t *tunnelServer) {
+		for name, provider := range enabledCredentialProviders(names, t.log) {
+			t.credentialProviders[name] = provider
+		}
+	}
+}
+
+// WithPingHandler calls onPing whenever the agent pings the server, which it does after it
+// connected
+func WithPingHandler(onPing func()) Option {
+	return func(t *tunnelServer) {
+		t.onPing = onPing
+	}
+}
+
 type tunnelServer struct {
 	tunnel.UnimplementedTunnelServer
 
 	// stream mounts
 	mounts []*config.Mount
 
-	forwarder              netstat.Forwarder
-	allowGitCredentials    bool
-	allowDockerCredentials bool
-	result                 *config.Result
-	workspace              *provider2.Workspace
-	log                    log.Logger
+	forwarder           netstat.Forwarder
+	credentialProviders map[string]agent.CredentialProvider
+	policy              *ForwardPolicy
+	result              *config.Result
+	workspace           *provider2.Workspace
+	onPing              func()
+	log                 log.Logger
+}
+
+// defaultCredentialProviders returns the names of the built-in credential providers to enable
+func defaultCredentialProviders(allowGitCredentials, allowDockerCredentials bool) []string {
+	names := []string{}
+	if allowGitCredentials {
+		names = append(names, agent.GitCredentialProviderName)
+	}
+	if allowDockerCredentials {
+		names = append(names, agent.DockerCredentialProviderName)
+	}
+
+	return names
+}
+
+func enabledCredentialProviders(names []string, log log.Logger) map[string]agent.CredentialProvider {
+	providers := map[string]agent.CredentialProvider{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		provider, err := agent.GetCredentialProvider(name)
+		if err != nil {
+			log.Debugf("Error enabling credential provider: %v", err)
+			continue
+		}
+
+		providers[name] = provider
+	}
+
+	return providers
 }
 
 func (t *tunnelServer) RunWithResult(ctx context.Context, reader io.Reader, writer io.WriteCloser) (*config.Result, error) {
@@ -119,46 +194,16 @@ func (t *tunnelServer) StopForwardPort(ctx context.Context, portRequest *tunnel.
 }
 
 func (t *tunnelServer) DockerCredentials(ctx context.Context, message *tunnel.Message) (*tunnel.Message, error) {
-	if !t.allowDockerCredentials {
-		return nil, fmt.Errorf("docker credentials forbidden")
-	}
-
-	request := &dockercredentials.Request{}
-	err := json.Unmarshal([]byte(message.Message), request)
-	if err != nil {
-		return nil, err
-	}
-
-	// check if list or get
-	if request.ServerURL != "" {
-		credentials, err := dockercredentials.GetAuthConfig(request.ServerURL)
-		if err != nil {
-			return nil, err
-		}
-
-		out, err := json.Marshal(credentials)
-		if err != nil {
-			return nil, err
-		}
-
-		return &tunnel.Message{Message: string(out)}, nil
-	}
-
-	// do a list
-	listResponse, err := dockercredentials.ListCredentials()
-	if err != nil {
-		return nil, err
-	}
-
-	out, err := json.Marshal(listResponse)
-	if err != nil {
-		return nil, err
-	}
-
-	return &tunnel.Message{Message: string(out)}, nil
+	return t.handleCredentials(ctx, agent.DockerCredentialProviderName, message)
 }
 
 func (t *tunnelServer) GitUser(ctx context.Context, empty *tunnel.Empty) (*tunnel.Message, error) {
+	if !t.policy.Allowed(ForwardKindGitUser, "") {
+		t.log.Debugf("Denied forwarding %s by policy", ForwardKindGitUser)
+		return nil, fmt.Errorf("git user forbidden by policy")
+	}
+	t.log.Debugf("Forwarding %s", ForwardKindGitUser)
+
 	gitUser, err := gitcredentials.GetUser()
 	if err != nil {
 		return nil, err
@@ -175,27 +220,38 @@ func (t *tunnelServer) GitUser(ctx context.Context, empty *tunnel.Empty) (*tunne
 }
 
 func (t *tunnelServer) GitCredentials(ctx context.Context, message *tunnel.Message) (*tunnel.Message, error) {
-	if !t.allowGitCredentials {
-		return nil, fmt.Errorf("git credentials forbidden")
-	}
+	return t.handleCredentials(ctx, agent.GitCredentialProviderName, message)
+}
 
-	credentials := &gitcredentials.GitCredentials{}
-	err := json.Unmarshal([]byte(message.Message), credentials)
-	if err != nil {
-		return nil, perrors.Wrap(err, "decode git credentials request")
+// Credentials answers the request of the credential provider with the given name, the provider
+// needs to be enabled
+func (t *tunnelServer) Credentials(ctx context.Context, request *tunnel.CredentialsRequest) (*tunnel.Message, error) {
+	return t.handleCredentials(ctx, request.Name, &tunnel.Message{Message: request.Message})
+}
+
+func (t *tunnelServer) handleCredentials(ctx context.Context, name string, message *tunnel.Message) (*tunnel.Message, error) {
+	provider, ok := t.credentialProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("%s credentials forbidden", name)
 	}
 
-	response, err := gitcredentials.GetCredentials(credentials)
-	if err != nil {
-		return nil, perrors.Wrap(err, "get git response")
+	target := ""
+	if targeter, ok := provider.(agent.CredentialTargeter); ok {
+		target = targeter.Target(message.Message)
+	}
+	kind := name + "-credentials"
+	if !t.policy.Allowed(kind, target) {
+		t.log.Debugf("Denied forwarding %s %s by policy", kind, target)
+		return nil, fmt.Errorf("%s credentials forbidden by policy", name)
 	}
+	t.log.Debugf("Forwarding %s %s", kind, target)
 
-	out, err := json.Marshal(response)
+	response, err := provider.Handle(ctx, message.Message)
 	if err != nil {
 		return nil, err
 	}
 
-	return &tunnel.Message{Message: string(out)}, nil
+	return &tunnel.Message{Message: response}, nil
 }
 
 func (t *tunnelServer) SendResult(ctx context.Context, result *tunnel.Message) (*tunnel.Empty, error) {
@@ -211,6 +267,9 @@ func (t *tunnelServer) SendResult(ctx context.Context, result *tunnel.Message) (
 
 func (t *tunnelServer) Ping(context.Context, *tunnel.Empty) (*tunnel.Empty, error) {
 	t.log.Debugf("Received ping from agent")
+	if t.onPing != nil {
+		t.onPing()
+	}
 	return &tunnel.Empty{}, nil
 }
 
@@ -0,0 +1,85 @@
+package tunnelserver
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/loft-sh/devpod/pkg/agent"
+	"github.com/loft-sh/devpod/pkg/agent/tunnel"
+	provider2 "github.com/loft-sh/devpod/pkg/provider"
+	"github.com/loft-sh/log"
+	"gotest.tools/assert"
+)
+
+func TestRunUpServerForwardPolicy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := RunUpServer(ctx, serverReader, serverWriter, true, true, &provider2.Workspace{}, log.Discard,
+			WithForwardPolicy(ParseForwardPolicy("", "git-credentials/github.com,git-user,docker-credentials")),
+		)
+		done <- err
+	}()
+
+	client, err := NewTunnelClient(clientReader, clientWriter, false)
+	assert.NilError(t, err)
+
+	_, err = client.GitCredentials(ctx, &tunnel.Message{Message: `{"protocol":"https","host":"github.com"}`})
+	assert.ErrorContains(t, err, "git credentials forbidden by policy")
+	_, err = client.DockerCredentials(ctx, &tunnel.Message{Message: `{"ServerURL":"https://ghcr.io"}`})
+	assert.ErrorContains(t, err, "docker credentials forbidden by policy")
+	_, err = client.GitUser(ctx, &tunnel.Empty{})
+	assert.ErrorContains(t, err, "git user forbidden by policy")
+
+	cancel()
+	assert.NilError(t, <-done)
+}
+
+type echoCredentialProvider struct{}
+
+func (e *echoCredentialProvider) Name() string {
+	return "echo"
+}
+
+func (e *echoCredentialProvider) Handle(ctx context.Context, request string) (string, error) {
+	return "echo " + request, nil
+}
+
+func TestRunServicesServerCredentials(t *testing.T) {
+	agent.RegisterCredentialProvider(&echoCredentialProvider{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	run := func(options ...Option) tunnel.TunnelClient {
+		serverReader, clientWriter := io.Pipe()
+		clientReader, serverWriter := io.Pipe()
+		go func() {
+			_ = RunServicesServer(ctx, serverReader, serverWriter, false, false, nil, log.Discard, options...)
+		}()
+
+		client, err := NewTunnelClient(clientReader, clientWriter, false)
+		assert.NilError(t, err)
+		return client
+	}
+
+	// providers are only served once they are enabled by name
+	client := run()
+	_, err := client.Credentials(ctx, &tunnel.CredentialsRequest{Name: "echo", Message: "hello"})
+	assert.ErrorContains(t, err, "echo credentials forbidden")
+
+	client = run(WithCredentialProviders("echo", " ", "unknown"))
+	response, err := client.Credentials(ctx, &tunnel.CredentialsRequest{Name: "echo", Message: "hello"})
+	assert.NilError(t, err)
+	assert.Equal(t, response.Message, "echo hello")
+	_, err = client.Credentials(ctx, &tunnel.CredentialsRequest{Name: "git", Message: "{}"})
+	assert.ErrorContains(t, err, "git credentials forbidden")
+
+	client = run(WithCredentialProviders("echo"), WithForwardPolicy(ParseForwardPolicy("", "echo-credentials")))
+	_, err = client.Credentials(ctx, &tunnel.CredentialsRequest{Name: "echo", Message: "hello"})
+	assert.ErrorContains(t, err, "echo credentials forbidden by policy")
+}
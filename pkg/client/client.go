@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/loft-sh/devpod/pkg/provider"
 )
@@ -100,6 +102,14 @@ type WorkspaceClient interface {
 
 	// AgentInfo returns the info to send to the agent
 	AgentInfo(options provider.CLIOptions) (string, *provider.AgentWorkspaceInfo, error)
+
+	// AgentInfoContext returns the info to send to the agent and aborts
+	// once the given context is done
+	AgentInfoContext(ctx context.Context, options provider.CLIOptions) (string, *provider.AgentWorkspaceInfo, error)
+
+	// Preflight validates the credentials and connectivity of the provider. Providers without
+	// a preflight command are not checked.
+	Preflight(ctx context.Context) error
 }
 
 type InitOptions struct{}
@@ -116,7 +126,10 @@ type DeleteOptions struct {
 	GracePeriod    string `json:"gracePeriod,omitempty"`
 }
 
-type CreateOptions struct{}
+type CreateOptions struct {
+	// SnapshotID creates the machine from this snapshot, requires a provider that supports snapshots
+	SnapshotID string `json:"snapshotId,omitempty"`
+}
 
 type StatusOptions struct {
 	ContainerStatus bool `json:"containerStatus,omitempty"`
@@ -168,6 +181,103 @@ func ParseStatus(in string) (Status, error) {
 	}
 }
 
+// StatusProgress is the phase of a busy workspace as reported by the provider
+type StatusProgress struct {
+	// Phase describes what the provider is doing, e.g. pulling image. It is empty if the
+	// provider doesn't report phases
+	Phase string
+
+	// Percent is the progress of the phase from 0 to 100 or negative if unknown
+	Percent int
+}
+
+// ProgressReporter is implemented by clients whose provider can report the phase of a busy
+// workspace in addition to its status
+type ProgressReporter interface {
+	// StatusProgress returns the progress reported by the last call to Status
+	StatusProgress() StatusProgress
+}
+
+// ParseStatusWithProgress parses the output of a status command. The status is on the first line
+// and can be followed by the optional lines "phase: <phase>" and "percent: <0-100>".
+func ParseStatusWithProgress(in string) (Status, StatusProgress, error) {
+	progress := StatusProgress{Percent: -1}
+	lines := strings.Split(strings.TrimSpace(in), "\n")
+	status, err := ParseStatus(lines[0])
+	if err != nil {
+		return status, progress, err
+	}
+
+	for _, line := range lines[1:] {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			return status, progress, fmt.Errorf("error parsing status: unexpected line '%s', expected phase: <phase> or percent: <0-100>", strings.TrimSpace(line))
+		}
+
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "phase":
+			progress.Phase = value
+		case "percent":
+			percent, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+			if err != nil || percent < 0 || percent > 100 {
+				return status, progress, fmt.Errorf("error parsing status: percent '%s' needs to be a number from 0 to 100", value)
+			}
+
+			progress.Percent = percent
+		default:
+			return status, progress, fmt.Errorf("error parsing status: unexpected line '%s', expected phase: <phase> or percent: <0-100>", strings.TrimSpace(line))
+		}
+	}
+
+	return status, progress, nil
+}
+
+// RetryableError is an error of a provider command that the provider marked as retryable, e.g.
+// because the cloud API rate limited the provider
+type RetryableError struct {
+	Err error
+
+	// RetryAfter is how long the provider asked to wait or 0 if it gave no hint
+	RetryAfter time.Duration
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// ParseRetryableError returns err as RetryableError if the output of the failed provider command
+// contains the line "rate-limited" or "retry-after: <seconds or duration>", like the HTTP
+// Retry-After header. Otherwise err is returned as it is.
+func ParseRetryableError(output string, err error) error {
+	retryable := false
+	retryAfter := time.Duration(0)
+	for _, line := range strings.Split(output, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), ":")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "rate-limited":
+			retryable = true
+		case "retry-after":
+			retryable = true
+			value = strings.TrimSpace(value)
+			if seconds, parseErr := strconv.Atoi(value); parseErr == nil && seconds > 0 {
+				retryAfter = time.Duration(seconds) * time.Second
+			} else if duration, parseErr := time.ParseDuration(value); parseErr == nil && duration > 0 {
+				retryAfter = duration
+			}
+		}
+	}
+	if !retryable {
+		return err
+	}
+
+	return &RetryableError{Err: err, RetryAfter: retryAfter}
+}
+
 type WorkspaceStatus struct {
 	ID       string `json:"id,omitempty"`
 	Context  string `json:"context,omitempty"`
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestParseStatusWithProgress(t *testing.T) {
+	status, progress, err := ParseStatusWithProgress("Running\n")
+	assert.NilError(t, err)
+	assert.Equal(t, status, Status(StatusRunning))
+	assert.Equal(t, progress, StatusProgress{Percent: -1})
+
+	status, progress, err = ParseStatusWithProgress("BUSY\nphase: pulling image\r\npercent: 40%\n")
+	assert.NilError(t, err)
+	assert.Equal(t, status, Status(StatusBusy))
+	assert.Equal(t, progress, StatusProgress{Phase: "pulling image", Percent: 40})
+
+	_, _, err = ParseStatusWithProgress("Busy\npercent: 140")
+	assert.ErrorContains(t, err, "needs to be a number from 0 to 100")
+
+	_, _, err = ParseStatusWithProgress("Busy\nprovisioning")
+	assert.ErrorContains(t, err, "unexpected line 'provisioning'")
+}
+
+func TestParseRetryableError(t *testing.T) {
+	providerErr := errors.New("get status: exit status 1")
+	err := ParseRetryableError("Error: too many requests\nretry-after: 30\n", providerErr)
+	retryable := &RetryableError{}
+	assert.Assert(t, errors.As(err, &retryable))
+	assert.Equal(t, retryable.RetryAfter, 30*time.Second)
+	assert.Assert(t, errors.Is(err, providerErr))
+
+	err = ParseRetryableError("Retry-After: 1m30s", providerErr)
+	assert.Assert(t, errors.As(err, &retryable))
+	assert.Equal(t, retryable.RetryAfter, 90*time.Second)
+
+	err = ParseRetryableError("rate-limited\n", providerErr)
+	assert.Assert(t, errors.As(err, &retryable))
+	assert.Equal(t, retryable.RetryAfter, time.Duration(0))
+
+	err = ParseRetryableError("Error: permission denied\n", providerErr)
+	assert.Equal(t, err, providerErr)
+}
@@ -0,0 +1,46 @@
+package clientimplementation
+
+import (
+	"context"
+	"sync"
+)
+
+// contextMutex is a mutex whose Lock can also be given up once a context is done. The zero
+// value is an unlocked mutex.
+type contextMutex struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+func (m *contextMutex) init() {
+	m.once.Do(func() {
+		m.ch = make(chan struct{}, 1)
+	})
+}
+
+// Lock locks the mutex and blocks until it is available
+func (m *contextMutex) Lock() {
+	m.init()
+	m.ch <- struct{}{}
+}
+
+// LockContext locks the mutex or returns the error of the context once it is done
+func (m *contextMutex) LockContext(ctx context.Context) error {
+	m.init()
+	select {
+	case m.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Unlock unlocks the mutex, it must be locked
+func (m *contextMutex) Unlock() {
+	m.init()
+	select {
+	case <-m.ch:
+	default:
+		panic("unlock of unlocked mutex")
+	}
+}
@@ -0,0 +1,33 @@
+package clientimplementation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestContextMutex(t *testing.T) {
+	m := contextMutex{}
+	assert.NilError(t, m.LockContext(context.Background()))
+
+	// a held mutex gives up once the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, m.LockContext(ctx), context.DeadlineExceeded)
+
+	// and is acquired as soon as it is unlocked
+	locked := make(chan struct{})
+	go func() {
+		m.Lock()
+		close(locked)
+	}()
+	m.Unlock()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("mutex wasn't acquired after unlock")
+	}
+	m.Unlock()
+}
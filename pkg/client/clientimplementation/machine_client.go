@@ -38,6 +38,8 @@ type machineClient struct {
 	config       *provider.ProviderConfig
 	machine      *provider.Machine
 	log          log.Logger
+
+	progress client.StatusProgress
 }
 
 func (s *machineClient) Provider() string {
@@ -91,17 +93,29 @@ func (s *machineClient) Create(ctx context.Context, options client.CreateOptions
 	defer writer.Close()
 
 	// create a machine
-	s.log.Infof("Create machine '%s' with provider '%s'...", s.machine.ID, s.config.Name)
+	command := s.config.Exec.Create
+	var extraEnv map[string]string
+	if options.SnapshotID != "" {
+		if !s.config.SupportsSnapshots() {
+			return fmt.Errorf("provider '%s' doesn't support snapshots", s.config.Name)
+		}
+
+		s.log.Infof("Create machine '%s' from snapshot '%s' with provider '%s'...", s.machine.ID, options.SnapshotID, s.config.Name)
+		command = s.config.Exec.CreateFromSnapshot
+		extraEnv = map[string]string{provider.SnapshotIDEnv: options.SnapshotID}
+	} else {
+		s.log.Infof("Create machine '%s' with provider '%s'...", s.machine.ID, s.config.Name)
+	}
 	err := RunCommandWithBinaries(
 		ctx,
 		"create",
-		s.config.Exec.Create,
+		command,
 		s.machine.Context,
 		nil,
 		s.machine,
 		s.devPodConfig.ProviderOptions(s.config.Name),
 		s.config,
-		nil,
+		extraEnv,
 		nil,
 		writer,
 		writer,
@@ -122,6 +136,8 @@ func (s *machineClient) Start(ctx context.Context, options client.StartOptions)
 	writer := s.log.Writer(logrus.InfoLevel, false)
 	defer writer.Close()
 
+	// keep the output to find out if the provider asks to retry
+	output := &bytes.Buffer{}
 	s.log.Infof("Starting machine '%s'...", s.machine.ID)
 	err := RunCommandWithBinaries(
 		ctx,
@@ -134,12 +150,12 @@ func (s *machineClient) Start(ctx context.Context, options client.StartOptions)
 		s.config,
 		nil,
 		nil,
-		writer,
-		writer,
+		io.MultiWriter(writer, output),
+		io.MultiWriter(writer, output),
 		s.log,
 	)
 	if err != nil {
-		return err
+		return client.ParseRetryableError(output.String(), err)
 	}
 	s.log.Donef("Successfully started '%s'", s.machine.ID)
 
@@ -216,18 +232,24 @@ func (s *machineClient) Status(ctx context.Context, options client.StatusOptions
 		s.log,
 	)
 	if err != nil {
-		return client.StatusNotFound, fmt.Errorf("get status: %s%s", strings.TrimSpace(stdout.String()), strings.TrimSpace(stderr.String()))
+		return client.StatusNotFound, client.ParseRetryableError(stdout.String()+"\n"+stderr.String(), fmt.Errorf("get status: %s%s", strings.TrimSpace(stdout.String()), strings.TrimSpace(stderr.String())))
 	}
 
 	// parse status
-	parsedStatus, err := client.ParseStatus(stdout.String())
+	parsedStatus, progress, err := client.ParseStatusWithProgress(stdout.String())
 	if err != nil {
 		return client.StatusNotFound, err
 	}
 
+	s.progress = progress
 	return parsedStatus, nil
 }
 
+// StatusProgress returns the phase the provider reported with the last status
+func (s *machineClient) StatusProgress() client.StatusProgress {
+	return s.progress
+}
+
 func (s *machineClient) Delete(ctx context.Context, options client.DeleteOptions) error {
 	var gracePeriod *time.Duration
 	if options.GracePeriod != "" {
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -45,7 +46,7 @@ func NewWorkspaceClient(devPodConfig *config.Config, prov *provider.ProviderConf
 }
 
 type workspaceClient struct {
-	m sync.Mutex
+	m contextMutex
 
 	workspaceLockOnce sync.Once
 	workspaceLock     *flock.Flock
@@ -56,6 +57,8 @@ type workspaceClient struct {
 	workspace    *provider.Workspace
 	machine      *provider.Machine
 	log          log.Logger
+
+	progress client.StatusProgress
 }
 
 func (s *workspaceClient) Provider() string {
@@ -147,6 +150,9 @@ func (s *workspaceClient) AgentInjectDockerCredentials() bool {
 	return options.ResolveAgentConfig(s.devPodConfig, s.config, s.workspace, s.machine).InjectDockerCredentials == "true"
 }
 
+// AgentInfo returns the compressed agent workspace info. It is resolved locally from the loaded
+// config without any remote call, so it is intentionally not cached, a cache would only persist
+// provider options on disk and could serve a stale agent config.
 func (s *workspaceClient) AgentInfo(cliOptions provider.CLIOptions) (string, *provider.AgentWorkspaceInfo, error) {
 	s.m.Lock()
 	defer s.m.Unlock()
@@ -154,6 +160,20 @@ func (s *workspaceClient) AgentInfo(cliOptions provider.CLIOptions) (string, *pr
 	return s.agentInfo(cliOptions)
 }
 
+func (s *workspaceClient) AgentInfoContext(ctx context.Context, cliOptions provider.CLIOptions) (string, *provider.AgentWorkspaceInfo, error) {
+	// the info itself is resolved without blocking, only waiting for the client lock, e.g. while
+	// another call runs the provider, needs to stop when ctx is done
+	err := s.m.LockContext(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "", nil, fmt.Errorf("timed out resolving agent info: %w", err)
+	} else if err != nil {
+		return "", nil, fmt.Errorf("resolving agent info canceled: %w", err)
+	}
+	defer s.m.Unlock()
+
+	return s.agentInfo(cliOptions)
+}
+
 func (s *workspaceClient) agentInfo(cliOptions provider.CLIOptions) (string, *provider.AgentWorkspaceInfo, error) {
 	// build struct
 	agentInfo := &provider.AgentWorkspaceInfo{
@@ -469,11 +489,43 @@ func (s *workspaceClient) Command(ctx context.Context, commandOptions client.Com
 	return runCommand(ctx, "command", s.config.Exec.Command, environ, commandOptions.Stdin, commandOptions.Stdout, commandOptions.Stderr, s.log.ErrorStreamOnly())
 }
 
+func (s *workspaceClient) Preflight(ctx context.Context) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if len(s.config.Exec.Preflight) == 0 {
+		return nil
+	}
+
+	out := &bytes.Buffer{}
+	err := RunCommandWithBinaries(
+		ctx,
+		"preflight",
+		s.config.Exec.Preflight,
+		s.workspace.Context,
+		s.workspace,
+		s.machine,
+		s.devPodConfig.ProviderOptions(s.config.Name),
+		s.config,
+		nil,
+		nil,
+		out,
+		out,
+		s.log.ErrorStreamOnly(),
+	)
+	if err != nil {
+		return fmt.Errorf("provider '%s' credentials are invalid or expired, or the provider is unreachable: %s\nPlease log in to the provider again and check its options via 'devpod provider set-options %s'", s.config.Name, strings.TrimSpace(out.String()+" "+err.Error()), s.config.Name)
+	}
+
+	return nil
+}
+
 func (s *workspaceClient) Status(ctx context.Context, options client.StatusOptions) (client.Status, error) {
 	s.m.Lock()
 	defer s.m.Unlock()
 
 	// check if provider has status command
+	s.progress = client.StatusProgress{Percent: -1}
 	if s.isMachineProvider() && len(s.config.Exec.Status) > 0 {
 		if s.machine == nil {
 			return client.StatusNotFound, nil
@@ -487,6 +539,8 @@ func (s *workspaceClient) Status(ctx context.Context, options client.StatusOptio
 		status, err := machineClient.Status(ctx, options)
 		if err != nil {
 			return status, err
+		} else if reporter, ok := machineClient.(client.ProgressReporter); ok {
+			s.progress = reporter.StatusProgress()
 		}
 
 		// try to check container status and if that fails check workspace folder
@@ -519,6 +573,14 @@ func (s *workspaceClient) Status(ctx context.Context, options client.StatusOptio
 	return client.StatusNotFound, nil
 }
 
+// StatusProgress returns the phase the machine provider reported with the last status
+func (s *workspaceClient) StatusProgress() client.StatusProgress {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.progress
+}
+
 func (s *workspaceClient) getContainerStatus(ctx context.Context) (client.Status, error) {
 	stdout := &bytes.Buffer{}
 	buf := &bytes.Buffer{}
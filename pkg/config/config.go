@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/ghodss/yaml"
 	"github.com/loft-sh/devpod/pkg/telemetry"
@@ -43,6 +45,9 @@ type ContextConfig struct {
 	// Providers holds the provider configuration
 	Providers map[string]*ProviderConfig `json:"providers,omitempty"`
 
+	// SSHTemplates holds the named templates of devpod ssh flags
+	SSHTemplates map[string]*SSHTemplate `json:"sshTemplates,omitempty"`
+
 	// OriginalProvider is the original default provider
 	OriginalProvider string `json:"-"`
 }
@@ -275,6 +280,47 @@ func LoadConfig(contextOverride string, providerOverride string) (*Config, error
 	return config, nil
 }
 
+// CheckContext returns an error listing the available contexts if the given context doesn't exist
+func CheckContext(context string) error {
+	if context == "" {
+		return nil
+	}
+
+	configOrigin, err := GetConfigPath()
+	if err != nil {
+		return err
+	}
+
+	contexts := []string{DefaultContext}
+	configBytes, err := os.ReadFile(configOrigin)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "read config")
+	} else if err == nil {
+		config := &Config{}
+		err = yaml.Unmarshal(configBytes, config)
+		if err != nil {
+			return err
+		}
+
+		contexts = []string{}
+		for name := range config.Contexts {
+			contexts = append(contexts, name)
+		}
+		sort.Strings(contexts)
+		if len(contexts) == 0 {
+			contexts = []string{DefaultContext}
+		}
+	}
+
+	for _, name := range contexts {
+		if name == context {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("context %s doesn't exist, available contexts: %s", context, strings.Join(contexts, ", "))
+}
+
 func SaveConfig(config *Config) error {
 	configOrigin, err := GetConfigPath()
 	if err != nil {
@@ -5,6 +5,9 @@ const (
 	ContextOptionSSHAgentForwarding         = "SSH_AGENT_FORWARDING"
 	ContextOptionSSHInjectDockerCredentials = "SSH_INJECT_DOCKER_CREDENTIALS"
 	ContextOptionSSHInjectGitCredentials    = "SSH_INJECT_GIT_CREDENTIALS"
+	ContextOptionSSHForwardAllowlist        = "SSH_FORWARD_ALLOWLIST"
+	ContextOptionSSHForwardDenylist         = "SSH_FORWARD_DENYLIST"
+	ContextOptionSSHCredentialProviders     = "SSH_CREDENTIAL_PROVIDERS"
 	ContextOptionExitAfterTimeout           = "EXIT_AFTER_TIMEOUT"
 	ContextOptionTelemetry                  = "TELEMETRY"
 	ContextOptionAgentURL                   = "AGENT_URL"
@@ -43,6 +46,18 @@ var ContextOptions = []ContextOption{
 		Default:     "true",
 		Enum:        []string{"true", "false"},
 	},
+	{
+		Name:        ContextOptionSSHForwardAllowlist,
+		Description: "Specifies a comma separated list of patterns for host data DevPod may forward into the workspace, e.g. git-user,git-credentials/github.com. If empty everything is allowed",
+	},
+	{
+		Name:        ContextOptionSSHForwardDenylist,
+		Description: "Specifies a comma separated list of patterns for host data DevPod should never forward into the workspace, e.g. docker-credentials",
+	},
+	{
+		Name:        ContextOptionSSHCredentialProviders,
+		Description: "Specifies a comma separated list of additional registered credential providers DevPod should serve to the workspace, next to git and docker",
+	},
 	{
 		Name:        ContextOptionTelemetry,
 		Description: "Specifies if DevPod should send telemetry information",
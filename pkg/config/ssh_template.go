@@ -0,0 +1,147 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/pflag"
+)
+
+var sshTemplateNameRegEx = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// sshTemplateExcludedFlags are evaluated before the template is loaded or select the template
+var sshTemplateExcludedFlags = map[string]bool{
+	"template":        true,
+	"json":            true,
+	"diagnose":        true,
+	"non-interactive": true,
+}
+
+// SSHTemplate bundles flags of devpod ssh under a name, so a team can share its connection
+// settings. Flags given on the command line take precedence over the template.
+type SSHTemplate struct {
+	// Description describes what the template is for
+	Description string `json:"description,omitempty"`
+
+	// Flags maps the flag names of devpod ssh without dashes to their values
+	Flags map[string]SSHTemplateValue `json:"flags,omitempty"`
+}
+
+// SSHTemplateFile is an exported template
+type SSHTemplateFile struct {
+	// Name is the name the template is imported as
+	Name string `json:"name"`
+
+	SSHTemplate
+}
+
+// SSHTemplateValue holds the values of a flag, a single string, bool or number in the file is
+// a single value and a list is used for flags that can be repeated
+type SSHTemplateValue []string
+
+// UnmarshalJSON accepts a string, bool, number or a list of them
+func (v *SSHTemplateValue) UnmarshalJSON(data []byte) error {
+	var raw interface{}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	list, ok := raw.([]interface{})
+	if !ok {
+		list = []interface{}{raw}
+	}
+
+	values := SSHTemplateValue{}
+	for _, item := range list {
+		switch value := item.(type) {
+		case string:
+			values = append(values, value)
+		case bool:
+			values = append(values, strconv.FormatBool(value))
+		case float64:
+			values = append(values, strconv.FormatFloat(value, 'f', -1, 64))
+		default:
+			return fmt.Errorf("unsupported flag value %v, expected a string, bool, number or a list of them", item)
+		}
+	}
+
+	*v = values
+	return nil
+}
+
+// ValidateSSHTemplateName checks that the name can be used on the command line
+func ValidateSSHTemplateName(name string) error {
+	if !sshTemplateNameRegEx.MatchString(name) {
+		return fmt.Errorf("invalid template name %s, it can only include letters, numbers, dashes and underscores", name)
+	}
+
+	return nil
+}
+
+// SSHTemplateNames returns the sorted names of the templates of the context
+func (c *ContextConfig) SSHTemplateNames() []string {
+	names := []string{}
+	for name := range c.SSHTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadSSHTemplateFile reads an exported template from a yaml or json file
+func LoadSSHTemplateFile(path string) (*SSHTemplateFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	templateFile := &SSHTemplateFile{}
+	err = yaml.Unmarshal(data, templateFile)
+	if err != nil {
+		return nil, fmt.Errorf("parse template %s: %w", path, err)
+	}
+
+	err = ValidateSSHTemplateName(templateFile.Name)
+	if err != nil {
+		return nil, fmt.Errorf("parse template %s: %w", path, err)
+	}
+
+	return templateFile, nil
+}
+
+// ApplySSHTemplate sets the flags of the template that weren't given on the command line. Only
+// flags of the given set can be used, which shouldn't include the global flags, since those
+// are evaluated before the template is loaded.
+func ApplySSHTemplate(flags *pflag.FlagSet, template *SSHTemplate) error {
+	names := []string{}
+	for name := range template.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		flag := flags.Lookup(name)
+		if flag == nil {
+			return fmt.Errorf("unknown flag %s in template", name)
+		} else if sshTemplateExcludedFlags[name] {
+			return fmt.Errorf("flag %s cannot be set by a template", name)
+		} else if flag.Changed {
+			continue
+		}
+
+		for _, value := range template.Flags[name] {
+			err := flags.Set(name, value)
+			if err != nil {
+				return fmt.Errorf("set flag %s from template: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
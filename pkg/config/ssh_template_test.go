@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"gotest.tools/assert"
+)
+
+func TestApplySSHTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "template.yaml")
+	err := os.WriteFile(path, []byte(`name: backend-dev
+flags:
+  forward-ports: ["5432", 8080]
+  agent-forwarding: false
+  user: vscode
+`), 0644)
+	assert.NilError(t, err)
+	templateFile, err := LoadSSHTemplateFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, templateFile.Name, "backend-dev")
+
+	var forwardPorts []string
+	var agentForwarding bool
+	var user string
+	flags := pflag.NewFlagSet("ssh", pflag.ContinueOnError)
+	flags.StringSliceVar(&forwardPorts, "forward-ports", []string{}, "")
+	flags.BoolVar(&agentForwarding, "agent-forwarding", true, "")
+	flags.StringVar(&user, "user", "", "")
+	assert.NilError(t, flags.Parse([]string{"--user", "root"}))
+
+	// flags on the command line take precedence
+	err = ApplySSHTemplate(flags, &templateFile.SSHTemplate)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, forwardPorts, []string{"5432", "8080"})
+	assert.Equal(t, agentForwarding, false)
+	assert.Equal(t, user, "root")
+
+	err = ApplySSHTemplate(flags, &SSHTemplate{Flags: map[string]SSHTemplateValue{"unknown": {"true"}}})
+	assert.ErrorContains(t, err, "unknown flag unknown")
+	flags.String("template", "", "")
+	err = ApplySSHTemplate(flags, &SSHTemplate{Flags: map[string]SSHTemplateValue{"template": {"other"}}})
+	assert.ErrorContains(t, err, "cannot be set by a template")
+}
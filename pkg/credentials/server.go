@@ -5,31 +5,56 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
+	"os/user"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/gofrs/flock"
 	"github.com/loft-sh/devpod/pkg/agent/tunnel"
+	"github.com/loft-sh/devpod/pkg/command"
 	"github.com/loft-sh/devpod/pkg/dockercredentials"
+	"github.com/loft-sh/devpod/pkg/file"
 	"github.com/loft-sh/devpod/pkg/gitcredentials"
+	devpodhttp "github.com/loft-sh/devpod/pkg/http"
 	"github.com/loft-sh/log"
+	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 )
 
 func RunCredentialsServer(
 	ctx context.Context,
-	userName string,
+	userNames []string,
 	port int,
+	binaryPath,
+	runtimeDir string,
 	configureGitUser,
 	configureGitHelper,
 	configureDockerHelper bool,
 	client tunnel.TunnelClient,
 	log log.Logger,
 ) error {
+	listeners := []net.Listener{}
+	defer func() {
+		for _, listener := range listeners {
+			_ = listener.Close()
+		}
+	}()
+
 	if configureGitUser || configureGitHelper || configureDockerHelper {
-		fileLock := flock.New(filepath.Join(os.TempDir(), "devpod-credentials.lock"))
+		if runtimeDir != "" && len(userNames) > 1 {
+			return fmt.Errorf("a custom runtime dir can only be used for a single user")
+		}
+
+		lockPath, err := credentialsLockPath()
+		if err != nil {
+			return err
+		}
+
+		fileLock := flock.New(lockPath)
 		locked, err := fileLock.TryLock()
 		if err != nil {
 			return errors.Wrap(err, "acquire lock")
@@ -40,74 +65,95 @@ func RunCredentialsServer(
 			_ = fileLock.Unlock()
 		}(fileLock)
 
-		binaryPath, err := os.Executable()
-		if err != nil {
-			return err
-		}
-
-		// configure docker credential helper
-		if configureDockerHelper {
-			// configure the creds store
-			err = dockercredentials.ConfigureCredentialsContainer(userName, port, log)
+		// the helpers invoke the devpod binary with an absolute path
+		if binaryPath == "" {
+			binaryPath, err = os.Executable()
 			if err != nil {
 				return err
 			}
 		}
 
-		// configure git user
-		if configureGitUser {
-			err = configureGitUserLocally(ctx, userName, client)
+		// every user gets its own socket in a folder only this user can access, so other
+		// users of the container cannot use or take over the credentials
+		for _, userName := range userNames {
+			userRuntimeDir, err := RuntimeDir(runtimeDir, userName)
 			if err != nil {
-				log.Debugf("Error configuring git user: %v", err)
+				return err
 			}
-		}
 
-		// configure git credential helper
-		if configureGitHelper {
-			// configure helper
-			err = gitcredentials.ConfigureHelper(binaryPath, userName, port)
+			socketPath := filepath.Join(userRuntimeDir, credentialsSocketName)
+			listener, err := listenSocket(userName, socketPath)
 			if err != nil {
-				return errors.Wrap(err, "configure git helper")
+				return err
 			}
+			listeners = append(listeners, listener)
 
-			// cleanup when we are done
-			defer func(userName string) {
-				_ = gitcredentials.RemoveHelper(userName)
-			}(userName)
-		}
-	}
+			// configure docker credential helper
+			if configureDockerHelper {
+				// the helper is shared by all users and finds the socket of the calling user by
+				// itself, unless the socket lives in a custom runtime dir
+				dockerSocketPath := ""
+				if runtimeDir != "" {
+					dockerSocketPath = socketPath
+				}
 
-	srv := &http.Server{
-		Addr: "localhost:" + strconv.Itoa(port),
-		Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-			log.Debugf("Incoming client connection at %s", request.URL.Path)
-			if request.URL.Path == "/git-credentials" {
-				err := handleGitCredentialsRequest(ctx, writer, request, client, log)
+				// configure the creds store
+				err = dockercredentials.ConfigureCredentialsContainer(userName, binaryPath, dockerSocketPath, log)
 				if err != nil {
-					http.Error(writer, err.Error(), http.StatusInternalServerError)
-					return
+					return err
 				}
-			} else if request.URL.Path == "/docker-credentials" {
-				err := handleDockerCredentialsRequest(ctx, writer, request, client, log)
+			}
+
+			// configure git user
+			if configureGitUser {
+				err = configureGitUserLocally(ctx, userName, client)
 				if err != nil {
-					http.Error(writer, err.Error(), http.StatusInternalServerError)
-					return
+					log.Debugf("Error configuring git user for %s: %v", userName, err)
 				}
 			}
-		}),
-	}
 
-	errChan := make(chan error, 1)
-	go func() {
-		log.Debugf("Credentials server started on port %d...", port)
+			// configure git credential helper
+			if configureGitHelper {
+				// configure helper
+				err = gitcredentials.ConfigureHelper(binaryPath, userName, socketPath)
+				if err != nil {
+					return errors.Wrapf(err, "configure git helper for %s", userName)
+				}
+
+				// cleanup when we are done
+				defer func(userName string) {
+					_ = gitcredentials.RemoveHelper(userName)
+				}(userName)
+			}
+		}
+	}
 
-		// always returns error. ErrServerClosed on graceful close
-		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
-			errChan <- err
-		} else {
-			errChan <- nil
+	// without credential helpers the server is only used by the agent itself
+	if len(listeners) == 0 {
+		listener, err := net.Listen("tcp", "localhost:"+strconv.Itoa(port))
+		if err != nil {
+			return err
 		}
-	}()
+		listeners = append(listeners, listener)
+	}
+
+	srv := &http.Server{
+		Handler: NewCredentialsHandler(ctx, client, log),
+	}
+
+	errChan := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			log.Debugf("Credentials server started on %s...", listener.Addr().String())
+
+			// always returns error. ErrServerClosed on graceful close
+			if err := srv.Serve(listener); err != http.ErrServerClosed {
+				errChan <- err
+			} else {
+				errChan <- nil
+			}
+		}(listener)
+	}
 
 	select {
 	case err := <-errChan:
@@ -118,6 +164,177 @@ func RunCredentialsServer(
 	}
 }
 
+// NewCredentialsHandler returns the http handler of the credentials server that
+// forwards the git and docker credential requests through the given tunnel client.
+// Requests to /credentials/<name> go to the credential provider with that name.
+func NewCredentialsHandler(ctx context.Context, client tunnel.TunnelClient, log log.Logger) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		log.Debugf("Incoming client connection at %s", request.URL.Path)
+		if request.URL.Path == "/git-credentials" {
+			err := handleGitCredentialsRequest(ctx, writer, request, client, log)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		} else if request.URL.Path == "/docker-credentials" {
+			err := handleDockerCredentialsRequest(ctx, writer, request, client, log)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		} else if name, ok := strings.CutPrefix(request.URL.Path, "/credentials/"); ok && name != "" {
+			err := handleCredentialsRequest(ctx, name, writer, request, client, log)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	})
+}
+
+// CleanupCredentialHelpers removes a git credential helper that was left behind by a
+// credentials server that failed or got killed. If another credentials server is
+// still running, the helper is left untouched.
+func CleanupCredentialHelpers(userNames []string, log log.Logger) error {
+	lockPath, err := credentialsLockPath()
+	if err != nil {
+		return err
+	}
+
+	fileLock := flock.New(lockPath)
+	locked, err := fileLock.TryLock()
+	if err != nil {
+		return errors.Wrap(err, "acquire lock")
+	} else if !locked {
+		log.Debugf("Credentials server still running, skip cleanup")
+		return nil
+	}
+	defer func(fileLock *flock.Flock) {
+		_ = fileLock.Unlock()
+	}(fileLock)
+
+	for _, userName := range userNames {
+		err = gitcredentials.RemoveHelper(userName)
+		if err != nil {
+			return errors.Wrapf(err, "remove git helper for %s", userName)
+		}
+	}
+
+	log.Debugf("Removed git credential helpers")
+	return nil
+}
+
+const credentialsSocketName = "credentials.sock"
+
+// RuntimeDir creates and returns the folder for the credentials socket of the given user, which
+// is the given folder or .devpod/run in the home of the user. The folder belongs to the user and
+// is only accessible by them, so other users of the container cannot reach the socket.
+func RuntimeDir(runtimeDir, userName string) (string, error) {
+	if runtimeDir == "" {
+		home, err := command.GetHome(userName)
+		if err != nil {
+			return "", errors.Wrapf(err, "find home directory of %s", userName)
+		}
+
+		err = file.MkdirAll(userName, filepath.Join(home, ".devpod"), 0755)
+		if err != nil {
+			return "", errors.Wrap(err, "create devpod dir")
+		}
+
+		runtimeDir = filepath.Join(home, ".devpod", "run")
+	}
+
+	err := os.MkdirAll(runtimeDir, 0700)
+	if err != nil {
+		return "", errors.Wrap(err, "create runtime dir")
+	}
+
+	// take over an existing folder, this fails for a folder of another user unless we are root
+	err = file.Chown(userName, runtimeDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "chown runtime dir %s", runtimeDir)
+	}
+	err = os.Chmod(runtimeDir, 0700)
+	if err != nil {
+		return "", errors.Wrapf(err, "secure runtime dir %s", runtimeDir)
+	}
+
+	return runtimeDir, nil
+}
+
+// SocketPath returns the path of the credentials socket of the user running the helper. The
+// home is looked up instead of read from HOME, which sudo might have reset.
+func SocketPath() (string, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return "", errors.Wrap(err, "find current user")
+	}
+
+	return filepath.Join(currentUser.HomeDir, ".devpod", "run", credentialsSocketName), nil
+}
+
+// NewHelperClient returns an http client and the base url to reach the credentials server,
+// either on the given port or on the given socket, which defaults to the socket of the current user
+func NewHelperClient(port int, socketPath string) (*http.Client, string, error) {
+	if port != 0 {
+		return devpodhttp.GetHTTPClient(), "http://localhost:" + strconv.Itoa(port), nil
+	} else if socketPath == "" {
+		var err error
+		socketPath, err = SocketPath()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				dialer := &net.Dialer{}
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}, "http://localhost", nil
+}
+
+// listenSocket listens on the credentials socket of the given user. The runtime dir is locked
+// by the caller, so a socket that already exists was left behind by a killed server.
+func listenSocket(userName, socketPath string) (net.Listener, error) {
+	err := os.Remove(socketPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "remove stale socket")
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "listen on credentials socket")
+	}
+
+	err = file.Chown(userName, socketPath)
+	if err != nil {
+		_ = listener.Close()
+		return nil, errors.Wrap(err, "chown credentials socket")
+	}
+
+	return listener, nil
+}
+
+// credentialsLockPath returns the lock that makes sure only one credentials server runs in the
+// container. It doesn't depend on the runtime dir, so servers with different dirs exclude each other.
+func credentialsLockPath() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", errors.Wrap(err, "find home directory")
+	}
+
+	devPodDir := filepath.Join(home, ".devpod")
+	err = os.MkdirAll(devPodDir, 0755)
+	if err != nil {
+		return "", errors.Wrap(err, "create devpod dir")
+	}
+
+	return filepath.Join(devPodDir, "credentials.lock"), nil
+}
+
 func configureGitUserLocally(ctx context.Context, userName string, client tunnel.TunnelClient) error {
 	// get local credentials
 	localGitUser, err := gitcredentials.GetUser()
@@ -176,6 +393,25 @@ func handleDockerCredentialsRequest(ctx context.Context, writer http.ResponseWri
 	return nil
 }
 
+func handleCredentialsRequest(ctx context.Context, name string, writer http.ResponseWriter, request *http.Request, client tunnel.TunnelClient, log log.Logger) error {
+	out, err := io.ReadAll(request.Body)
+	if err != nil {
+		return errors.Wrap(err, "read request body")
+	}
+
+	log.Debugf("Received %s credentials request", name)
+	response, err := client.Credentials(ctx, &tunnel.CredentialsRequest{Name: name, Message: string(out)})
+	if err != nil {
+		return errors.Wrapf(err, "get %s credentials response", name)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write([]byte(response.Message))
+	log.Debugf("Successfully wrote back %d bytes", len(response.Message))
+	return nil
+}
+
 func handleGitCredentialsRequest(ctx context.Context, writer http.ResponseWriter, request *http.Request, client tunnel.TunnelClient, log log.Logger) error {
 	out, err := io.ReadAll(request.Body)
 	if err != nil {
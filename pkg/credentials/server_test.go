@@ -0,0 +1,294 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loft-sh/devpod/pkg/agent"
+	"github.com/loft-sh/devpod/pkg/agent/tunnel"
+	"github.com/loft-sh/devpod/pkg/agent/tunnelserver"
+	"github.com/loft-sh/devpod/pkg/dockercredentials"
+	"github.com/loft-sh/devpod/pkg/gitcredentials"
+	"github.com/loft-sh/log"
+	"github.com/mitchellh/go-homedir"
+	"gotest.tools/assert"
+)
+
+func TestRunCredentialsServerStartupFailure(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	homedir.DisableCache = true
+
+	// block the socket so the server fails to start
+	socketPath := filepath.Join(homeDir, ".devpod", "run", "credentials.sock")
+	assert.NilError(t, os.MkdirAll(socketPath, 0700))
+	assert.NilError(t, os.WriteFile(filepath.Join(socketPath, "file"), nil, 0600))
+
+	err := RunCredentialsServer(context.Background(), []string{""}, 0, "", "", false, true, false, nil, log.Discard)
+	assert.ErrorContains(t, err, "remove stale socket")
+
+	// the git helper must not be left behind
+	_, err = os.Stat(filepath.Join(homeDir, ".gitconfig"))
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestCleanupCredentialHelpers(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	homedir.DisableCache = true
+
+	gitConfigPath := filepath.Join(homeDir, ".gitconfig")
+	err := os.WriteFile(gitConfigPath, []byte(`[user]
+        name = test
+[credential]
+        helper = "/usr/local/bin/devpod agent git-credentials --port 12345"
+`), 0644)
+	assert.NilError(t, err)
+
+	err = CleanupCredentialHelpers([]string{""}, log.Discard)
+	assert.NilError(t, err)
+
+	out, err := os.ReadFile(gitConfigPath)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(string(out), "git-credentials"))
+	assert.Assert(t, strings.Contains(string(out), "name = test"))
+}
+
+func TestRuntimeDir(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	homedir.DisableCache = true
+
+	runtimeDir, err := RuntimeDir("", "")
+	assert.NilError(t, err)
+	assert.Equal(t, runtimeDir, filepath.Join(homeDir, ".devpod", "run"))
+
+	// existing folders are tightened
+	customDir := filepath.Join(t.TempDir(), "run")
+	assert.NilError(t, os.Mkdir(customDir, 0777))
+	runtimeDir, err = RuntimeDir(customDir, "")
+	assert.NilError(t, err)
+	info, err := os.Stat(runtimeDir)
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0700))
+}
+
+type fakeCredentialProvider struct {
+	name   string
+	handle func(request string) (string, error)
+}
+
+func (f *fakeCredentialProvider) Name() string {
+	return f.name
+}
+
+func (f *fakeCredentialProvider) Handle(_ context.Context, request string) (string, error) {
+	return f.handle(request)
+}
+
+// startTunnelClient connects a tunnel server and client over an in-memory pipe
+func startTunnelClient(ctx context.Context, t *testing.T, providers ...agent.CredentialProvider) tunnel.TunnelClient {
+
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	options := []tunnelserver.Option{}
+	for _, provider := range providers {
+		options = append(options, tunnelserver.WithCredentialProvider(provider))
+	}
+	go func() {
+		_ = tunnelserver.RunServicesServer(ctx, serverReader, serverWriter, false, false, nil, log.Discard, options...)
+	}()
+
+	client, err := tunnelserver.NewTunnelClient(clientReader, clientWriter, false)
+	assert.NilError(t, err)
+	_, err = client.Ping(ctx, &tunnel.Empty{})
+	assert.NilError(t, err)
+	return client
+}
+
+// startCredentialsTunnel returns a credentials server that forwards requests through a tunnel
+func startCredentialsTunnel(t *testing.T, providers ...agent.CredentialProvider) *httptest.Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	client := startTunnelClient(ctx, t, providers...)
+	server := httptest.NewServer(NewCredentialsHandler(ctx, client, log.Discard))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func postCredentials(t *testing.T, url string, request interface{}) (int, []byte) {
+	body, err := json.Marshal(request)
+	assert.NilError(t, err)
+
+	response, err := http.Post(url, "application/json", strings.NewReader(string(body)))
+	assert.NilError(t, err)
+	defer response.Body.Close()
+
+	out, err := io.ReadAll(response.Body)
+	assert.NilError(t, err)
+	return response.StatusCode, out
+}
+
+func TestGitCredentialsTunnel(t *testing.T) {
+	server := startCredentialsTunnel(t, &fakeCredentialProvider{
+		name: agent.GitCredentialProviderName,
+		handle: func(request string) (string, error) {
+			credentials := &gitcredentials.GitCredentials{}
+			err := json.Unmarshal([]byte(request), credentials)
+			if err != nil {
+				return "", err
+			} else if credentials.Host != "github.com" {
+				return "", fmt.Errorf("no credentials for %s", credentials.Host)
+			}
+
+			credentials.Username = "user"
+			credentials.Password = "secret"
+			out, err := json.Marshal(credentials)
+			return string(out), err
+		},
+	})
+
+	statusCode, out := postCredentials(t, server.URL+"/git-credentials", &gitcredentials.GitCredentials{Protocol: "https", Host: "github.com"})
+	assert.Equal(t, statusCode, http.StatusOK)
+	credentials := &gitcredentials.GitCredentials{}
+	assert.NilError(t, json.Unmarshal(out, credentials))
+	assert.Equal(t, credentials.Username, "user")
+	assert.Equal(t, credentials.Password, "secret")
+
+	statusCode, out = postCredentials(t, server.URL+"/git-credentials", &gitcredentials.GitCredentials{Protocol: "https", Host: "gitlab.com"})
+	assert.Equal(t, statusCode, http.StatusInternalServerError)
+	assert.Assert(t, strings.Contains(string(out), "no credentials for gitlab.com"), string(out))
+}
+
+func TestGitCredentialsTunnelDisabled(t *testing.T) {
+	server := startCredentialsTunnel(t)
+
+	statusCode, out := postCredentials(t, server.URL+"/git-credentials", &gitcredentials.GitCredentials{Protocol: "https", Host: "github.com"})
+	assert.Equal(t, statusCode, http.StatusInternalServerError)
+	assert.Assert(t, strings.Contains(string(out), "git credentials forbidden"), string(out))
+}
+
+func TestDockerCredentialsTunnel(t *testing.T) {
+	server := startCredentialsTunnel(t, &fakeCredentialProvider{
+		name: agent.DockerCredentialProviderName,
+		handle: func(request string) (string, error) {
+			dockerRequest := &dockercredentials.Request{}
+			err := json.Unmarshal([]byte(request), dockerRequest)
+			if err != nil {
+				return "", err
+			}
+
+			out, err := json.Marshal(&dockercredentials.Credentials{
+				ServerURL: dockerRequest.ServerURL,
+				Username:  "user",
+				Secret:    "secret",
+			})
+			return string(out), err
+		},
+	})
+
+	statusCode, out := postCredentials(t, server.URL+"/docker-credentials", &dockercredentials.Request{ServerURL: "ghcr.io"})
+	assert.Equal(t, statusCode, http.StatusOK)
+	credentials := &dockercredentials.Credentials{}
+	assert.NilError(t, json.Unmarshal(out, credentials))
+	assert.Equal(t, credentials.ServerURL, "ghcr.io")
+	assert.Equal(t, credentials.Secret, "secret")
+}
+
+func TestCustomCredentialsTunnel(t *testing.T) {
+	server := startCredentialsTunnel(t, &fakeCredentialProvider{
+		name: "vault",
+		handle: func(request string) (string, error) {
+			return `{"token":"secret"}`, nil
+		},
+	})
+
+	statusCode, out := postCredentials(t, server.URL+"/credentials/vault", map[string]string{"role": "dev"})
+	assert.Equal(t, statusCode, http.StatusOK)
+	assert.Equal(t, string(out), `{"token":"secret"}`)
+
+	statusCode, out = postCredentials(t, server.URL+"/credentials/aws", map[string]string{})
+	assert.Equal(t, statusCode, http.StatusInternalServerError)
+	assert.Assert(t, strings.Contains(string(out), "aws credentials forbidden"), string(out))
+}
+
+func TestGitCredentialsAfterEnvReset(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	homedir.DisableCache = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := startTunnelClient(ctx, t, &fakeCredentialProvider{
+		name: agent.GitCredentialProviderName,
+		handle: func(request string) (string, error) {
+			return `{"username":"user","password":"secret"}`, nil
+		},
+	})
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- RunCredentialsServer(ctx, []string{""}, 0, "/usr/local/bin/devpod", "", false, true, false, client, log.Discard)
+	}()
+
+	// wait until the helper is configured
+	gitConfigPath := filepath.Join(homeDir, ".gitconfig")
+	socketPath := filepath.Join(homeDir, ".devpod", "run", "credentials.sock")
+	helper := fmt.Sprintf(`helper = "/usr/local/bin/devpod agent git-credentials --socket %s"`, socketPath)
+	assert.NilError(t, waitFor(func() bool {
+		out, err := os.ReadFile(gitConfigPath)
+		return err == nil && strings.Contains(string(out), helper)
+	}))
+
+	// only the owner can reach the socket
+	info, err := os.Stat(filepath.Dir(socketPath))
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0700))
+
+	// a second server doesn't start, even with another runtime dir
+	otherDir := filepath.Join(t.TempDir(), "run")
+	err = RunCredentialsServer(ctx, []string{""}, 0, "/usr/local/bin/devpod", otherDir, false, true, false, client, log.Discard)
+	assert.NilError(t, err)
+	_, err = os.Stat(otherDir)
+	assert.Assert(t, os.IsNotExist(err))
+
+	// simulate a user switch via sudo that resets the environment, the helper only
+	// needs the binary path and socket from the git config
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PATH", "")
+	helperClient, serverURL, err := NewHelperClient(0, socketPath)
+	assert.NilError(t, err)
+	body, err := json.Marshal(&gitcredentials.GitCredentials{Protocol: "https", Host: "github.com"})
+	assert.NilError(t, err)
+	response, err := helperClient.Post(serverURL+"/git-credentials", "application/json", strings.NewReader(string(body)))
+	assert.NilError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, response.StatusCode, http.StatusOK)
+	credentials := &gitcredentials.GitCredentials{}
+	assert.NilError(t, json.NewDecoder(response.Body).Decode(credentials))
+	assert.Equal(t, credentials.Password, "secret")
+
+	cancel()
+	assert.NilError(t, <-errChan)
+}
+
+func waitFor(condition func() bool) error {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return fmt.Errorf("timed out")
+}
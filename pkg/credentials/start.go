@@ -23,7 +23,7 @@ func StartCredentialsServer(ctx context.Context, cancel context.CancelFunc, clie
 	go func() {
 		defer cancel()
 
-		err := RunCredentialsServer(ctx, "", port, false, false, false, client, log)
+		err := RunCredentialsServer(ctx, nil, port, "", "", false, false, false, client, log)
 		if err != nil {
 			log.Errorf("Error running git credentials server: %v", err)
 		}
@@ -310,6 +310,18 @@ type PortAttribute struct {
 
 type DevPodCustomizations struct {
 	PrebuildRepository types.StrArray `json:"prebuildRepository,omitempty"`
+
+	// Profiles are named environments a session can select via devpod ssh --profile
+	Profiles map[string]DevPodProfile `json:"profiles,omitempty"`
+}
+
+type DevPodProfile struct {
+	// RemoteEnv are environment variables that are set in the session
+	RemoteEnv map[string]string `json:"remoteEnv,omitempty"`
+
+	// RCCommand is executed via sh before the interactive shell starts, only the variables it
+	// exports are set in the session
+	RCCommand string `json:"rcCommand,omitempty"`
 }
 
 type VSCodeCustomizations struct {
@@ -14,10 +14,11 @@ type ImageDetailsConfig struct {
 }
 
 type ContainerDetails struct {
-	ID      string                 `json:"ID,omitempty"`
-	Created string                 `json:"Created,omitempty"`
-	State   ContainerDetailsState  `json:"State,omitempty"`
-	Config  ContainerDetailsConfig `json:"Config,omitempty"`
+	ID       string                 `json:"ID,omitempty"`
+	Created  string                 `json:"Created,omitempty"`
+	Platform string                 `json:"Platform,omitempty"`
+	State    ContainerDetailsState  `json:"State,omitempty"`
+	Config   ContainerDetailsConfig `json:"Config,omitempty"`
 }
 
 type ContainerDetailsConfig struct {
@@ -47,6 +47,29 @@ func GetDevPodCustomizations(parsedConfig *DevContainerConfig) *DevPodCustomizat
 	return devPod
 }
 
+// GetDevPodProfiles returns the profiles of all devpod customizations, a later profile replaces
+// an earlier one with the same name
+func GetDevPodProfiles(mergedConfig *MergedDevContainerConfig) map[string]DevPodProfile {
+	profiles := map[string]DevPodProfile{}
+	if mergedConfig == nil || mergedConfig.Customizations == nil {
+		return profiles
+	}
+
+	for _, customization := range mergedConfig.Customizations["devpod"] {
+		devPod := &DevPodCustomizations{}
+		err := Convert(customization, devPod)
+		if err != nil {
+			continue
+		}
+
+		for name, profile := range devPod.Profiles {
+			profiles[name] = profile
+		}
+	}
+
+	return profiles
+}
+
 func GetVSCodeConfiguration(mergedConfig *MergedDevContainerConfig) *VSCodeCustomizations {
 	if mergedConfig.Customizations == nil || mergedConfig.Customizations["vscode"] == nil {
 		return &VSCodeCustomizations{}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetDevPodProfiles(t *testing.T) {
+	mergedConfig := &MergedDevContainerConfig{}
+	if profiles := GetDevPodProfiles(mergedConfig); len(profiles) != 0 {
+		t.Fatalf("expected no profiles, got %v", profiles)
+	}
+
+	mergedConfig.Customizations = map[string][]interface{}{
+		"devpod": {
+			map[string]interface{}{
+				"profiles": map[string]interface{}{
+					"dev":  map[string]interface{}{"remoteEnv": map[string]interface{}{"APP_ENV": "dev"}},
+					"prod": map[string]interface{}{"remoteEnv": map[string]interface{}{"APP_ENV": "prod"}},
+				},
+			},
+			map[string]interface{}{
+				"profiles": map[string]interface{}{
+					"prod": map[string]interface{}{"rcCommand": "source .env.prod"},
+				},
+			},
+		},
+	}
+	expected := map[string]DevPodProfile{
+		"dev":  {RemoteEnv: map[string]string{"APP_ENV": "dev"}},
+		"prod": {RCCommand: "source .env.prod"},
+	}
+	if profiles := GetDevPodProfiles(mergedConfig); !reflect.DeepEqual(profiles, expected) {
+		t.Fatalf("expected %v, got %v", expected, profiles)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/loft-sh/devpod/pkg/agent"
 	"github.com/loft-sh/devpod/pkg/agent/tunnelserver"
@@ -23,6 +24,11 @@ func (r *runner) setupContainer(
 	containerDetails *config.ContainerDetails,
 	mergedConfig *config.MergedDevContainerConfig,
 ) (*config.Result, error) {
+	// the agent and its ssh server only run on linux
+	if containerDetails != nil && strings.EqualFold(containerDetails.Platform, "windows") {
+		return nil, fmt.Errorf("windows containers are not supported, DevPod requires a linux dev container")
+	}
+
 	// inject agent
 	err := agent.InjectAgent(ctx, func(ctx context.Context, command string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 		return r.Driver.CommandDevContainer(ctx, r.ID, "root", command, stdin, stdout, stderr)
@@ -120,6 +126,7 @@ func (r *runner) setupContainer(
 		r.WorkspaceConfig.Agent.InjectDockerCredentials != "false",
 		config.GetMounts(result),
 		r.Log,
+		tunnelserver.WithForwardPolicy(tunnelserver.ParseForwardPolicy(r.WorkspaceConfig.CLIOptions.ForwardAllowlist, r.WorkspaceConfig.CLIOptions.ForwardDenylist)),
 	)
 	if err != nil {
 		return nil, errors.Wrap(err, "run tunnel machine")
@@ -3,6 +3,7 @@ package dockercredentials
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 
 	"github.com/docker/cli/cli/config"
@@ -30,27 +31,54 @@ type Credentials struct {
 	Secret    string
 }
 
-func ConfigureCredentialsContainer(userName string, port int, log log.Logger) error {
+// ConfigureCredentialsContainer configures the devpod docker credentials helper for the user,
+// binaryPath is the devpod binary the helper invokes and defaults to the current executable.
+// The helper talks to the credentials server on socketPath, or on the socket of the calling
+// user if it is empty
+func ConfigureCredentialsContainer(userName, binaryPath, socketPath string, log log.Logger) error {
+	helperArgs := ""
+	if socketPath != "" {
+		helperArgs = fmt.Sprintf(" --socket '%s'", socketPath)
+	}
+
 	userHome, err := command.GetHome(userName)
 	if err != nil {
 		return err
 	}
 
-	configDir := os.Getenv("DOCKER_CONFIG")
-	if configDir == "" {
-		configDir = filepath.Join(userHome, ".docker")
+	// DOCKER_CONFIG is usually dropped when switching users via sudo, so the config in the
+	// home of the user is always configured as well
+	configDirs := []string{filepath.Join(userHome, ".docker")}
+	if os.Getenv("DOCKER_CONFIG") != "" && filepath.Clean(os.Getenv("DOCKER_CONFIG")) != configDirs[0] {
+		configDirs = append(configDirs, os.Getenv("DOCKER_CONFIG"))
 	}
 
-	return configureCredentials(userName, "#!/bin/sh", "/usr/local/bin", configDir, port, log)
-}
+	for _, configDir := range configDirs {
+		err = configureCredentials(userName, binaryPath, "#!/bin/sh", "/usr/local/bin", configDir, helperArgs, log)
+		if err != nil {
+			return err
+		}
+	}
 
-func configureCredentials(userName, shebang string, targetDir, configDir string, port int, log log.Logger) error {
-	binaryPath, err := os.Executable()
+	// docker looks up the helper via PATH
+	_, err = exec.LookPath("docker-credential-devpod")
 	if err != nil {
-		return err
+		log.Warnf("Docker credentials helper is not in PATH, make sure /usr/local/bin is part of the PATH within the container: %v", err)
+	}
+
+	return nil
+}
+
+func configureCredentials(userName, binaryPath, shebang string, targetDir, configDir string, helperArgs string, log log.Logger) error {
+	if binaryPath == "" {
+		var err error
+		binaryPath, err = os.Executable()
+		if err != nil {
+			return err
+		}
 	}
 
-	err = file.MkdirAll(userName, configDir, 0777)
+	err := file.MkdirAll(userName, configDir, 0777)
 	if err != nil {
 		return err
 	}
@@ -64,7 +92,7 @@ func configureCredentials(userName, shebang string, targetDir, configDir string,
 	credentialHelperPath := filepath.Join(targetDir, "docker-credential-devpod")
 	log.Debugf("Wrote docker credentials helper to %s", credentialHelperPath)
 	err = os.WriteFile(credentialHelperPath, []byte(fmt.Sprintf(shebang+`
-'%s' agent docker-credentials --port '%d' "$@"`, binaryPath, port)), 0777)
+'%s' agent docker-credentials%s "$@"`, binaryPath, helperArgs)), 0777)
 	if err != nil {
 		return errors.Wrap(err, "write credential helper")
 	}
@@ -85,7 +113,7 @@ func configureCredentials(userName, shebang string, targetDir, configDir string,
 
 func ConfigureCredentialsDockerless(targetFolder string, port int, log log.Logger) (string, error) {
 	dockerConfigDir := filepath.Join(targetFolder, ".cache", random.String(6))
-	err := configureCredentials("", "#!/.dockerless/bin/sh", dockerConfigDir, dockerConfigDir, port, log)
+	err := configureCredentials("", "", "#!/.dockerless/bin/sh", dockerConfigDir, dockerConfigDir, fmt.Sprintf(" --port '%d'", port), log)
 	if err != nil {
 		_ = os.RemoveAll(dockerConfigDir)
 		return "", err
@@ -108,7 +136,7 @@ func ConfigureCredentialsDockerless(targetFolder string, port int, log log.Logge
 
 func ConfigureCredentialsMachine(targetFolder string, port int, log log.Logger) (string, error) {
 	dockerConfigDir := filepath.Join(targetFolder, ".cache", random.String(12))
-	err := configureCredentials("", "#!/bin/sh", dockerConfigDir, dockerConfigDir, port, log)
+	err := configureCredentials("", "", "#!/bin/sh", dockerConfigDir, dockerConfigDir, fmt.Sprintf(" --port '%d'", port), log)
 	if err != nil {
 		_ = os.RemoveAll(dockerConfigDir)
 		return "", err
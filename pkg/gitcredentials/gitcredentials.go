@@ -29,7 +29,9 @@ type GitUser struct {
 	Email string `json:"email,omitempty"`
 }
 
-func ConfigureHelper(binaryPath, userName string, port int) error {
+// ConfigureHelper configures the devpod git credentials helper for the user, which talks
+// to the credentials server on the given socket
+func ConfigureHelper(binaryPath, userName, socketPath string) error {
 	homeDir, err := command.GetHome(userName)
 	if err != nil {
 		return err
@@ -42,11 +44,11 @@ func ConfigureHelper(binaryPath, userName string, port int) error {
 	}
 
 	config := string(out)
-	if !strings.Contains(config, fmt.Sprintf(`helper = "%s agent git-credentials --port %d"`, binaryPath, port)) {
+	if !strings.Contains(config, fmt.Sprintf(`helper = "%s agent git-credentials --socket %s"`, binaryPath, socketPath)) {
 		content := removeCredentialHelper(config) + fmt.Sprintf(`
 [credential]
-        helper = "%s agent git-credentials --port %d"
-`, binaryPath, port)
+        helper = "%s agent git-credentials --socket %s"
+`, binaryPath, socketPath)
 
 		err = os.WriteFile(gitConfigPath, []byte(content), 0644)
 		if err != nil {
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// CaptivePortalProbeURL returns 204 without content, a captive portal redirects it to its login
+// page or answers with the page itself
+const CaptivePortalProbeURL = "http://connectivitycheck.gstatic.com/generate_204"
+
+// CaptivePortalTimeout is how long the captive portal probe may take
+const CaptivePortalTimeout = 3 * time.Second
+
+// CaptivePortal is a network that intercepts requests until the user logged in
+type CaptivePortal struct {
+	// LoginURL is the page the portal redirects to or the probe url if it answered directly
+	LoginURL string
+}
+
+func (c *CaptivePortal) Error() string {
+	return fmt.Sprintf("you appear to be behind a captive portal, log in via %s and try again", c.LoginURL)
+}
+
+// ProbeCaptivePortal requests the probe url and returns the captive portal if the answer is
+// not the expected 204. It returns nil without a portal and an error if the probe failed,
+// e.g. because there is no network at all.
+func ProbeCaptivePortal(ctx context.Context, probeURL string) (*CaptivePortal, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return nil, nil
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		location, err := resp.Location()
+		if err != nil {
+			return &CaptivePortal{LoginURL: probeURL}, nil
+		}
+
+		return &CaptivePortal{LoginURL: location.String()}, nil
+	case resp.StatusCode == http.StatusOK:
+		return &CaptivePortal{LoginURL: probeURL}, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestProbeCaptivePortal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/generate_204":
+			w.WriteHeader(http.StatusNoContent)
+		case "/redirect":
+			http.Redirect(w, r, "http://portal.example.com/login", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte("<html>Please log in</html>"))
+		}
+	}))
+	defer server.Close()
+
+	portal, err := ProbeCaptivePortal(context.Background(), server.URL+"/generate_204")
+	assert.NilError(t, err)
+	assert.Assert(t, portal == nil)
+
+	portal, err = ProbeCaptivePortal(context.Background(), server.URL+"/redirect")
+	assert.NilError(t, err)
+	assert.Equal(t, portal.LoginURL, "http://portal.example.com/login")
+
+	portal, err = ProbeCaptivePortal(context.Background(), server.URL+"/page")
+	assert.NilError(t, err)
+	assert.Equal(t, portal.LoginURL, server.URL+"/page")
+	assert.ErrorContains(t, portal, "captive portal")
+}
@@ -170,7 +170,7 @@ func inject(
 
 	lineStr := strings.TrimSpace(line)
 	if isInjectingOfBinaryNeeded(lineStr) {
-		log.Debugf("Inject binary")
+		log.Infof("Installing DevPod agent...")
 		defer log.Debugf("Done injecting binary")
 
 		fileReader, err := getFileReader(localFile, lineStr)
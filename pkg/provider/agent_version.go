@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AgentVersionFile holds the version of the agent that was last found in the workspace container
+const AgentVersionFile = "agent_version"
+
+// LoadAgentVersion returns the version of the agent that was last found in the workspace
+// container or an empty string if it is unknown
+func LoadAgentVersion(context, workspaceID string) (string, error) {
+	workspaceDir, err := GetWorkspaceDir(context, workspaceID)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := os.ReadFile(filepath.Join(workspaceDir, AgentVersionFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// SaveAgentVersion saves the version of the agent found in the workspace container
+func SaveAgentVersion(context, workspaceID, version string) error {
+	workspaceDir, err := GetWorkspaceDir(context, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(workspaceDir, AgentVersionFile), []byte(version), 0666)
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const BannerAckFile = "banner_ack"
+
+// BannerAcknowledged returns true if the given banner was acknowledged today
+func BannerAcknowledged(context, workspaceID, banner string) (bool, error) {
+	workspaceDir, err := GetWorkspaceDir(context, workspaceID)
+	if err != nil {
+		return false, err
+	}
+
+	out, err := os.ReadFile(filepath.Join(workspaceDir, BannerAckFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return strings.TrimSpace(string(out)) == bannerAck(banner), nil
+}
+
+// AcknowledgeBanner suppresses the given banner for the rest of the day, a changed
+// banner is shown again
+func AcknowledgeBanner(context, workspaceID, banner string) error {
+	workspaceDir, err := GetWorkspaceDir(context, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(workspaceDir, BannerAckFile), []byte(bannerAck(banner)), 0666)
+}
+
+func bannerAck(banner string) string {
+	hash := sha256.Sum256([]byte(banner))
+	return fmt.Sprintf("%s %s", time.Now().Format("2006-01-02"), hex.EncodeToString(hash[:]))
+}
@@ -47,6 +47,15 @@ func GetLocksDir(context string) (string, error) {
 	return filepath.Join(configDir, "contexts", context, "locks"), nil
 }
 
+func GetSessionsDir(context string) (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "contexts", context, "sessions"), nil
+}
+
 func GetWorkspacesDir(context string) (string, error) {
 	configDir, err := config.GetConfigDir()
 	if err != nil {
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/gofrs/flock"
+	"github.com/loft-sh/devpod/pkg/types"
+)
+
+const ConnectionHistoryFile = "connection_history.json"
+
+// MaxConnectionHistory is the maximum amount of entries kept in the connection history
+const MaxConnectionHistory = 50
+
+type ConnectionHistoryEntry struct {
+	// Timestamp is the time the connection was started
+	Timestamp types.Time `json:"timestamp,omitempty"`
+
+	// User is the user that was used to connect
+	User string `json:"user,omitempty"`
+
+	// Duration is the duration of the connection
+	Duration string `json:"duration,omitempty"`
+
+	// Success signals if the connection ended without an error
+	Success bool `json:"success,omitempty"`
+
+	// Error holds the error if the connection failed
+	Error string `json:"error,omitempty"`
+
+	// ExitCode is the exit code the session ended with, a non-zero exit code doesn't mean that
+	// the connection failed
+	ExitCode int `json:"exitCode,omitempty"`
+}
+
+// AppendConnectionHistory adds the entry to the workspace connection history. If the
+// history is currently written by another process, the entry is skipped instead of waiting.
+func AppendConnectionHistory(context, workspaceID string, entry ConnectionHistoryEntry) error {
+	workspaceDir, err := GetWorkspaceDir(context, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	fileLock := flock.New(filepath.Join(workspaceDir, ConnectionHistoryFile+".lock"))
+	locked, err := fileLock.TryLock()
+	if err != nil {
+		return err
+	} else if !locked {
+		return nil
+	}
+	defer func(fileLock *flock.Flock) {
+		_ = fileLock.Unlock()
+	}(fileLock)
+
+	history, err := LoadConnectionHistory(context, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	history = append(history, entry)
+	if len(history) > MaxConnectionHistory {
+		history = history[len(history)-MaxConnectionHistory:]
+	}
+
+	out, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so readers never see a partially written history
+	historyFile := filepath.Join(workspaceDir, ConnectionHistoryFile)
+	err = os.WriteFile(historyFile+".tmp", out, 0666)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(historyFile+".tmp", historyFile)
+}
+
+// LoadConnectionHistory returns the connection history of the workspace, oldest entry first
+func LoadConnectionHistory(context, workspaceID string) ([]ConnectionHistoryEntry, error) {
+	workspaceDir, err := GetWorkspaceDir(context, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := os.ReadFile(filepath.Join(workspaceDir, ConnectionHistoryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ConnectionHistoryEntry{}, nil
+		}
+
+		return nil, err
+	}
+
+	return parseConnectionHistory(out)
+}
+
+// parseConnectionHistory parses the history and keeps all complete entries if the history
+// was only partially written
+func parseConnectionHistory(out []byte) ([]ConnectionHistoryEntry, error) {
+	history := []ConnectionHistoryEntry{}
+	err := json.Unmarshal(out, &history)
+	if err == nil {
+		return history, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	token, tokenErr := decoder.Token()
+	if tokenErr != nil || token != json.Delim('[') {
+		return nil, err
+	}
+
+	history = []ConnectionHistoryEntry{}
+	for decoder.More() {
+		entry := ConnectionHistoryEntry{}
+		if decoder.Decode(&entry) != nil {
+			break
+		}
+
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,21 @@
+package provider
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseConnectionHistory(t *testing.T) {
+	history, err := parseConnectionHistory([]byte(`[{"user":"root","success":true},{"user":"vscode"}]`))
+	assert.NilError(t, err)
+	assert.Equal(t, len(history), 2)
+
+	// a partially written last entry is skipped
+	history, err = parseConnectionHistory([]byte(`[{"user":"root","success":true},{"user":"vsc`))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, history, []ConnectionHistoryEntry{{User: "root", Success: true}})
+
+	_, err = parseConnectionHistory([]byte(`{"user":"root"}`))
+	assert.Assert(t, err != nil)
+}
@@ -5,7 +5,8 @@ import (
 )
 
 const (
-	CommandEnv = "COMMAND"
+	CommandEnv    = "COMMAND"
+	SnapshotIDEnv = "SNAPSHOT_ID"
 )
 
 type ProviderConfig struct {
@@ -216,6 +217,10 @@ type ProviderCommands struct {
 	// Create creates a new server
 	Create types.StrArray `json:"create,omitempty"`
 
+	// CreateFromSnapshot creates a new server from the snapshot in SNAPSHOT_ID. Optional,
+	// without it the provider doesn't support snapshots
+	CreateFromSnapshot types.StrArray `json:"createFromSnapshot,omitempty"`
+
 	// Delete destroys a server
 	Delete types.StrArray `json:"delete,omitempty"`
 
@@ -228,6 +233,10 @@ type ProviderCommands struct {
 	// Status retrieves the server status
 	Status types.StrArray `json:"status,omitempty"`
 
+	// Preflight validates the credentials and connectivity of the provider before a workspace
+	// is started. Optional, without it no check is done
+	Preflight types.StrArray `json:"preflight,omitempty"`
+
 	// Proxy proxies commands
 	Proxy *ProxyCommands `json:"proxy,omitempty"`
 }
@@ -257,6 +266,10 @@ func (c *ProviderConfig) IsMachineProvider() bool {
 	return len(c.Exec.Create) > 0
 }
 
+func (c *ProviderConfig) SupportsSnapshots() bool {
+	return c.IsMachineProvider() && len(c.Exec.CreateFromSnapshot) > 0
+}
+
 func (c *ProviderConfig) IsProxyProvider() bool {
 	return c.Exec.Proxy != nil
 }
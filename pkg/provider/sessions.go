@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/loft-sh/devpod/pkg/command"
+)
+
+// RegisterSession marks the current process as an active ssh session to the workspace.
+// The returned function removes the session again.
+func RegisterSession(context, workspaceID string) (func(), error) {
+	sessionsDir, err := GetSessionsDir(context)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(sessionsDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionFile := filepath.Join(sessionsDir, strconv.Itoa(os.Getpid()))
+	err = os.WriteFile(sessionFile, []byte(workspaceID), 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = os.Remove(sessionFile)
+	}, nil
+}
+
+// CountActiveSessions returns the amount of active ssh sessions within the context. Sessions
+// of processes that are not running anymore are removed.
+func CountActiveSessions(context string) (int, error) {
+	sessionsDir, err := GetSessionsDir(context)
+	if err != nil {
+		return 0, err
+	}
+
+	entries, err := os.ReadDir(sessionsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	count := 0
+	for _, entry := range entries {
+		isRunning, err := command.IsRunning(entry.Name())
+		if err != nil || !isRunning {
+			_ = os.Remove(filepath.Join(sessionsDir, entry.Name()))
+			continue
+		}
+
+		count++
+	}
+
+	return count, nil
+}
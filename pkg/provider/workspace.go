@@ -39,6 +39,9 @@ type Workspace struct {
 	// Source is the source where this workspace will be created from
 	Source WorkspaceSource `json:"source,omitempty"`
 
+	// SSH holds ssh specific settings
+	SSH WorkspaceSSHConfig `json:"ssh,omitempty"`
+
 	// DevContainerImage is the container image to use, overriding whatever is in the devcontainer.json
 	DevContainerImage string `json:"devContainerImage,omitempty"`
 
@@ -61,6 +64,14 @@ type Workspace struct {
 	Origin string `json:"-"`
 }
 
+type WorkspaceSSHConfig struct {
+	// RestrictedCommands are the commands allowed in a restricted ssh session
+	RestrictedCommands []string `json:"restrictedCommands,omitempty"`
+
+	// Banner is a notice printed before an interactive ssh session starts
+	Banner string `json:"banner,omitempty"`
+}
+
 type WorkspaceIDEConfig struct {
 	// Name is the name of the IDE
 	Name string `json:"name,omitempty"`
@@ -162,6 +173,11 @@ type CLIOptions struct {
 	DisableDaemon        bool     `json:"disableDaemon,omitempty"`
 	DaemonInterval       string   `json:"daemonInterval,omitempty"`
 
+	// ForwardAllowlist and ForwardDenylist are the comma separated patterns of the forward
+	// policy of the context, they restrict the host data that is forwarded into the workspace
+	ForwardAllowlist string `json:"forwardAllowlist,omitempty"`
+	ForwardDenylist  string `json:"forwardDenylist,omitempty"`
+
 	// build options
 	Repository string   `json:"repository,omitempty"`
 	SkipPush   bool     `json:"skipPush,omitempty"`
@@ -0,0 +1,166 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const agentChannelType = "auth-agent@openssh.com"
+
+// ParseAgentKeyFingerprints validates the fingerprints given to --forward-agent-keys. They need
+// to be in the SHA256 format ssh-add -l prints.
+func ParseAgentKeyFingerprints(fingerprints []string) ([]string, error) {
+	parsed := []string{}
+	for _, fingerprint := range fingerprints {
+		fingerprint = strings.TrimSpace(fingerprint)
+		if fingerprint == "" {
+			continue
+		}
+
+		encoded, ok := strings.CutPrefix(fingerprint, "SHA256:")
+		if !ok || encoded == "" || strings.ContainsAny(encoded, " =") {
+			return nil, fmt.Errorf("invalid key fingerprint %q, expected the SHA256:... format printed by 'ssh-add -l'", fingerprint)
+		}
+
+		parsed = append(parsed, fingerprint)
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("no key fingerprints given")
+	}
+
+	return parsed, nil
+}
+
+// filteredAgent exposes only the keys with the allowed fingerprints of the wrapped agent. The
+// remote side can't change the local agent, so adding, removing and locking keys is refused.
+type filteredAgent struct {
+	agent   agent.ExtendedAgent
+	allowed map[string]bool
+}
+
+// NewFilteredAgent returns an agent that only lists and signs with the keys of the given agent
+// whose SHA256 fingerprint is in fingerprints.
+func NewFilteredAgent(upstream agent.ExtendedAgent, fingerprints []string) agent.ExtendedAgent {
+	allowed := map[string]bool{}
+	for _, fingerprint := range fingerprints {
+		allowed[fingerprint] = true
+	}
+
+	return &filteredAgent{agent: upstream, allowed: allowed}
+}
+
+func (f *filteredAgent) isAllowed(key ssh.PublicKey) bool {
+	return f.allowed[ssh.FingerprintSHA256(key)]
+}
+
+func (f *filteredAgent) List() ([]*agent.Key, error) {
+	keys, err := f.agent.List()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := []*agent.Key{}
+	for _, key := range keys {
+		if f.isAllowed(key) {
+			filtered = append(filtered, key)
+		}
+	}
+
+	return filtered, nil
+}
+
+func (f *filteredAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return f.SignWithFlags(key, data, 0)
+}
+
+func (f *filteredAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	if !f.isAllowed(key) {
+		return nil, fmt.Errorf("key %s is not forwarded", ssh.FingerprintSHA256(key))
+	}
+
+	return f.agent.SignWithFlags(key, data, flags)
+}
+
+func (f *filteredAgent) Signers() ([]ssh.Signer, error) {
+	signers, err := f.agent.Signers()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := []ssh.Signer{}
+	for _, signer := range signers {
+		if f.isAllowed(signer.PublicKey()) {
+			filtered = append(filtered, signer)
+		}
+	}
+
+	return filtered, nil
+}
+
+func (f *filteredAgent) Add(key agent.AddedKey) error {
+	return fmt.Errorf("adding keys to a filtered agent is not allowed")
+}
+
+func (f *filteredAgent) Remove(key ssh.PublicKey) error {
+	return fmt.Errorf("removing keys from a filtered agent is not allowed")
+}
+
+func (f *filteredAgent) RemoveAll() error {
+	return fmt.Errorf("removing keys from a filtered agent is not allowed")
+}
+
+func (f *filteredAgent) Lock(passphrase []byte) error {
+	return fmt.Errorf("locking a filtered agent is not allowed")
+}
+
+func (f *filteredAgent) Unlock(passphrase []byte) error {
+	return fmt.Errorf("unlocking a filtered agent is not allowed")
+}
+
+func (f *filteredAgent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
+
+// ForwardFilteredAgentToRemote works like agent.ForwardToRemote, but every agent connection the
+// remote side opens only sees the keys with the given fingerprints. The requests are answered
+// locally, so the remote side never talks to the agent at authSock directly.
+func ForwardFilteredAgentToRemote(client *ssh.Client, authSock string, fingerprints []string) error {
+	channels := client.HandleChannelOpen(agentChannelType)
+	if channels == nil {
+		return fmt.Errorf("agent forwarding is already set up")
+	}
+
+	// fail early if the agent isn't reachable
+	conn, err := net.Dial("unix", authSock)
+	if err != nil {
+		return err
+	}
+	_ = conn.Close()
+
+	go func() {
+		for newChannel := range channels {
+			channel, reqs, err := newChannel.Accept()
+			if err != nil {
+				continue
+			}
+			go ssh.DiscardRequests(reqs)
+
+			go func() {
+				defer channel.Close()
+				conn, err := net.Dial("unix", authSock)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+
+				_ = agent.ServeAgent(NewFilteredAgent(agent.NewClient(conn), fingerprints), channel)
+			}()
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"gotest.tools/assert"
+)
+
+func TestFilteredAgent(t *testing.T) {
+	keyring := agent.NewKeyring().(agent.ExtendedAgent)
+	fingerprints := []string{}
+	for i := 0; i < 2; i++ {
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		assert.NilError(t, err)
+		assert.NilError(t, keyring.Add(agent.AddedKey{PrivateKey: privateKey}))
+
+		signer, err := ssh.NewSignerFromKey(privateKey)
+		assert.NilError(t, err)
+		fingerprints = append(fingerprints, ssh.FingerprintSHA256(signer.PublicKey()))
+	}
+
+	filtered := NewFilteredAgent(keyring, fingerprints[:1])
+	keys, err := filtered.List()
+	assert.NilError(t, err)
+	assert.Equal(t, len(keys), 1)
+	assert.Equal(t, ssh.FingerprintSHA256(keys[0]), fingerprints[0])
+
+	signers, err := filtered.Signers()
+	assert.NilError(t, err)
+	assert.Equal(t, len(signers), 1)
+
+	_, err = filtered.Sign(keys[0], []byte("data"))
+	assert.NilError(t, err)
+
+	allKeys, err := keyring.List()
+	assert.NilError(t, err)
+	for _, key := range allKeys {
+		if ssh.FingerprintSHA256(key) == fingerprints[1] {
+			_, err = filtered.Sign(key, []byte("data"))
+			assert.ErrorContains(t, err, "is not forwarded")
+		}
+	}
+
+	assert.ErrorContains(t, filtered.RemoveAll(), "not allowed")
+	allKeys, err = keyring.List()
+	assert.NilError(t, err)
+	assert.Equal(t, len(allKeys), 2)
+}
+
+func TestParseAgentKeyFingerprints(t *testing.T) {
+	fingerprints, err := ParseAgentKeyFingerprints([]string{" SHA256:abc ", "", "SHA256:def"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, fingerprints, []string{"SHA256:abc", "SHA256:def"})
+
+	_, err = ParseAgentKeyFingerprints([]string{"MD5:aa:bb"})
+	assert.ErrorContains(t, err, "invalid key fingerprint")
+
+	_, err = ParseAgentKeyFingerprints([]string{""})
+	assert.ErrorContains(t, err, "no key fingerprints")
+}
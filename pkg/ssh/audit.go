@@ -0,0 +1,111 @@
+package ssh
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// AuditRecord is a single connection record of the audit log
+type AuditRecord struct {
+	// Timestamp is the time the connection was started
+	Timestamp string `json:"timestamp"`
+
+	// Workspace is the workspace that was connected to
+	Workspace string `json:"workspace,omitempty"`
+
+	// User is the user that was used to connect
+	User string `json:"user,omitempty"`
+
+	// HostKeyFingerprint is the SHA256 fingerprint of the host key presented by the server
+	HostKeyFingerprint string `json:"hostKeyFingerprint,omitempty"`
+
+	// ClientVersion is the ssh version string of the client
+	ClientVersion string `json:"clientVersion,omitempty"`
+
+	// ServerVersion is the ssh version string of the server
+	ServerVersion string `json:"serverVersion,omitempty"`
+
+	// Success signals if the connection ended without an error
+	Success bool `json:"success"`
+
+	// Error holds the error if the connection failed
+	Error string `json:"error,omitempty"`
+}
+
+// AuditLog collects the parameters of a connection and appends them as a json line to a file
+type AuditLog struct {
+	m sync.Mutex
+
+	path   string
+	record AuditRecord
+}
+
+// NewAuditLog creates a new audit log for a connection to the workspace
+func NewAuditLog(path, workspace, user string) *AuditLog {
+	return &AuditLog{
+		path: path,
+		record: AuditRecord{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Workspace: workspace,
+			User:      user,
+		},
+	}
+}
+
+func (a *AuditLog) hostKeyCallback(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	a.record.HostKeyFingerprint = ssh.FingerprintSHA256(key)
+	return nil
+}
+
+func (a *AuditLog) connected(conn ssh.ConnMetadata) {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	a.record.User = conn.User()
+	a.record.ClientVersion = string(conn.ClientVersion())
+	a.record.ServerVersion = string(conn.ServerVersion())
+}
+
+// Write appends the record with the result of the connection to the audit log
+func (a *AuditLog) Write(err error) error {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	a.record.Success = err == nil
+	if err != nil {
+		a.record.Error = err.Error()
+	}
+
+	out, err := json.Marshal(a.record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(out, '\n'))
+	return err
+}
+
+// StdioClientWithAudit creates a new ssh client like StdioClientWithUser and records the
+// negotiated connection parameters in audit if it is not nil
+func StdioClientWithAudit(reader io.Reader, writer io.WriteCloser, user string, audit *AuditLog) (*ssh.Client, error) {
+	if audit == nil {
+		return StdioClientWithUser(reader, writer, user, false)
+	}
+
+	return StdioClientWithOptions(reader, writer, user, ClientOptions{Audit: audit})
+}
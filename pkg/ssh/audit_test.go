@@ -0,0 +1,38 @@
+package ssh
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	assert.NilError(t, NewAuditLog(path, "test", "vscode").Write(nil))
+	assert.NilError(t, NewAuditLog(path, "test", "root").Write(fmt.Errorf("workspace is stopped")))
+
+	out, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Equal(t, len(lines), 2)
+
+	records := []AuditRecord{}
+	for _, line := range lines {
+		record := AuditRecord{}
+		assert.NilError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+
+	assert.Equal(t, records[0].Workspace, "test")
+	assert.Equal(t, records[0].User, "vscode")
+	assert.Assert(t, records[0].Success)
+	assert.Equal(t, records[1].User, "root")
+	assert.Assert(t, !records[1].Success)
+	assert.Equal(t, records[1].Error, "workspace is stopped")
+}
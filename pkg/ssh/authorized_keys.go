@@ -0,0 +1,53 @@
+package ssh
+
+import (
+	"bytes"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// HasAuthorizedKey returns true if the authorized_keys content already contains the key,
+// comments and options of the entries are ignored
+func HasAuthorizedKey(authorizedKeys []byte, key ssh.PublicKey) bool {
+	for len(authorizedKeys) > 0 {
+		existingKey, _, _, rest, err := ssh.ParseAuthorizedKey(authorizedKeys)
+		if err != nil {
+			return false
+		} else if bytes.Equal(existingKey.Marshal(), key.Marshal()) {
+			return true
+		}
+
+		authorizedKeys = rest
+	}
+
+	return false
+}
+
+// ReplaceAuthorizedKey removes the entries of oldKey from the authorized_keys content and adds
+// newKey if it isn't authorized yet. oldKey may be nil. Lines that cannot be parsed are kept.
+func ReplaceAuthorizedKey(authorizedKeys []byte, oldKey, newKey ssh.PublicKey) []byte {
+	out := &bytes.Buffer{}
+	for _, line := range bytes.SplitAfter(authorizedKeys, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		if oldKey != nil {
+			existingKey, _, _, _, err := ssh.ParseAuthorizedKey(line)
+			if err == nil && bytes.Equal(existingKey.Marshal(), oldKey.Marshal()) {
+				continue
+			}
+		}
+
+		out.Write(line)
+	}
+	if HasAuthorizedKey(out.Bytes(), newKey) {
+		return out.Bytes()
+	}
+
+	if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+		out.WriteString("\n")
+	}
+	out.Write(ssh.MarshalAuthorizedKey(newKey))
+	return out.Bytes()
+}
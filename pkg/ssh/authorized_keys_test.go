@@ -0,0 +1,48 @@
+package ssh
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"gotest.tools/assert"
+)
+
+func TestHasAuthorizedKey(t *testing.T) {
+	publicKey, _, err := makeSSHKeyPair()
+	assert.NilError(t, err)
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	assert.NilError(t, err)
+	otherPublicKey, _, err := makeSSHKeyPair()
+	assert.NilError(t, err)
+
+	assert.Assert(t, !HasAuthorizedKey(nil, key))
+	assert.Assert(t, !HasAuthorizedKey([]byte(otherPublicKey), key))
+	assert.Assert(t, HasAuthorizedKey([]byte("# comment\n"+otherPublicKey+publicKey), key))
+
+	// options and comments of the entry are ignored
+	entry := "no-pty " + strings.TrimSpace(publicKey) + " teammate@laptop\n"
+	assert.Assert(t, HasAuthorizedKey([]byte(entry), key))
+}
+
+func TestReplaceAuthorizedKey(t *testing.T) {
+	oldPublicKey, _, err := makeSSHKeyPair()
+	assert.NilError(t, err)
+	oldKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(oldPublicKey))
+	assert.NilError(t, err)
+	newPublicKey, _, err := makeSSHKeyPair()
+	assert.NilError(t, err)
+	newKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(newPublicKey))
+	assert.NilError(t, err)
+	otherPublicKey, _, err := makeSSHKeyPair()
+	assert.NilError(t, err)
+
+	// the old key is replaced, other entries are kept
+	authorizedKeys := "# comment\n" + otherPublicKey + "no-pty " + strings.TrimSpace(oldPublicKey) + " devpod"
+	out := ReplaceAuthorizedKey([]byte(authorizedKeys), oldKey, newKey)
+	assert.Equal(t, string(out), "# comment\n"+otherPublicKey+newPublicKey)
+
+	// the new key is not added twice
+	assert.Equal(t, string(ReplaceAuthorizedKey(out, nil, newKey)), string(out))
+	assert.Equal(t, string(ReplaceAuthorizedKey(nil, oldKey, newKey)), newPublicKey)
+}
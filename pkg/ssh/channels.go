@@ -0,0 +1,69 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// channelQueueTimeout is the time a new channel waits for a free slot of the ChannelLimiter
+const channelQueueTimeout = 10 * time.Second
+
+// ChannelLimiter limits the number of channels DevPod opens concurrently on a connection, so
+// editors opening many forwarded connections cannot exhaust a constrained workspace
+type ChannelLimiter struct {
+	slots chan struct{}
+}
+
+// NewChannelLimiter creates a new limiter for max channels, a max of 0 or less disables the limit
+// and returns nil
+func NewChannelLimiter(max int) *ChannelLimiter {
+	if max <= 0 {
+		return nil
+	}
+
+	return &ChannelLimiter{slots: make(chan struct{}, max)}
+}
+
+// Acquire waits until a channel can be opened and fails if no channel becomes free in time
+func (c *ChannelLimiter) Acquire(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(channelQueueTimeout):
+		return fmt.Errorf("all %d channels are in use, please close unused connections or raise the limit via --max-channels", cap(c.slots))
+	}
+}
+
+// Release frees a channel acquired via Acquire
+func (c *ChannelLimiter) Release() {
+	if c == nil {
+		return
+	}
+
+	<-c.slots
+}
+
+// ChannelError explains channels the workspace refused to open due to a resource shortage,
+// which usually means the ssh server limits the number of sessions
+func ChannelError(err error) error {
+	if isResourceShortage(err) {
+		return fmt.Errorf("the workspace refused to open another channel, because its ssh server limits the number of sessions (e.g. MaxSessions of sshd). Please close unused connections or limit the channels of DevPod via --max-channels: %w", err)
+	}
+
+	return err
+}
+
+func isResourceShortage(err error) bool {
+	openChannelErr := &ssh.OpenChannelError{}
+	return errors.As(err, &openChannelErr) && openChannelErr.Reason == ssh.ResourceShortage
+}
@@ -0,0 +1,24 @@
+package ssh
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestChannelLimiter(t *testing.T) {
+	assert.Assert(t, NewChannelLimiter(0) == nil)
+	assert.NilError(t, NewChannelLimiter(0).Acquire(context.Background()))
+
+	limiter := NewChannelLimiter(1)
+	assert.NilError(t, limiter.Acquire(context.Background()))
+
+	// a full limiter waits for a free channel
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, limiter.Acquire(ctx), context.Canceled)
+
+	limiter.Release()
+	assert.NilError(t, limiter.Acquire(context.Background()))
+}
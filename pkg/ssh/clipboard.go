@@ -0,0 +1,48 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/loft-sh/devpod/pkg/command"
+)
+
+// WriteClipboard sets the local clipboard via the clipboard tool of the operating system, which is
+// pbcopy on macOS, clip on Windows and wl-copy, xclip or xsel on Linux
+func WriteClipboard(content []byte) error {
+	args, err := clipboardCommand()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(content)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return command.WrapCommandError(out, err)
+	}
+
+	return nil
+}
+
+func clipboardCommand() ([]string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"pbcopy"}, nil
+	case "windows":
+		return []string{"clip"}, nil
+	}
+
+	if os.Getenv("WAYLAND_DISPLAY") != "" && command.Exists("wl-copy") {
+		return []string{"wl-copy"}, nil
+	} else if command.Exists("xclip") {
+		return []string{"xclip", "-selection", "clipboard"}, nil
+	} else if command.Exists("xsel") {
+		return []string{"xsel", "--clipboard", "--input"}, nil
+	}
+
+	return nil, fmt.Errorf("no clipboard tool found, please install wl-copy, xclip or xsel")
+}
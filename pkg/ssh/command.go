@@ -0,0 +1,30 @@
+package ssh
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// DecodeCommand decodes the base64 encoded command of --command-base64. The decoded command is
+// sent as is over the exec channel, so only the shell of the user in the workspace parses it.
+func DecodeCommand(encoded string) (string, error) {
+	encoded = strings.TrimSpace(encoded)
+	command, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// tools often strip the padding
+		var rawErr error
+		command, rawErr = base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+		if rawErr != nil {
+			return "", fmt.Errorf("decode command: %w", err)
+		}
+	}
+
+	if strings.TrimSpace(string(command)) == "" {
+		return "", fmt.Errorf("decoded command is empty")
+	} else if strings.ContainsRune(string(command), 0) {
+		return "", fmt.Errorf("decoded command contains a NUL byte, which a shell cannot run")
+	}
+
+	return string(command), nil
+}
@@ -0,0 +1,26 @@
+package ssh
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestDecodeCommand(t *testing.T) {
+	command := `echo "it's $HOME" | sed 's/"/\\"/g'`
+	decoded, err := DecodeCommand(base64.StdEncoding.EncodeToString([]byte(command)) + "\n")
+	assert.NilError(t, err)
+	assert.Equal(t, decoded, command)
+
+	decoded, err = DecodeCommand(base64.RawStdEncoding.EncodeToString([]byte(command)))
+	assert.NilError(t, err)
+	assert.Equal(t, decoded, command)
+
+	_, err = DecodeCommand("not base64!")
+	assert.ErrorContains(t, err, "decode command")
+	_, err = DecodeCommand(base64.StdEncoding.EncodeToString([]byte("  ")))
+	assert.ErrorContains(t, err, "empty")
+	_, err = DecodeCommand(base64.StdEncoding.EncodeToString([]byte("echo \x00")))
+	assert.ErrorContains(t, err, "NUL byte")
+}
@@ -21,11 +21,40 @@ var (
 	MarkerEndPrefix   = "# DevPod End "
 )
 
-func ConfigureSSHConfig(configPath, context, workspace, user string, log log.Logger) error {
-	return configureSSHConfigSameFile(configPath, context, workspace, user, "", log)
+// multiplexDirectives are user managed ssh config directives that are kept
+// within the DevPod host section when the section is rewritten
+var multiplexDirectives = []string{"controlmaster", "controlpath", "controlpersist"}
+
+// ConfigureSSHConfig adds the workspace host to the ssh config. If identitiesOnly is true, the
+// host is pinned to the DevPod key, so ssh doesn't offer all keys loaded in the ssh agent. If
+// hostAlias is not empty, the host is reachable via the alias as well, an alias configured
+// earlier is kept if hostAlias is empty. If tag is true and the local OpenSSH supports it, the
+// host is tagged with ConfigTag.
+func ConfigureSSHConfig(configPath, context, workspace, user, hostAlias string, identitiesOnly, tag bool, log log.Logger) error {
+	if strings.ContainsAny(hostAlias, " \t*?!,") {
+		return fmt.Errorf("invalid host alias %s, it must not contain whitespace or patterns", hostAlias)
+	}
+
+	identityFile := ""
+	if identitiesOnly {
+		// make sure the key exists
+		_, err := GetDevPodPrivateKeyRaw()
+		if err != nil {
+			return err
+		}
+
+		identityFile = filepath.Join(GetDevPodKeysDir(), DevPodSSHPrivateKeyFile)
+	}
+
+	if tag && !SupportsConfigTag() {
+		log.Debugf("Skip the ssh config tag, it requires OpenSSH 9.4 or newer")
+		tag = false
+	}
+
+	return configureSSHConfigSameFile(configPath, context, workspace, user, hostAlias, "", identityFile, tag, log)
 }
 
-func configureSSHConfigSameFile(configPath, context, workspace, user, command string, log log.Logger) error {
+func configureSSHConfigSameFile(configPath, context, workspace, user, hostAlias, command, identityFile string, tag bool, log log.Logger) error {
 	configLock.Lock()
 	defer configLock.Unlock()
 
@@ -38,7 +67,7 @@ func configureSSHConfigSameFile(configPath, context, workspace, user, command st
 		}
 	}
 
-	newFile, err := addHost(sshConfigPath, workspace+"."+"devpod", user, context, workspace, command)
+	newFile, err := addHost(sshConfigPath, workspace+"."+"devpod", hostAlias, user, context, workspace, command, identityFile, tag)
 	if err != nil {
 		return errors.Wrap(err, "parse ssh config")
 	}
@@ -52,11 +81,39 @@ type DevPodSSHEntry struct {
 	Workspace string
 }
 
-func addHost(path, host, user, context, workspace, command string) (string, error) {
-	newConfig, err := removeFromConfig(path, host)
+func addHost(path, host, hostAlias, user, context, workspace, command, identityFile string, tag bool) (string, error) {
+	// keep multiplexing directives the user has configured for this host
+	preservedLines := []string{}
+	previousAlias := ""
+	newConfig, err := transformHostSection(path, host, func(line string) string {
+		if isMultiplexDirective(line) {
+			preservedLines = append(preservedLines, "  "+strings.TrimSpace(line))
+		} else if fields := strings.Fields(line); len(fields) > 1 && strings.ToLower(fields[0]) == "host" {
+			for _, field := range fields[1:] {
+				if field != host {
+					previousAlias = field
+				}
+			}
+		}
+
+		return ""
+	})
 	if err != nil {
 		return "", err
 	}
+	if hostAlias == "" {
+		hostAlias = previousAlias
+	}
+
+	// make sure we don't shadow another host
+	hostNames := host
+	if hostAlias != "" && hostAlias != host {
+		if hasHost(newConfig, hostAlias) {
+			return "", fmt.Errorf("host alias %s is already used by another host in %s", hostAlias, path)
+		}
+
+		hostNames = hostAlias + " " + host
+	}
 	newLines := []string{newConfig}
 
 	// get path to executable
@@ -69,7 +126,10 @@ func addHost(path, host, user, context, workspace, command string) (string, erro
 	startMarker := MarkerStartPrefix + host
 	endMarker := MarkerEndPrefix + host
 	newLines = append(newLines, startMarker)
-	newLines = append(newLines, "Host "+host)
+	newLines = append(newLines, "Host "+hostNames)
+	if tag {
+		newLines = append(newLines, "  Tag "+ConfigTag)
+	}
 	newLines = append(newLines, "  ForwardAgent yes")
 	newLines = append(newLines, "  LogLevel error")
 	newLines = append(newLines, "  StrictHostKeyChecking no")
@@ -77,20 +137,77 @@ func addHost(path, host, user, context, workspace, command string) (string, erro
 	if command != "" {
 		newLines = append(newLines, fmt.Sprintf("  ProxyCommand %s", command))
 	} else {
-		newLines = append(newLines, fmt.Sprintf("  ProxyCommand %s ssh --stdio --context %s --user %s %s", execPath, context, user, workspace))
+		newLines = append(newLines, "  ProxyCommand "+ProxyCommand(execPath, context, user, workspace))
 	}
 	newLines = append(newLines, "  User "+user)
+	if identityFile != "" {
+		newLines = append(newLines, "  IdentitiesOnly yes")
+		newLines = append(newLines, fmt.Sprintf("  IdentityFile \"%s\"", identityFile))
+	}
+	newLines = append(newLines, preservedLines...)
 	newLines = append(newLines, endMarker)
 	return strings.Join(newLines, "\n"), nil
 }
 
+// hasHost returns true if the config contains a Host entry that matches the given name exactly
+func hasHost(config, name string) bool {
+	for _, line := range strings.Split(config, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.ToLower(fields[0]) != "host" {
+			continue
+		}
+
+		for _, field := range fields[1:] {
+			if strings.Trim(field, "\"") == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ProxyCommand returns the command an ssh client should use as ProxyCommand to connect to the workspace
+func ProxyCommand(execPath, context, user, workspace string) string {
+	return fmt.Sprintf("%s ssh --stdio --context %s --user %s %s", execPath, context, user, workspace)
+}
+
+func isMultiplexDirective(line string) bool {
+	fields := strings.Fields(strings.ToLower(line))
+	if len(fields) == 0 {
+		return false
+	}
+
+	directive := strings.Split(fields[0], "=")[0]
+	for _, multiplexDirective := range multiplexDirectives {
+		if directive == multiplexDirective {
+			return true
+		}
+	}
+
+	return false
+}
+
 func GetUser(workspace string) (string, error) {
+	user, err := GetConfiguredUser(workspace)
+	if err != nil {
+		return "", err
+	} else if user == "" {
+		return "root", nil
+	}
+
+	return user, nil
+}
+
+// GetConfiguredUser returns the user of the workspace from the ssh config or an empty
+// string if no user is configured
+func GetConfiguredUser(workspace string) (string, error) {
 	sshConfigPath, err := getSSHConfig()
 	if err != nil {
 		return "", err
 	}
 
-	user := "root"
+	user := ""
 	_, err = transformHostSection(sshConfigPath, workspace+"."+"devpod", func(line string) string {
 		splitted := strings.Split(strings.ToLower(strings.TrimSpace(line)), " ")
 		if len(splitted) == 2 && splitted[0] == "user" {
@@ -0,0 +1,110 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestAddHostControlMaster(t *testing.T) {
+	existingConfig := strings.Join([]string{
+		"Host *",
+		"  ControlMaster auto",
+		"  ControlPath ~/.ssh/cm-%r@%h:%p",
+		"  ControlPersist 10m",
+		"",
+		MarkerStartPrefix + "test.devpod",
+		"Host test.devpod",
+		"  ProxyCommand devpod ssh --stdio test",
+		"  User old",
+		"  ControlPath ~/.ssh/devpod-%h",
+		"  ControlPersist=yes",
+		MarkerEndPrefix + "test.devpod",
+	}, "\n")
+
+	configPath := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(configPath, []byte(existingConfig), 0600)
+	assert.NilError(t, err)
+
+	newConfig, err := addHost(configPath, "test.devpod", "", "vscode", "default", "test", "", "", false)
+	assert.NilError(t, err)
+
+	// global multiplexing directives are left untouched
+	assert.Assert(t, strings.HasPrefix(newConfig, "Host *\n  ControlMaster auto\n  ControlPath ~/.ssh/cm-%r@%h:%p\n  ControlPersist 10m\n"))
+
+	// host specific multiplexing directives are kept within the new section
+	assert.Equal(t, strings.Count(newConfig, "ControlPath ~/.ssh/devpod-%h"), 1)
+	assert.Equal(t, strings.Count(newConfig, "ControlPersist=yes"), 1)
+	assert.Assert(t, strings.Contains(newConfig, "  User vscode\n  ControlPath ~/.ssh/devpod-%h\n  ControlPersist=yes\n"+MarkerEndPrefix+"test.devpod"))
+	assert.Assert(t, !strings.Contains(newConfig, "User old"))
+	assert.Equal(t, strings.Count(newConfig, "ProxyCommand"), 1)
+
+	// rewriting the section again is stable
+	err = os.WriteFile(configPath, []byte(newConfig), 0600)
+	assert.NilError(t, err)
+	rewrittenConfig, err := addHost(configPath, "test.devpod", "", "vscode", "default", "test", "", "", false)
+	assert.NilError(t, err)
+	assert.Equal(t, rewrittenConfig, newConfig)
+}
+
+func TestAddHostIdentityFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	newConfig, err := addHost(configPath, "test.devpod", "", "vscode", "default", "test", "", "/home/user/.devpod/keys/id_devpod_rsa", false)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(newConfig, "  User vscode\n  IdentitiesOnly yes\n  IdentityFile \"/home/user/.devpod/keys/id_devpod_rsa\"\n"+MarkerEndPrefix+"test.devpod"), newConfig)
+
+	// opting out doesn't pin the identity
+	newConfig, err = addHost(configPath, "test.devpod", "", "vscode", "default", "test", "", "", false)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(newConfig, "IdentitiesOnly"))
+	assert.Assert(t, !strings.Contains(newConfig, "IdentityFile"))
+}
+
+func TestAddHostTag(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	newConfig, err := addHost(configPath, "test.devpod", "", "vscode", "default", "test", "", "", true)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(newConfig, "Host test.devpod\n  Tag devpod\n"), newConfig)
+
+	newConfig, err = addHost(configPath, "test.devpod", "", "vscode", "default", "test", "", "", false)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(newConfig, "Tag"))
+}
+
+func TestAddHostAlias(t *testing.T) {
+	existingConfig := strings.Join([]string{
+		"Host other",
+		"  HostName example.com",
+	}, "\n")
+
+	configPath := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(configPath, []byte(existingConfig), 0600)
+	assert.NilError(t, err)
+
+	newConfig, err := addHost(configPath, "test.devpod", "test", "vscode", "default", "test", "", "", false)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(newConfig, "\nHost test test.devpod\n"), newConfig)
+	assert.Assert(t, strings.HasPrefix(newConfig, existingConfig))
+
+	// the alias is kept when the section is rewritten without one
+	err = os.WriteFile(configPath, []byte(newConfig), 0600)
+	assert.NilError(t, err)
+	rewrittenConfig, err := addHost(configPath, "test.devpod", "", "vscode", "default", "test", "", "", false)
+	assert.NilError(t, err)
+	assert.Equal(t, rewrittenConfig, newConfig)
+
+	// existing hosts are not shadowed
+	_, err = addHost(configPath, "test.devpod", "other", "vscode", "default", "test", "", "", false)
+	assert.ErrorContains(t, err, "host alias other is already used")
+}
+
+func TestIsMultiplexDirective(t *testing.T) {
+	assert.Assert(t, isMultiplexDirective("  ControlMaster auto"))
+	assert.Assert(t, isMultiplexDirective("controlpath=/tmp/%h"))
+	assert.Assert(t, isMultiplexDirective("ControlPersist 5m"))
+	assert.Assert(t, !isMultiplexDirective("  ProxyCommand devpod ssh"))
+	assert.Assert(t, !isMultiplexDirective(""))
+}
@@ -0,0 +1,150 @@
+package ssh
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loft-sh/devpod/pkg/command"
+	"github.com/loft-sh/devpod/pkg/config"
+	"github.com/pkg/errors"
+)
+
+var daemonNameRegEx = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,47}$`)
+
+// TunnelDaemon is a named connection to a workspace that runs in the background. Sessions attach
+// to it via its unix socket, which serves the ssh server of the workspace.
+type TunnelDaemon struct {
+	Name      string    `json:"name"`
+	Workspace string    `json:"workspace"`
+	User      string    `json:"user,omitempty"`
+	PID       int       `json:"pid"`
+	Socket    string    `json:"socket"`
+	Started   time.Time `json:"started"`
+}
+
+// DaemonsDir returns the folder of the daemon sockets, pid and log files and creates it if
+// necessary
+func DaemonsDir() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(configDir, "daemons")
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", errors.Wrap(err, "create daemons dir")
+	}
+
+	return dir, nil
+}
+
+// ValidateDaemonName checks that the name can be used for the files of a daemon
+func ValidateDaemonName(name string) error {
+	if !daemonNameRegEx.MatchString(name) {
+		return fmt.Errorf("invalid daemon name %s, it can only include letters, numbers, dashes and underscores", name)
+	}
+
+	return nil
+}
+
+// DaemonSocket returns the path of the socket of the daemon
+func DaemonSocket(dir, name string) string {
+	return filepath.Join(dir, name+".sock")
+}
+
+// DaemonLogFile returns the path of the log file of the daemon
+func DaemonLogFile(dir, name string) string {
+	return filepath.Join(dir, name+".log")
+}
+
+// SaveDaemon writes the pid file of the daemon
+func SaveDaemon(dir string, daemon *TunnelDaemon) error {
+	out, err := json.Marshal(daemon)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, daemon.Name+".pid"), out, 0600)
+}
+
+// LoadDaemon reads the pid file of the daemon and fails if the daemon isn't running
+func LoadDaemon(dir, name string) (*TunnelDaemon, error) {
+	err := ValidateDaemonName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := os.ReadFile(filepath.Join(dir, name+".pid"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("daemon %s isn't running, start it with 'devpod ssh --daemon --name %s <workspace>'", name, name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	daemon := &TunnelDaemon{}
+	err = json.Unmarshal(out, daemon)
+	if err != nil {
+		return nil, fmt.Errorf("parse pid file of daemon %s: %w", name, err)
+	}
+
+	running, err := command.IsRunning(strconv.Itoa(daemon.PID))
+	if err != nil || !running {
+		RemoveDaemon(dir, name)
+		return nil, fmt.Errorf("daemon %s isn't running anymore, check its log at %s", name, DaemonLogFile(dir, name))
+	}
+
+	return daemon, nil
+}
+
+// ListDaemons returns the running daemons sorted by name and removes the files of daemons that
+// exited without cleaning up
+func ListDaemons(dir string) ([]*TunnelDaemon, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	daemons := []*TunnelDaemon{}
+	for _, entry := range entries {
+		name, found := strings.CutSuffix(entry.Name(), ".pid")
+		if !found {
+			continue
+		}
+
+		daemon, err := LoadDaemon(dir, name)
+		if err != nil {
+			continue
+		}
+
+		daemons = append(daemons, daemon)
+	}
+	sort.Slice(daemons, func(i, j int) bool {
+		return daemons[i].Name < daemons[j].Name
+	})
+
+	return daemons, nil
+}
+
+// RemoveDaemon removes the pid file and socket of the daemon, the log is kept for debugging
+func RemoveDaemon(dir, name string) {
+	_ = os.Remove(filepath.Join(dir, name+".pid"))
+	_ = os.Remove(DaemonSocket(dir, name))
+}
+
+// StopDaemon terminates the daemon, which removes its files on exit
+func StopDaemon(daemon *TunnelDaemon) error {
+	process, err := os.FindProcess(daemon.PID)
+	if err != nil {
+		return err
+	}
+
+	return stopProcess(process)
+}
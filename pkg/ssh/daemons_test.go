@@ -0,0 +1,46 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestDaemons(t *testing.T) {
+	dir := t.TempDir()
+	running := &TunnelDaemon{Name: "b-tunnel", Workspace: "my-workspace", PID: os.Getpid(), Socket: DaemonSocket(dir, "b-tunnel"), Started: time.Now().UTC().Truncate(time.Second)}
+	assert.NilError(t, SaveDaemon(dir, running))
+	assert.NilError(t, SaveDaemon(dir, &TunnelDaemon{Name: "a-tunnel", Workspace: "my-workspace", PID: os.Getpid()}))
+
+	daemon, err := LoadDaemon(dir, "b-tunnel")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, daemon, running)
+
+	daemons, err := ListDaemons(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(daemons), 2)
+	assert.Equal(t, daemons[0].Name, "a-tunnel")
+	assert.Equal(t, daemons[1].Name, "b-tunnel")
+
+	_, err = LoadDaemon(dir, "missing")
+	assert.ErrorContains(t, err, "daemon missing isn't running")
+}
+
+func TestDaemonsStale(t *testing.T) {
+	dir := t.TempDir()
+	assert.NilError(t, SaveDaemon(dir, &TunnelDaemon{Name: "stale", PID: 1 << 30}))
+
+	_, err := LoadDaemon(dir, "stale")
+	assert.ErrorContains(t, err, "daemon stale isn't running anymore")
+	_, err = os.Stat(filepath.Join(dir, "stale.pid"))
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestValidateDaemonName(t *testing.T) {
+	assert.NilError(t, ValidateDaemonName("my_tunnel-1"))
+	assert.ErrorContains(t, ValidateDaemonName("../tunnel"), "invalid daemon name")
+	assert.ErrorContains(t, ValidateDaemonName(""), "invalid daemon name")
+}
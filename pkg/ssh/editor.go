@@ -0,0 +1,29 @@
+package ssh
+
+import (
+	"path"
+	"strings"
+
+	"github.com/alessio/shellescape"
+)
+
+// editorScript opens the file with the editor from the environment of a login shell, so
+// variables from the profile of the workspace user are picked up
+const editorScript = `exec ${VISUAL:-${EDITOR:-vi}} "$1"`
+
+// EditorCommand returns a command that opens the file in the editor of the workspace, which is
+// $VISUAL or $EDITOR of the workspace user and vi otherwise. A relative file is resolved against
+// workdir or the home folder of the user if workdir is empty.
+func EditorCommand(file, workdir string) string {
+	if !path.IsAbs(file) {
+		if workdir == "" {
+			workdir = "."
+		}
+		file = path.Join(workdir, file)
+		if !path.IsAbs(file) && !strings.HasPrefix(file, ".") {
+			file = "./" + file
+		}
+	}
+
+	return shellescape.QuoteCommand([]string{"sh", "-lc", editorScript, "devpod-edit", file})
+}
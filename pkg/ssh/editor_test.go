@@ -0,0 +1,23 @@
+package ssh
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestEditorCommand(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, EditorCommand("/etc/hosts", dir), `sh -lc 'exec ${VISUAL:-${EDITOR:-vi}} "$1"' devpod-edit /etc/hosts`)
+	assert.Equal(t, EditorCommand("-file", ""), `sh -lc 'exec ${VISUAL:-${EDITOR:-vi}} "$1"' devpod-edit ./-file`)
+
+	// the editor is resolved from the environment and gets the file relative to the workdir
+	command := exec.Command("sh", "-c", EditorCommand("my file.txt", dir))
+	command.Env = []string{"HOME=" + dir, "EDITOR=echo"}
+	out, err := command.Output()
+	assert.NilError(t, err)
+	assert.Equal(t, strings.TrimSpace(string(out)), filepath.Join(dir, "my file.txt"))
+}
@@ -0,0 +1,101 @@
+package ssh
+
+import (
+	"sort"
+	"strings"
+)
+
+// hostEnv describes the local machine or session and would break the session in the workspace
+var hostEnv = map[string]bool{
+	"HOME":            true,
+	"PATH":            true,
+	"USER":            true,
+	"LOGNAME":         true,
+	"SHELL":           true,
+	"PWD":             true,
+	"OLDPWD":          true,
+	"SHLVL":           true,
+	"_":               true,
+	"TERM":            true,
+	"HOSTNAME":        true,
+	"TMPDIR":          true,
+	"DISPLAY":         true,
+	"XDG_RUNTIME_DIR": true,
+	"SSH_AUTH_SOCK":   true,
+	"SSH_CLIENT":      true,
+	"SSH_CONNECTION":  true,
+	"SSH_TTY":         true,
+}
+
+// unsafeEnv changes what programs or shells in the workspace execute
+var unsafeEnv = map[string]bool{
+	"LD_PRELOAD":      true,
+	"LD_LIBRARY_PATH": true,
+	"LD_AUDIT":        true,
+	"BASH_ENV":        true,
+	"ENV":             true,
+	"IFS":             true,
+	"PS4":             true,
+	"PROMPT_COMMAND":  true,
+}
+
+// unsafeEnvPrefixes are prefixes of variables that are unsafe like unsafeEnv
+var unsafeEnvPrefixes = []string{"DYLD_", "BASH_FUNC_"}
+
+// secretEnvPrefixes are prefixes of variables holding cloud credentials or their configuration
+var secretEnvPrefixes = []string{"AWS_", "AZURE_", "ARM_", "GOOGLE_", "GCP_", "CLOUDSDK_", "DIGITALOCEAN_", "VAULT_"}
+
+// secretEnvParts are parts of names of variables that usually hold secrets, e.g. GITHUB_TOKEN,
+// NPM_AUTH_TOKEN or DB_PASSWORD
+var secretEnvParts = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL", "API_KEY", "APIKEY", "ACCESS_KEY", "PRIVATE_KEY"}
+
+// PassthroughEnv returns the variables of environ, as returned by os.Environ, to forward into the
+// session. Variables describing the local machine or that look like secrets are never forwarded,
+// variables that change what programs in the workspace execute only if unsafe is true. It also returns the sorted
+// names of the forwarded variables.
+func PassthroughEnv(environ []string, unsafe bool) (map[string]string, []string) {
+	env := map[string]string{}
+	names := []string{}
+	for _, entry := range environ {
+		name, value, found := strings.Cut(entry, "=")
+		if !found || name == "" || hostEnv[name] || isSecretEnv(name) || (!unsafe && isUnsafeEnv(name)) {
+			continue
+		}
+
+		env[name] = value
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return env, names
+}
+
+func isUnsafeEnv(name string) bool {
+	if unsafeEnv[name] {
+		return true
+	}
+
+	for _, prefix := range unsafeEnvPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSecretEnv(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, prefix := range secretEnvPrefixes {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	for _, part := range secretEnvParts {
+		if strings.Contains(upper, part) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,33 @@
+package ssh
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestPassthroughEnv(t *testing.T) {
+	environ := []string{"HOME=/home/me", "GOFLAGS=-mod=vendor", "LD_PRELOAD=/tmp/hook.so", "BASH_FUNC_x%%=() { :; }", "EMPTY=", "EDITOR=vim"}
+
+	env, names := PassthroughEnv(environ, false)
+	assert.DeepEqual(t, env, map[string]string{"GOFLAGS": "-mod=vendor", "EMPTY": "", "EDITOR": "vim"})
+	assert.DeepEqual(t, names, []string{"EDITOR", "EMPTY", "GOFLAGS"})
+
+	env, names = PassthroughEnv(environ, true)
+	assert.Equal(t, env["LD_PRELOAD"], "/tmp/hook.so")
+	assert.DeepEqual(t, names, []string{"BASH_FUNC_x%%", "EDITOR", "EMPTY", "GOFLAGS", "LD_PRELOAD"})
+}
+
+func TestPassthroughEnvSkipsSecrets(t *testing.T) {
+	environ := []string{
+		"AWS_ACCESS_KEY_ID=AKIA", "AWS_PROFILE=dev", "AZURE_CLIENT_SECRET=s", "GOOGLE_APPLICATION_CREDENTIALS=/key.json",
+		"GITHUB_TOKEN=ghp", "GH_TOKEN=ghp", "NPM_AUTH_TOKEN=npm", "DB_PASSWORD=pw", "OPENAI_API_KEY=sk", "VAULT_ADDR=https://vault",
+		"SSH_AUTH_SOCK=/tmp/agent.sock", "LANG=C.UTF-8", "GIT_AUTHOR_NAME=me",
+	}
+
+	for _, unsafe := range []bool{false, true} {
+		env, names := PassthroughEnv(environ, unsafe)
+		assert.DeepEqual(t, env, map[string]string{"LANG": "C.UTF-8", "GIT_AUTHOR_NAME": "me"})
+		assert.DeepEqual(t, names, []string{"GIT_AUTHOR_NAME", "LANG"})
+	}
+}
@@ -0,0 +1,35 @@
+package ssh
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SessionExitError returns the exit status of the remote shell or command within err unwrapped,
+// so devpod exits with it. If ignoreExit is set, the exit status is ignored and nil returned.
+// Other errors are returned as they are.
+func SessionExitError(err error, ignoreExit bool) error {
+	var exitErr *ssh.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	} else if ignoreExit {
+		return nil
+	}
+
+	return exitErr
+}
+
+// ConnectionError returns err if the connection itself failed, e.g. during the handshake or in
+// the transport. It returns nil if the connection worked and the session only ended with a
+// non-zero exit status of the remote shell or command, because of --idle-disconnect or because
+// DevPod was terminated.
+func ConnectionError(err error) error {
+	var exitErr *ssh.ExitError
+	var signalErr *SignalError
+	if errors.As(err, &exitErr) || errors.As(err, &signalErr) || errors.Is(err, ErrIdleDisconnect) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,38 @@
+package ssh
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"gotest.tools/assert"
+)
+
+func TestSessionExitError(t *testing.T) {
+	exitErr := &ssh.ExitError{}
+	wrapped := errors.Wrap(fmt.Errorf("run in container: %w", exitErr), "tunnel to container")
+
+	// the exit status of the remote shell is propagated unwrapped
+	assert.Equal(t, SessionExitError(wrapped, false), error(exitErr))
+	assert.Equal(t, SessionExitError(exitErr, false), error(exitErr))
+	assert.NilError(t, SessionExitError(nil, false))
+
+	// --ignore-exit ignores the exit status
+	assert.NilError(t, SessionExitError(wrapped, true))
+
+	// errors of the connection are kept
+	connectErr := fmt.Errorf("connect to server: %w", fmt.Errorf("connection refused"))
+	assert.Equal(t, SessionExitError(connectErr, false), connectErr)
+	assert.Equal(t, SessionExitError(connectErr, true), connectErr)
+}
+
+func TestConnectionError(t *testing.T) {
+	assert.NilError(t, ConnectionError(nil))
+	assert.NilError(t, ConnectionError(errors.Wrap(&ssh.ExitError{}, "run in container")))
+	assert.NilError(t, ConnectionError(ErrIdleDisconnect))
+	assert.NilError(t, ConnectionError(&SignalError{}))
+
+	handshakeErr := fmt.Errorf("ssh: handshake failed: EOF")
+	assert.Equal(t, ConnectionError(handshakeErr), handshakeErr)
+}
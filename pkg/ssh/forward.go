@@ -2,6 +2,7 @@ package ssh
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net"
 	"sync"
@@ -12,6 +13,12 @@ import (
 )
 
 func PortForward(ctx context.Context, client *ssh.Client, localNetwork, localAddr, remoteNetwork, remoteAddr string, exitAfterTimeout time.Duration, log log.Logger) error {
+	return PortForwardWithLimiter(ctx, client, nil, localNetwork, localAddr, remoteNetwork, remoteAddr, exitAfterTimeout, log)
+}
+
+// PortForwardWithLimiter forwards like PortForward, but waits for a free channel of the limiter
+// before a new connection is forwarded
+func PortForwardWithLimiter(ctx context.Context, client *ssh.Client, limiter *ChannelLimiter, localNetwork, localAddr, remoteNetwork, remoteAddr string, exitAfterTimeout time.Duration, log log.Logger) error {
 	listener, err := net.Listen(localNetwork, localAddr)
 	if err != nil {
 		return err
@@ -46,27 +53,165 @@ func PortForward(ctx context.Context, client *ssh.Client, localNetwork, localAdd
 		go func() {
 			defer counter.Dec()
 
+			err := limiter.Acquire(ctx)
+			if err != nil {
+				log.Warnf("Refused connection to %s: %v", localAddr, err)
+				_ = local.Close()
+				return
+			}
+			defer limiter.Release()
+
 			forward(local, client, remoteNetwork, remoteAddr, log)
 		}()
 	}
 }
 
+// ReversePortForward listens on the remote address in the workspace and forwards the connections
+// to the local address, like ssh -R
+func ReversePortForward(ctx context.Context, client *ssh.Client, limiter *ChannelLimiter, remoteAddr, localNetwork, localAddr string, log log.Logger) error {
+	listener, err := client.Listen("tcp", remoteAddr)
+	if err != nil {
+		return fmt.Errorf("listen on %s in the workspace: %w", remoteAddr, ChannelError(err))
+	}
+
+	return serve(ctx, listener, limiter, remoteAddr, func(remote net.Conn) {
+		local, err := net.Dial(localNetwork, localAddr)
+		if err != nil {
+			log.Debugf("error dialing local %s: %v", localAddr, err)
+			_ = remote.Close()
+			return
+		}
+
+		pipe(local, remote, log)
+	}, log)
+}
+
+// DynamicPortForward starts a SOCKS5 proxy on the local address that connects to the requested
+// addresses from the workspace, like ssh -D
+func DynamicPortForward(ctx context.Context, client *ssh.Client, limiter *ChannelLimiter, localNetwork, localAddr string, log log.Logger) error {
+	listener, err := net.Listen(localNetwork, localAddr)
+	if err != nil {
+		return err
+	}
+
+	return serve(ctx, listener, limiter, localAddr, func(local net.Conn) {
+		remoteAddr, err := socksHandshake(local)
+		if err != nil {
+			log.Debugf("error in socks handshake: %v", err)
+			_ = local.Close()
+			return
+		}
+
+		sshConn, err := client.Dial("tcp", remoteAddr)
+		if err != nil {
+			log.Debugf("error dialing remote %s: %v", remoteAddr, ChannelError(err))
+			_ = socksReply(local, socksReplyFailure)
+			_ = local.Close()
+			return
+		}
+		err = socksReply(local, socksReplySuccess)
+		if err != nil {
+			_ = sshConn.Close()
+			_ = local.Close()
+			return
+		}
+
+		pipe(local, sshConn, log)
+	}, log)
+}
+
+// serve accepts connections from the listener until the context is done and handles each of them
+// once the limiter has a free channel
+func serve(ctx context.Context, listener net.Listener, limiter *ChannelLimiter, addr string, handle func(conn net.Conn), log log.Logger) error {
+	defer listener.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			_ = listener.Close()
+		}
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		go func() {
+			err := limiter.Acquire(ctx)
+			if err != nil {
+				log.Warnf("Refused connection to %s: %v", addr, err)
+				_ = conn.Close()
+				return
+			}
+			defer limiter.Release()
+
+			handle(conn)
+		}()
+	}
+}
+
+// StdioForward dials the remote address through the ssh client and bridges it with the given stdin and stdout
+func StdioForward(ctx context.Context, client *ssh.Client, remoteNetwork, remoteAddr string, stdin io.Reader, stdout io.Writer) error {
+	sshConn, err := client.Dial(remoteNetwork, remoteAddr)
+	if err != nil {
+		return fmt.Errorf("dial %s: %w", remoteAddr, ChannelError(err))
+	}
+	defer sshConn.Close()
+
+	errChan := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(sshConn, stdin)
+		errChan <- err
+	}()
+	go func() {
+		_, err := io.Copy(stdout, sshConn)
+		errChan <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-errChan:
+		return err
+	}
+}
+
 func forward(localConn net.Conn, client *ssh.Client, remoteNetwork, remoteAddr string, log log.Logger) {
 	// Setup sshConn (type net.Conn)
 	sshConn, err := client.Dial(remoteNetwork, remoteAddr)
 	if err != nil {
+		if isResourceShortage(err) {
+			log.Warnf("Error forwarding connection to %s: %v", remoteAddr, ChannelError(err))
+			return
+		}
+
 		log.Debugf("error dialing remote: %v", err)
 		return
 	}
 	defer sshConn.Close()
 
+	pipe(localConn, sshConn, log)
+}
+
+// pipe copies between the local and the remote connection until both directions are closed
+func pipe(localConn, sshConn net.Conn, log log.Logger) {
 	// Copy localConn.Reader to sshConn.Writer
 	waitGroup := sync.WaitGroup{}
 	go func() {
 		defer waitGroup.Done()
 		defer sshConn.Close()
 
-		_, err = io.Copy(sshConn, localConn)
+		_, err := io.Copy(sshConn, localConn)
 		if err != nil {
 			log.Debugf("error copying to remote: %v", err)
 		}
@@ -78,7 +223,7 @@ func forward(localConn net.Conn, client *ssh.Client, remoteNetwork, remoteAddr s
 		defer waitGroup.Done()
 		defer localConn.Close()
 
-		_, err = io.Copy(localConn, sshConn)
+		_, err := io.Copy(localConn, sshConn)
 		if err != nil {
 			log.Debugf("error copying to local: %v", err)
 		}
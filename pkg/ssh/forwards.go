@@ -0,0 +1,177 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/loft-sh/devpod/pkg/port"
+)
+
+const (
+	// ForwardTypeLocal forwards a local address to an address in the workspace, like ssh -L
+	ForwardTypeLocal = "local"
+	// ForwardTypeRemote forwards an address in the workspace to a local address, like ssh -R
+	ForwardTypeRemote = "remote"
+	// ForwardTypeDynamic starts a local SOCKS5 proxy that connects from the workspace, like ssh -D
+	ForwardTypeDynamic = "dynamic"
+)
+
+var forwardTypes = []string{ForwardTypeLocal, ForwardTypeRemote, ForwardTypeDynamic}
+
+// ForwardSpec is a single entry of a forwards file
+type ForwardSpec struct {
+	// Type is one of local, remote or dynamic
+	Type string `json:"type"`
+
+	// Bind is the address to listen on, a port, host:port or the path of a unix socket. It is
+	// local for local and dynamic forwards and in the workspace for remote forwards
+	Bind string `json:"bind"`
+
+	// Target is the address to connect to, it is not used by dynamic forwards
+	Target string `json:"target,omitempty"`
+}
+
+// Forward is a parsed forward that can be started
+type Forward struct {
+	Type   string
+	Bind   port.Address
+	Target port.Address
+}
+
+// String returns a short description of the forward for logs
+func (f Forward) String() string {
+	if f.Type == ForwardTypeDynamic {
+		return fmt.Sprintf("%s %s/%s", f.Type, f.Bind.Protocol, f.Bind.Address)
+	}
+
+	return fmt.Sprintf("%s %s/%s to %s/%s", f.Type, f.Bind.Protocol, f.Bind.Address, f.Target.Protocol, f.Target.Address)
+}
+
+// LoadForwardsFile reads a json file with an array of forward specs. Errors of an entry include
+// its index and line in the file.
+func LoadForwardsFile(path string) ([]Forward, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read forwards file: %w", err)
+	}
+
+	forwards, err := ParseForwards(data)
+	if err != nil {
+		return nil, fmt.Errorf("forwards file %s: %w", path, err)
+	}
+
+	return forwards, nil
+}
+
+// ParseForwards parses a json array of forward specs
+func ParseForwards(data []byte) ([]Forward, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	} else if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("expected an array of forwards")
+	}
+
+	forwards := []Forward{}
+	for index := 0; decoder.More(); index++ {
+		line := lineAt(data, decoder.InputOffset())
+		raw := json.RawMessage{}
+		err = decoder.Decode(&raw)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d (line %d): %w", index, line, err)
+		}
+
+		forward, err := parseForwardSpec(raw)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d (line %d): %w", index, line, err)
+		}
+
+		forwards = append(forwards, forward)
+	}
+
+	return forwards, nil
+}
+
+func parseForwardSpec(raw json.RawMessage) (Forward, error) {
+	spec := ForwardSpec{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&spec)
+	if err != nil {
+		return Forward{}, err
+	}
+
+	switch spec.Type {
+	case ForwardTypeLocal, ForwardTypeRemote:
+		if spec.Target == "" {
+			return Forward{}, fmt.Errorf("target is required for %s forwards", spec.Type)
+		}
+	case ForwardTypeDynamic:
+		if spec.Target != "" {
+			return Forward{}, fmt.Errorf("target cannot be used with dynamic forwards")
+		}
+	case "":
+		return Forward{}, fmt.Errorf("type is required, expected one of: %s", strings.Join(forwardTypes, ", "))
+	default:
+		return Forward{}, fmt.Errorf("unsupported type %s, expected one of: %s", spec.Type, strings.Join(forwardTypes, ", "))
+	}
+
+	forward := Forward{Type: spec.Type}
+	forward.Bind, err = ParseForwardAddress(spec.Bind)
+	if err != nil {
+		return Forward{}, fmt.Errorf("bind: %w", err)
+	} else if spec.Type == ForwardTypeRemote && forward.Bind.Protocol != "tcp" {
+		return Forward{}, fmt.Errorf("bind: remote forwards can only listen on a tcp port")
+	}
+
+	if spec.Target != "" {
+		forward.Target, err = ParseForwardAddress(spec.Target)
+		if err != nil {
+			return Forward{}, fmt.Errorf("target: %w", err)
+		}
+	}
+
+	return forward, nil
+}
+
+// ParseForwardAddress parses a port, host:port or the path of a unix socket. A port without a
+// host listens on or connects to localhost.
+func ParseForwardAddress(address string) (port.Address, error) {
+	if address == "" {
+		return port.Address{}, fmt.Errorf("address is required")
+	} else if strings.ContainsAny(address, `/\`) {
+		return port.Address{Protocol: "unix", Address: address}, nil
+	}
+
+	host, portNumber := "localhost", address
+	if strings.Contains(address, ":") {
+		var err error
+		host, portNumber, err = net.SplitHostPort(address)
+		if err != nil {
+			return port.Address{}, fmt.Errorf("invalid address %s, expected a port, host:port or the path of a unix socket", address)
+		}
+	}
+
+	number, err := strconv.Atoi(portNumber)
+	if err != nil || number < 0 || number > 65535 {
+		return port.Address{}, fmt.Errorf("invalid port in address %s", address)
+	}
+
+	return port.Address{Protocol: "tcp", Address: net.JoinHostPort(host, portNumber)}, nil
+}
+
+// lineAt returns the line of the first non-whitespace character after offset, the decoder
+// offset points behind the previous token
+func lineAt(data []byte, offset int64) int {
+	for offset < int64(len(data)) && strings.ContainsRune(" \t\r\n,", rune(data[offset])) {
+		offset++
+	}
+
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
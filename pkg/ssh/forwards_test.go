@@ -0,0 +1,45 @@
+package ssh
+
+import (
+	"testing"
+
+	"github.com/loft-sh/devpod/pkg/port"
+	"gotest.tools/assert"
+)
+
+func TestParseForwards(t *testing.T) {
+	forwards, err := ParseForwards([]byte(`[
+  {"type": "local", "bind": "8080", "target": "localhost:80"},
+  {"type": "remote", "bind": "0.0.0.0:9000", "target": "/tmp/app.sock"},
+  {"type": "dynamic", "bind": "1080"}
+]`))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, forwards, []Forward{
+		{Type: ForwardTypeLocal, Bind: port.Address{Protocol: "tcp", Address: "localhost:8080"}, Target: port.Address{Protocol: "tcp", Address: "localhost:80"}},
+		{Type: ForwardTypeRemote, Bind: port.Address{Protocol: "tcp", Address: "0.0.0.0:9000"}, Target: port.Address{Protocol: "unix", Address: "/tmp/app.sock"}},
+		{Type: ForwardTypeDynamic, Bind: port.Address{Protocol: "tcp", Address: "localhost:1080"}},
+	})
+}
+
+func TestParseForwardsErrors(t *testing.T) {
+	_, err := ParseForwards([]byte(`{"type": "local"}`))
+	assert.ErrorContains(t, err, "expected an array of forwards")
+
+	_, err = ParseForwards([]byte(`[
+  {"type": "local", "bind": "8080", "target": "80"},
+  {"type": "tunnel", "bind": "8081", "target": "81"}
+]`))
+	assert.ErrorContains(t, err, "entry 1 (line 3): unsupported type tunnel")
+
+	_, err = ParseForwards([]byte(`[{"type": "local", "bind": "8080"}]`))
+	assert.ErrorContains(t, err, "entry 0 (line 1): target is required for local forwards")
+
+	_, err = ParseForwards([]byte(`[{"type": "dynamic", "bind": "1080", "port": 1}]`))
+	assert.ErrorContains(t, err, `unknown field "port"`)
+
+	_, err = ParseForwards([]byte(`[{"type": "remote", "bind": "/tmp/remote.sock", "target": "80"}]`))
+	assert.ErrorContains(t, err, "remote forwards can only listen on a tcp port")
+
+	_, err = ParseForwards([]byte(`[{"type": "local", "bind": "localhost:http", "target": "80"}]`))
+	assert.ErrorContains(t, err, "bind: invalid port in address localhost:http")
+}
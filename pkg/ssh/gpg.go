@@ -0,0 +1,85 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/loft-sh/devpod/pkg/ssh/server"
+	"golang.org/x/crypto/ssh"
+)
+
+// LocalGPGAgentSocket returns the extra socket of the local gpg agent, which is meant for
+// forwarding and restricts what remote clients can do with the agent. gpgconf knows the
+// location, which is below /run/user on most Linux distributions and in ~/.gnupg on macOS.
+func LocalGPGAgentSocket() (string, error) {
+	if runtime.GOOS == "windows" {
+		return "", fmt.Errorf("gpg agent forwarding is not supported on Windows, Gpg4win doesn't provide a unix socket")
+	}
+
+	out, err := exec.Command("gpgconf", "--list-dirs", "agent-extra-socket").Output()
+	if err != nil {
+		return "", fmt.Errorf("find gpg agent socket, make sure gpg is installed: %w", err)
+	}
+
+	socket := strings.TrimSpace(string(out))
+	if socket == "" {
+		return "", fmt.Errorf("gpg agent has no extra socket")
+	}
+
+	// make sure the agent is running, it is usually only started on demand
+	err = exec.Command("gpgconf", "--launch", "gpg-agent").Run()
+	if err != nil {
+		return "", fmt.Errorf("start gpg agent: %w", err)
+	}
+
+	return socket, nil
+}
+
+// ForwardGPGAgentToRemote forwards the gpg agent connections the ssh server opens to the local
+// socket. The session needs to request the forwarding via server.GPGAgentForwardingEnv.
+func ForwardGPGAgentToRemote(client *ssh.Client, socket string) error {
+	channels := client.HandleChannelOpen(server.GPGAgentChannelType)
+	if channels == nil {
+		return fmt.Errorf("gpg agent forwarding is already set up")
+	}
+
+	go func() {
+		for newChannel := range channels {
+			channel, reqs, err := newChannel.Accept()
+			if err != nil {
+				continue
+			}
+			go ssh.DiscardRequests(reqs)
+
+			go func() {
+				defer channel.Close()
+				conn, err := net.Dial("unix", socket)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+
+				waitGroup := sync.WaitGroup{}
+				waitGroup.Add(2)
+				go func() {
+					defer waitGroup.Done()
+					_, _ = io.Copy(conn, channel)
+					_ = conn.(*net.UnixConn).CloseWrite()
+				}()
+				go func() {
+					defer waitGroup.Done()
+					_, _ = io.Copy(channel, conn)
+					_ = channel.CloseWrite()
+				}()
+				waitGroup.Wait()
+			}()
+		}
+	}()
+
+	return nil
+}
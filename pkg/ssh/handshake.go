@@ -0,0 +1,79 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/loft-sh/devpod/pkg/stdio"
+	"golang.org/x/crypto/ssh"
+)
+
+// ClientOptions configure the handshake of a stdio ssh client
+type ClientOptions struct {
+	// Audit records the connection parameters if not nil
+	Audit *AuditLog
+
+	// BannerTimeout is how long to wait for the server to finish the handshake, a server that
+	// sends its banner slowly or not at all would otherwise block forever. Zero waits forever.
+	BannerTimeout time.Duration
+
+	// BannerCallback is called with the authentication banner of the server if not nil
+	BannerCallback ssh.BannerCallback
+}
+
+// StdioClientWithOptions creates a new ssh client like StdioClientWithUser with the given options
+func StdioClientWithOptions(reader io.Reader, writer io.WriteCloser, user string, options ClientOptions) (*ssh.Client, error) {
+	conn := stdio.NewStdioStream(reader, writer, false)
+	clientConfig, err := ConfigFromKeyBytes(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConfig.User = user
+	clientConfig.BannerCallback = options.BannerCallback
+	if options.Audit != nil {
+		clientConfig.HostKeyCallback = options.Audit.hostKeyCallback
+	}
+
+	c, chans, req, err := clientConnWithTimeout(conn, "stdio", clientConfig, options.BannerTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Audit != nil {
+		options.Audit.connected(c)
+	}
+	return ssh.NewClient(c, chans, req), nil
+}
+
+// clientConnWithTimeout runs the handshake of ssh.NewClientConn and closes the connection if it
+// doesn't finish within the timeout. The stdio connections can't use deadlines for this.
+func clientConnWithTimeout(conn net.Conn, addr string, config *ssh.ClientConfig, timeout time.Duration) (ssh.Conn, <-chan ssh.NewChannel, <-chan *ssh.Request, error) {
+	if timeout <= 0 {
+		return ssh.NewClientConn(conn, addr, config)
+	}
+
+	type result struct {
+		conn  ssh.Conn
+		chans <-chan ssh.NewChannel
+		reqs  <-chan *ssh.Request
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+		done <- result{conn: c, chans: chans, reqs: reqs, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.conn, r.chans, r.reqs, r.err
+	case <-timer.C:
+		_ = conn.Close()
+		return nil, nil, nil, fmt.Errorf("ssh handshake didn't finish within the banner timeout of %s, the server sends no or a slow banner, increase it via --banner-timeout", timeout)
+	}
+}
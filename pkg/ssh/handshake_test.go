@@ -0,0 +1,21 @@
+package ssh
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestStdioClientBannerTimeout(t *testing.T) {
+	// the server reads the client version, but never answers
+	fromServer, _ := io.Pipe()
+	toServer, clientWriter := io.Pipe()
+	go func() {
+		_, _ = io.Copy(io.Discard, toServer)
+	}()
+
+	_, err := StdioClientWithOptions(fromServer, clientWriter, "root", ClientOptions{BannerTimeout: 50 * time.Millisecond})
+	assert.ErrorContains(t, err, "banner timeout of 50ms")
+}
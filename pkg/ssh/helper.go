@@ -22,7 +22,7 @@ func NewSSHPassClient(user, addr, password string) (*ssh.Client, error) {
 		clientConfig.User = user
 	}
 
-	client, err := ssh.Dial("tcp", addr, clientConfig)
+	client, err := DialWithProxy(context.Background(), addr, "", nil, clientConfig)
 	if err != nil {
 		return nil, fmt.Errorf("dial to %v failed: %w", addr, err)
 	}
@@ -40,7 +40,7 @@ func NewSSHClient(user, addr string, keyBytes []byte) (*ssh.Client, error) {
 		sshConfig.User = user
 	}
 
-	client, err := ssh.Dial("tcp", addr, sshConfig)
+	client, err := DialWithProxy(context.Background(), addr, "", nil, sshConfig)
 	if err != nil {
 		return nil, fmt.Errorf("dial to %v failed: %w", addr, err)
 	}
@@ -91,12 +91,22 @@ func ConfigFromKeyBytes(keyBytes []byte) (*ssh.ClientConfig, error) {
 }
 
 func Run(ctx context.Context, client *ssh.Client, command string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	return RunWithEnv(ctx, client, command, nil, stdin, stdout, stderr)
+}
+
+// RunWithEnv runs the command with the given environment variables set in the session
+func RunWithEnv(ctx context.Context, client *ssh.Client, command string, env map[string]string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 	sess, err := client.NewSession()
 	if err != nil {
 		return err
 	}
 	defer sess.Close()
 
+	err = SetEnv(sess, env)
+	if err != nil {
+		return err
+	}
+
 	exit := make(chan struct{})
 	defer close(exit)
 	go func() {
@@ -118,3 +128,15 @@ func Run(ctx context.Context, client *ssh.Client, command string, stdin io.Reade
 
 	return nil
 }
+
+// SetEnv sets the environment variables in the session
+func SetEnv(sess *ssh.Session, env map[string]string) error {
+	for k, v := range env {
+		err := sess.Setenv(k, v)
+		if err != nil {
+			return fmt.Errorf("set environment variable %s: %w", k, err)
+		}
+	}
+
+	return nil
+}
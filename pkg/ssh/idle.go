@@ -0,0 +1,69 @@
+package ssh
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrIdleDisconnect is returned if a session was closed because there was no input
+var ErrIdleDisconnect = errors.New("session was closed after inactivity")
+
+// IdleReader calls onIdle once if nothing was read from the underlying reader for the given timeout
+type IdleReader struct {
+	m sync.Mutex
+
+	reader  io.Reader
+	timeout time.Duration
+	timer   *time.Timer
+	idle    bool
+}
+
+// NewIdleReader creates a new idle reader, the timeout starts immediately
+func NewIdleReader(reader io.Reader, timeout time.Duration, onIdle func()) *IdleReader {
+	i := &IdleReader{
+		reader:  reader,
+		timeout: timeout,
+	}
+	i.timer = time.AfterFunc(timeout, func() {
+		i.m.Lock()
+		if i.idle {
+			i.m.Unlock()
+			return
+		}
+		i.idle = true
+		i.m.Unlock()
+
+		onIdle()
+	})
+
+	return i
+}
+
+// Read reads from the underlying reader and resets the timeout on input
+func (i *IdleReader) Read(p []byte) (int, error) {
+	n, err := i.reader.Read(p)
+	if n > 0 {
+		i.m.Lock()
+		if !i.idle {
+			i.timer.Reset(i.timeout)
+		}
+		i.m.Unlock()
+	}
+
+	return n, err
+}
+
+// Idle returns true if the timeout was reached
+func (i *IdleReader) Idle() bool {
+	i.m.Lock()
+	defer i.m.Unlock()
+
+	return i.idle
+}
+
+// Stop stops the timeout
+func (i *IdleReader) Stop() {
+	i.timer.Stop()
+}
@@ -0,0 +1,33 @@
+package ssh
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestIdleReader(t *testing.T) {
+	idleChan := make(chan struct{})
+	reader := NewIdleReader(strings.NewReader("input"), 50*time.Millisecond, func() {
+		close(idleChan)
+	})
+	defer reader.Stop()
+
+	// input resets the timeout
+	time.Sleep(30 * time.Millisecond)
+	out, err := io.ReadAll(reader)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "input")
+	time.Sleep(30 * time.Millisecond)
+	assert.Assert(t, !reader.Idle())
+
+	select {
+	case <-idleChan:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for idle reader")
+	}
+	assert.Assert(t, reader.Idle())
+}
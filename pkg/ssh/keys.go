@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/loft-sh/devpod/pkg/provider"
 	"github.com/mitchellh/go-homedir"
@@ -236,3 +237,59 @@ func GetPublicKey(context, workspaceID string) (string, error) {
 
 	return GetPublicKeyBase(workspaceDir)
 }
+
+// RotateDevPodKey replaces the DevPod key pair with a new one, see RotateKeyBase
+func RotateDevPodKey() (string, error) {
+	return RotateKeyBase(GetDevPodKeysDir(), time.Now())
+}
+
+// RotateKeyBase moves the key pair in dir into a backup folder and generates a new key pair. It
+// returns the backup folder, which is empty if there was no key pair to back up.
+func RotateKeyBase(dir string, now time.Time) (string, error) {
+	keyLock.Lock()
+	defer keyLock.Unlock()
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	// back up the old key pair
+	backupDir := ""
+	privateKeyFile := filepath.Join(dir, DevPodSSHPrivateKeyFile)
+	publicKeyFile := filepath.Join(dir, DevPodSSHPublicKeyFile)
+	_, err = os.Stat(privateKeyFile)
+	if err == nil {
+		backupDir = filepath.Join(dir, "backup-"+now.UTC().Format("20060102-150405"))
+		err = os.Mkdir(backupDir, 0700)
+		if err != nil {
+			return "", errors.Wrap(err, "create backup folder")
+		}
+
+		for _, file := range []string{DevPodSSHPrivateKeyFile, DevPodSSHPublicKeyFile} {
+			err = os.Rename(filepath.Join(dir, file), filepath.Join(backupDir, file))
+			if err != nil && !os.IsNotExist(err) {
+				return "", errors.Wrap(err, "back up ssh key")
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	pubKey, privateKey, err := makeSSHKeyPair()
+	if err != nil {
+		return "", errors.Wrap(err, "generate key pair")
+	}
+
+	err = os.WriteFile(publicKeyFile, []byte(pubKey), 0644)
+	if err != nil {
+		return "", errors.Wrap(err, "write public ssh key")
+	}
+
+	err = os.WriteFile(privateKeyFile, []byte(privateKey), 0600)
+	if err != nil {
+		return "", errors.Wrap(err, "write private ssh key")
+	}
+
+	return backupDir, nil
+}
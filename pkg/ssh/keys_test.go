@@ -0,0 +1,35 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestRotateKeyBase(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	// without a key pair there is nothing to back up
+	backupDir, err := RotateKeyBase(dir, now)
+	assert.NilError(t, err)
+	assert.Equal(t, backupDir, "")
+	oldPrivateKey, err := GetPrivateKeyRawBase(dir)
+	assert.NilError(t, err)
+
+	backupDir, err = RotateKeyBase(dir, now)
+	assert.NilError(t, err)
+	assert.Equal(t, backupDir, filepath.Join(dir, "backup-20240501-123000"))
+	backupPrivateKey, err := os.ReadFile(filepath.Join(backupDir, DevPodSSHPrivateKeyFile))
+	assert.NilError(t, err)
+	assert.Equal(t, string(backupPrivateKey), string(oldPrivateKey))
+	_, err = os.Stat(filepath.Join(backupDir, DevPodSSHPublicKeyFile))
+	assert.NilError(t, err)
+
+	newPrivateKey, err := GetPrivateKeyRawBase(dir)
+	assert.NilError(t, err)
+	assert.Assert(t, string(newPrivateKey) != string(oldPrivateKey))
+}
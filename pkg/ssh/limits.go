@@ -0,0 +1,101 @@
+package ssh
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alessio/shellescape"
+)
+
+// cpuPeriod is the cgroup cpu period in microseconds the cpu quota refers to
+const cpuPeriod = 100000
+
+// limitScript applies the limits to itself and runs the command as child, so the cgroup can be
+// removed afterwards. It prefers a cgroup v2 child cgroup and falls back to ulimit for memory.
+const limitScript = `cpu="$1"; memory="$2"; command="$3"
+parent=""; dir=""
+if [ -f /sys/fs/cgroup/cgroup.controllers ]; then
+  parent="/sys/fs/cgroup$(sed -n 's/^0:://p' /proc/self/cgroup)"
+  dir="${parent%/}/devpod-$$"
+  if mkdir "$dir" 2>/dev/null; then
+    if ! { { [ -z "$cpu" ] || echo "$cpu 100000" > "$dir/cpu.max"; } && { [ -z "$memory" ] || echo "$memory" > "$dir/memory.max"; } && echo $$ > "$dir/cgroup.procs"; } 2>/dev/null; then
+      rmdir "$dir" 2>/dev/null; dir=""
+    fi
+  else
+    dir=""
+  fi
+fi
+if [ -z "$dir" ]; then
+  [ -z "$cpu" ] || echo "devpod: cgroup v2 is not writable in the workspace, running without cpu limit" >&2
+  if [ -n "$memory" ] && ! ulimit -v "$((memory / 1024))" 2>/dev/null; then
+    echo "devpod: cgroup v2 is not writable and ulimit failed in the workspace, running without memory limit" >&2
+  fi
+fi
+sh -c "$command"; code=$?
+if [ -n "$dir" ]; then { echo $$ > "$parent/cgroup.procs" && rmdir "$dir"; } 2>/dev/null; fi
+exit $code`
+
+// ResourceLimits constrain the cpu and memory of a command run in the workspace
+type ResourceLimits struct {
+	// CPUs is the maximum number of cpus the command can use, e.g. 1.5. 0 means no limit
+	CPUs float64
+
+	// Memory is the maximum memory of the command in bytes. 0 means no limit
+	Memory int64
+}
+
+// IsZero returns true if no limit is set
+func (l ResourceLimits) IsZero() bool {
+	return l.CPUs == 0 && l.Memory == 0
+}
+
+// WrapCommand returns a shell command that runs the command with the limits. It uses a cgroup v2
+// if the workspace allows it and ulimit for the memory otherwise. Limits that cannot be applied
+// print a warning to stderr and the command runs without them.
+func (l ResourceLimits) WrapCommand(command string) string {
+	if l.IsZero() {
+		return command
+	}
+
+	cpu := ""
+	if l.CPUs > 0 {
+		cpu = strconv.Itoa(int(l.CPUs * cpuPeriod))
+	}
+	memory := ""
+	if l.Memory > 0 {
+		memory = strconv.FormatInt(l.Memory, 10)
+	}
+
+	return shellescape.QuoteCommand([]string{"sh", "-c", limitScript, "devpod-limits", cpu, memory, command})
+}
+
+// ParseCPUs parses a number of cpus like 0.5 or 2
+func ParseCPUs(cpus string) (float64, error) {
+	value, err := strconv.ParseFloat(cpus, 64)
+	if err != nil || value <= 0 || value*cpuPeriod < 1000 {
+		return 0, fmt.Errorf("cpu limit %s needs to be a number of cpus of at least 0.01, e.g. 0.5 or 2", cpus)
+	}
+
+	return value, nil
+}
+
+// ParseMemory parses a memory size like 512M or 2G in bytes, the units are binary
+func ParseMemory(memory string) (int64, error) {
+	units := map[string]int64{"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+	number := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(memory), "B"), "I")
+	unit := ""
+	if len(number) > 0 {
+		if _, ok := units[number[len(number)-1:]]; ok {
+			unit = number[len(number)-1:]
+			number = number[:len(number)-1]
+		}
+	}
+
+	value, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("memory limit %s needs to be a size like 512M or 2G", memory)
+	}
+
+	return value * units[unit], nil
+}
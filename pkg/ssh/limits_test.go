@@ -0,0 +1,35 @@
+package ssh
+
+import (
+	"os/exec"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseMemory(t *testing.T) {
+	for memory, expected := range map[string]int64{
+		"1024":  1024,
+		"512M":  512 << 20,
+		"2Gi":   2 << 30,
+		"100kb": 100 << 10,
+	} {
+		value, err := ParseMemory(memory)
+		assert.NilError(t, err)
+		assert.Equal(t, value, expected, memory)
+	}
+
+	_, err := ParseMemory("2X")
+	assert.ErrorContains(t, err, "needs to be a size")
+}
+
+func TestWrapCommand(t *testing.T) {
+	assert.Equal(t, ResourceLimits{}.WrapCommand("ls"), "ls")
+
+	// the wrapped command keeps its output and exit code
+	out, err := exec.Command("sh", "-c", ResourceLimits{Memory: 1 << 30}.WrapCommand("echo hello; exit 3")).Output()
+	exitErr, ok := err.(*exec.ExitError)
+	assert.Assert(t, ok, err)
+	assert.Equal(t, exitErr.ExitCode(), 3)
+	assert.Equal(t, string(out), "hello\n")
+}
@@ -0,0 +1,99 @@
+package ssh
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// ConfigTag is the tag of the DevPod hosts in the ssh config, users can match them via 'Match tagged devpod'
+const ConfigTag = "devpod"
+
+var openSSHVersionRegEx = regexp.MustCompile(`OpenSSH_(?:for_Windows_)?(\d+)\.(\d+)`)
+
+// OpenSSHVersion returns the major and minor version of the local OpenSSH client
+func OpenSSHVersion() (int, int, error) {
+	// ssh -V prints the version to stderr
+	out, err := exec.Command("ssh", "-V").CombinedOutput()
+	if err != nil {
+		return 0, 0, fmt.Errorf("get ssh version: %w", err)
+	}
+
+	return parseOpenSSHVersion(string(out))
+}
+
+// SupportsConfigTag returns true if the local OpenSSH client supports the Tag directive, which
+// was added in OpenSSH 9.4
+func SupportsConfigTag() bool {
+	major, minor, err := OpenSSHVersion()
+	if err != nil {
+		return false
+	}
+
+	return major > 9 || (major == 9 && minor >= 4)
+}
+
+func parseOpenSSHVersion(version string) (int, int, error) {
+	match := openSSHVersionRegEx.FindStringSubmatch(version)
+	if match == nil {
+		return 0, 0, fmt.Errorf("unknown ssh version %q", version)
+	}
+
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	return major, minor, nil
+}
+
+// OpenSSHCommandOptions are the options of a connection OpenSSHCommand reproduces
+type OpenSSHCommandOptions struct {
+	// ProxyCommand connects ssh to the workspace, see ProxyCommand
+	ProxyCommand string
+
+	// Host is the name ssh connects to, the ProxyCommand ignores it and the port
+	Host string
+
+	User         string
+	IdentityFile string
+
+	AgentForwarding bool
+	Forwards        []Forward
+
+	// Command is run instead of an interactive shell if not empty
+	Command string
+}
+
+// OpenSSHCommand returns the arguments of a plain ssh command that connects to the workspace
+// like devpod ssh does, so problems can be reproduced with OpenSSH and -vvv
+func OpenSSHCommand(options OpenSSHCommandOptions) []string {
+	args := []string{
+		"ssh",
+		"-o", "ProxyCommand=" + options.ProxyCommand,
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-l", options.User,
+	}
+	if options.IdentityFile != "" {
+		args = append(args, "-i", options.IdentityFile, "-o", "IdentitiesOnly=yes")
+	}
+	if options.AgentForwarding {
+		args = append(args, "-A")
+	}
+	for _, forward := range options.Forwards {
+		switch forward.Type {
+		case ForwardTypeRemote:
+			args = append(args, "-R", forward.Bind.Address+":"+forward.Target.Address)
+		case ForwardTypeDynamic:
+			args = append(args, "-D", forward.Bind.Address)
+		default:
+			args = append(args, "-L", forward.Bind.Address+":"+forward.Target.Address)
+		}
+	}
+
+	args = append(args, options.Host)
+	if options.Command != "" {
+		args = append(args, options.Command)
+	}
+
+	return args
+}
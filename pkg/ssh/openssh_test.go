@@ -0,0 +1,53 @@
+package ssh
+
+import (
+	"testing"
+
+	"github.com/loft-sh/devpod/pkg/port"
+	"gotest.tools/assert"
+)
+
+func TestParseOpenSSHVersion(t *testing.T) {
+	major, minor, err := parseOpenSSHVersion("OpenSSH_9.6p1 Ubuntu-3ubuntu13, OpenSSL 3.0.13 30 Jan 2024\n")
+	assert.NilError(t, err)
+	assert.Equal(t, major, 9)
+	assert.Equal(t, minor, 6)
+
+	major, minor, err = parseOpenSSHVersion("OpenSSH_for_Windows_8.1p1, LibreSSL 3.0.2")
+	assert.NilError(t, err)
+	assert.Equal(t, major, 8)
+	assert.Equal(t, minor, 1)
+
+	_, _, err = parseOpenSSHVersion("Dropbear v2022.83")
+	assert.ErrorContains(t, err, "unknown ssh version")
+}
+
+func TestOpenSSHCommand(t *testing.T) {
+	args := OpenSSHCommand(OpenSSHCommandOptions{
+		ProxyCommand:    "devpod ssh --stdio --context default --user vscode my-workspace",
+		Host:            "my-workspace.devpod",
+		User:            "vscode",
+		IdentityFile:    "/home/me/.devpod/keys/id_devpod_rsa",
+		AgentForwarding: true,
+		Forwards: []Forward{
+			{Type: ForwardTypeLocal, Bind: port.Address{Protocol: "tcp", Address: "localhost:8080"}, Target: port.Address{Protocol: "tcp", Address: "localhost:3000"}},
+			{Type: ForwardTypeRemote, Bind: port.Address{Protocol: "tcp", Address: "localhost:5432"}, Target: port.Address{Protocol: "tcp", Address: "localhost:5432"}},
+			{Type: ForwardTypeDynamic, Bind: port.Address{Protocol: "tcp", Address: "localhost:1080"}},
+		},
+		Command: "make test",
+	})
+	assert.DeepEqual(t, args, []string{
+		"ssh",
+		"-o", "ProxyCommand=devpod ssh --stdio --context default --user vscode my-workspace",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-l", "vscode",
+		"-i", "/home/me/.devpod/keys/id_devpod_rsa", "-o", "IdentitiesOnly=yes",
+		"-A",
+		"-L", "localhost:8080:localhost:3000",
+		"-R", "localhost:5432:localhost:5432",
+		"-D", "localhost:1080",
+		"my-workspace.devpod",
+		"make test",
+	})
+}
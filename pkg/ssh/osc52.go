@@ -0,0 +1,125 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+)
+
+// MaxClipboardSize is the maximum size of clipboard content accepted from a remote OSC 52 sequence
+const MaxClipboardSize = 1 << 20
+
+// maxOSCSize is the maximum size of a buffered OSC sequence, longer sequences are passed through
+// without being parsed
+var maxOSCSize = len("52;") + 32 + base64.StdEncoding.EncodedLen(MaxClipboardSize)
+
+type oscState int
+
+const (
+	oscNone oscState = iota
+	oscEscape
+	oscBody
+	oscBodyEscape
+	oscSkip
+	oscSkipEscape
+)
+
+// OSC52Writer passes the output of a session through unchanged and calls onCopy with the content
+// of OSC 52 clipboard writes, so DevPod can set the local clipboard for terminals that don't support
+// OSC 52 themselves. Clipboard content larger than MaxClipboardSize is ignored.
+type OSC52Writer struct {
+	writer io.Writer
+	onCopy func(content []byte)
+
+	state oscState
+	body  []byte
+}
+
+// NewOSC52Writer creates a new OSC 52 writer
+func NewOSC52Writer(writer io.Writer, onCopy func(content []byte)) *OSC52Writer {
+	return &OSC52Writer{
+		writer: writer,
+		onCopy: onCopy,
+	}
+}
+
+// Write scans p for OSC 52 sequences, which might be split across writes, and writes it unchanged
+func (o *OSC52Writer) Write(p []byte) (int, error) {
+	for _, b := range p {
+		o.scan(b)
+	}
+
+	return o.writer.Write(p)
+}
+
+func (o *OSC52Writer) scan(b byte) {
+	switch o.state {
+	case oscNone:
+		if b == 0x1b {
+			o.state = oscEscape
+		}
+	case oscEscape:
+		if b == ']' {
+			o.state = oscBody
+			o.body = o.body[:0]
+		} else if b != 0x1b {
+			o.state = oscNone
+		}
+	case oscBody:
+		if b == 0x07 {
+			o.finish()
+		} else if b == 0x1b {
+			o.state = oscBodyEscape
+		} else if len(o.body) >= maxOSCSize {
+			o.state = oscSkip
+			o.body = nil
+		} else {
+			o.body = append(o.body, b)
+		}
+	case oscBodyEscape:
+		if b == '\\' {
+			o.finish()
+		} else {
+			// an escape without backslash aborts the sequence
+			o.state = oscNone
+			o.scan(b)
+		}
+	case oscSkip:
+		if b == 0x07 {
+			o.state = oscNone
+		} else if b == 0x1b {
+			o.state = oscSkipEscape
+		}
+	case oscSkipEscape:
+		o.state = oscNone
+		if b != '\\' {
+			o.scan(b)
+		}
+	}
+}
+
+func (o *OSC52Writer) finish() {
+	o.state = oscNone
+	body := o.body
+	o.body = o.body[:0]
+	if !bytes.HasPrefix(body, []byte("52;")) {
+		return
+	}
+
+	// the selection targets are followed by the base64 content, ? requests the clipboard instead
+	index := bytes.IndexByte(body[3:], ';')
+	if index < 0 {
+		return
+	}
+	data := body[3+index+1:]
+	if bytes.Equal(data, []byte("?")) {
+		return
+	}
+
+	content, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil || len(content) > MaxClipboardSize {
+		return
+	}
+
+	o.onCopy(content)
+}
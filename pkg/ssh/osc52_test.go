@@ -0,0 +1,38 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestOSC52Writer(t *testing.T) {
+	copied := []string{}
+	out := &bytes.Buffer{}
+	writer := NewOSC52Writer(out, func(content []byte) {
+		copied = append(copied, string(content))
+	})
+
+	input := "before\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("hello")) + "\x07between\x1b]52;;" + base64.StdEncoding.EncodeToString([]byte("world")) + "\x1b\\\x1b]52;c;?\x07\x1b]0;title\x07after"
+	for i := 0; i < len(input); i += 3 {
+		end := i + 3
+		if end > len(input) {
+			end = len(input)
+		}
+		_, err := writer.Write([]byte(input[i:end]))
+		assert.NilError(t, err)
+	}
+
+	assert.Equal(t, out.String(), input)
+	assert.DeepEqual(t, copied, []string{"hello", "world"})
+
+	// oversized content is ignored
+	copied = []string{}
+	oversized := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", MaxClipboardSize+1)))
+	_, err := writer.Write([]byte("\x1b]52;c;" + oversized + "\x07\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("ok")) + "\x07"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, copied, []string{"ok"})
+}
@@ -0,0 +1,73 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// OutputFile writes the output of a remote command to a local file
+type OutputFile struct {
+	m sync.Mutex
+
+	file     *os.File
+	writer   *bufio.Writer
+	metadata bool
+	start    time.Time
+}
+
+// NewOutputFile creates or truncates the file at path, if appendFile is true the output is
+// appended instead. If metadata is true, a footer with the exit code and the start and
+// finish time is written when the file is closed.
+func NewOutputFile(path string, appendFile, metadata bool) (*OutputFile, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendFile {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(path, flags, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("open output file: %w", err)
+	}
+
+	return &OutputFile{
+		file:     file,
+		writer:   bufio.NewWriter(file),
+		metadata: metadata,
+		start:    time.Now(),
+	}, nil
+}
+
+// Write writes p to the file
+func (o *OutputFile) Write(p []byte) (int, error) {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	return o.writer.Write(p)
+}
+
+// Close writes the metadata footer if enabled, flushes and closes the file
+func (o *OutputFile) Close(exitCode int) error {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	if o.metadata {
+		_, _ = fmt.Fprintf(o.writer, "\n# devpod: exit code %d, started %s, finished %s\n", exitCode, o.start.UTC().Format(time.RFC3339), time.Now().UTC().Format(time.RFC3339))
+	}
+
+	err := o.writer.Flush()
+	if err != nil {
+		_ = o.file.Close()
+		return err
+	}
+
+	err = o.file.Sync()
+	if err != nil {
+		_ = o.file.Close()
+		return err
+	}
+
+	return o.file.Close()
+}
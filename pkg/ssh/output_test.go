@@ -0,0 +1,38 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestOutputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output.log")
+
+	// truncates existing content
+	err := os.WriteFile(path, []byte("old\n"), 0666)
+	assert.NilError(t, err)
+	output, err := NewOutputFile(path, false, false)
+	assert.NilError(t, err)
+	_, err = output.Write([]byte("first\n"))
+	assert.NilError(t, err)
+	assert.NilError(t, output.Close(0))
+
+	out, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "first\n")
+
+	// appends with metadata footer
+	output, err = NewOutputFile(path, true, true)
+	assert.NilError(t, err)
+	_, err = output.Write([]byte("second\n"))
+	assert.NilError(t, err)
+	assert.NilError(t, output.Close(3))
+
+	out, err = os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(string(out), "first\nsecond\n\n# devpod: exit code 3, started "), string(out))
+}
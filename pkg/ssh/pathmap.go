@@ -0,0 +1,102 @@
+package ssh
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PathMapping maps a local folder to a folder in the workspace
+type PathMapping struct {
+	Local  string
+	Remote string
+}
+
+// PathRewrite is a single path that was rewritten by RewritePaths
+type PathRewrite struct {
+	From string
+	To   string
+}
+
+// ParsePathMapping parses a mapping in the form localDir:remoteDir. The last colon separates the
+// folders, so local Windows paths like C:\project work.
+func ParsePathMapping(mapping string) (PathMapping, error) {
+	index := strings.LastIndex(mapping, ":")
+	if index <= 0 || index == len(mapping)-1 {
+		return PathMapping{}, fmt.Errorf("invalid path mapping %s, expected format localDir:remoteDir", mapping)
+	}
+
+	local := strings.TrimRight(mapping[:index], `/\`)
+	remote := strings.TrimRight(mapping[index+1:], "/")
+	if local == "" || !strings.HasPrefix(mapping[index+1:], "/") {
+		return PathMapping{}, fmt.Errorf("invalid path mapping %s, expected an absolute local and remote folder", mapping)
+	}
+	if remote == "" {
+		remote = "/"
+	}
+
+	return PathMapping{Local: local, Remote: remote}, nil
+}
+
+// RewritePaths replaces the local folders of the mappings with the remote ones in the command. Only
+// whole path components are replaced, e.g. /src maps /src/main.go but not /srcs. Separators
+// after a Windows folder are converted to slashes.
+func RewritePaths(command string, mappings []PathMapping) (string, []PathRewrite) {
+	// prefer the most specific mapping
+	mappings = append([]PathMapping{}, mappings...)
+	sort.SliceStable(mappings, func(i, j int) bool {
+		return len(mappings[i].Local) > len(mappings[j].Local)
+	})
+
+	rewrites := []PathRewrite{}
+	out := &strings.Builder{}
+	for i := 0; i < len(command); {
+		mapping, ok := matchPathMapping(command, i, mappings)
+		if !ok {
+			out.WriteByte(command[i])
+			i++
+			continue
+		}
+
+		// rewrite the rest of the path
+		end := i + len(mapping.Local)
+		for end < len(command) && isPathByte(command[end]) {
+			end++
+		}
+		rest := strings.ReplaceAll(command[i+len(mapping.Local):end], `\`, "/")
+		to := strings.TrimSuffix(mapping.Remote, "/") + rest
+		if to == "" {
+			to = "/"
+		}
+
+		rewrites = append(rewrites, PathRewrite{From: command[i:end], To: to})
+		out.WriteString(to)
+		i = end
+	}
+
+	return out.String(), rewrites
+}
+
+func matchPathMapping(command string, index int, mappings []PathMapping) (PathMapping, bool) {
+	if index > 0 && isPathByte(command[index-1]) {
+		return PathMapping{}, false
+	}
+
+	for _, mapping := range mappings {
+		if !strings.HasPrefix(command[index:], mapping.Local) {
+			continue
+		}
+
+		end := index + len(mapping.Local)
+		if end == len(command) || !isPathByte(command[end]) || command[end] == '/' || command[end] == '\\' {
+			return mapping, true
+		}
+	}
+
+	return PathMapping{}, false
+}
+
+// isPathByte returns true if the byte can be part of a path within a command
+func isPathByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || strings.IndexByte(`/\._-~+@`, b) >= 0
+}
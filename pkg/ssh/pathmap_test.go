@@ -0,0 +1,46 @@
+package ssh
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParsePathMapping(t *testing.T) {
+	mapping, err := ParsePathMapping("/home/me/project/:/workspaces/project")
+	assert.NilError(t, err)
+	assert.Equal(t, mapping, PathMapping{Local: "/home/me/project", Remote: "/workspaces/project"})
+
+	mapping, err = ParsePathMapping(`C:\Users\me\project:/workspaces/project`)
+	assert.NilError(t, err)
+	assert.Equal(t, mapping, PathMapping{Local: `C:\Users\me\project`, Remote: "/workspaces/project"})
+
+	for _, invalid := range []string{"/home/me", ":/workspaces", "/home/me:", "/home/me:workspaces"} {
+		_, err = ParsePathMapping(invalid)
+		assert.ErrorContains(t, err, "invalid path mapping")
+	}
+}
+
+func TestRewritePaths(t *testing.T) {
+	mappings := []PathMapping{
+		{Local: "/home/me/project", Remote: "/workspaces/project"},
+		{Local: "/home/me/project/vendor", Remote: "/opt/vendor"},
+		{Local: `C:\src`, Remote: "/src"},
+	}
+
+	command, rewrites := RewritePaths(`go test /home/me/project/pkg/... -coverprofile="/home/me/project" --vendor=/home/me/project/vendor/lib`, mappings)
+	assert.Equal(t, command, `go test /workspaces/project/pkg/... -coverprofile="/workspaces/project" --vendor=/opt/vendor/lib`)
+	assert.DeepEqual(t, rewrites, []PathRewrite{
+		{From: "/home/me/project/pkg/...", To: "/workspaces/project/pkg/..."},
+		{From: "/home/me/project", To: "/workspaces/project"},
+		{From: "/home/me/project/vendor/lib", To: "/opt/vendor/lib"},
+	})
+
+	// only whole path components are rewritten
+	command, rewrites = RewritePaths("cat /home/me/projects/a /x/home/me/project/b", mappings)
+	assert.Equal(t, command, "cat /home/me/projects/a /x/home/me/project/b")
+	assert.Equal(t, len(rewrites), 0)
+
+	command, _ = RewritePaths(`code C:\src\main.go`, mappings)
+	assert.Equal(t, command, "code /src/main.go")
+}
@@ -0,0 +1,150 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// proxyFromEnvironment resolves the proxy for a request from HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+var proxyFromEnvironment = http.ProxyFromEnvironment
+
+// DialWithProxy dials the given ssh address and establishes an ssh connection. If proxyURL is
+// empty, the proxy is taken from the HTTPS_PROXY / HTTP_PROXY environment variables while
+// respecting NO_PROXY. If resolver is nil, a new resolver without overrides is used.
+func DialWithProxy(ctx context.Context, addr, proxyURL string, resolver *Resolver, config *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := DialContext(ctx, addr, proxyURL, resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// DialContext opens a tcp connection to the given address, tunneled through an
+// HTTP CONNECT proxy if one is configured for it
+func DialContext(ctx context.Context, addr, proxyURL string, resolver *Resolver) (net.Conn, error) {
+	if resolver == nil {
+		var err error
+		resolver, err = NewResolver(nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	proxy, err := resolveProxy(addr, proxyURL)
+	if err != nil {
+		return nil, err
+	} else if proxy == nil {
+		return resolver.DialContext(ctx, &net.Dialer{Timeout: 30 * time.Second}, addr)
+	}
+
+	return dialHTTPConnect(ctx, proxy, addr, resolver)
+}
+
+func resolveProxy(addr, proxyURL string) (*url.URL, error) {
+	if proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse proxy url")
+		}
+
+		return proxy, nil
+	}
+
+	// we use the https scheme here, so HTTPS_PROXY takes precedence over HTTP_PROXY
+	proxy, err := proxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: "https", Host: addr}})
+	if err != nil {
+		return nil, errors.Wrap(err, "get proxy from environment")
+	}
+
+	return proxy, nil
+}
+
+func dialHTTPConnect(ctx context.Context, proxy *url.URL, addr string, resolver *Resolver) (net.Conn, error) {
+	proxyAddr := proxy.Host
+	if proxy.Port() == "" {
+		switch proxy.Scheme {
+		case "https":
+			proxyAddr = net.JoinHostPort(proxy.Hostname(), "443")
+		case "http", "":
+			proxyAddr = net.JoinHostPort(proxy.Hostname(), "80")
+		}
+	}
+
+	conn, err := resolver.DialContext(ctx, &net.Dialer{Timeout: 30 * time.Second}, proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("dial proxy %s: %w", proxyAddr, err)
+	}
+
+	switch proxy.Scheme {
+	case "https":
+		conn = tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+	case "http", "":
+	default:
+		_ = conn.Close()
+		return nil, fmt.Errorf("unsupported proxy scheme %s, only http and https proxies are supported", proxy.Scheme)
+	}
+
+	// send the connect request
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + password))
+		request.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	err = request.Write(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("write connect request to proxy: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("read connect response from proxy: %w", err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %s refused connection to %s: %s", proxyAddr, addr, response.Status)
+	}
+
+	// the proxy might have already sent data of the tunneled connection
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+
+	return conn, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+
+	reader *bufio.Reader
+}
+
+func (b *bufferedConn) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
@@ -0,0 +1,133 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func startEchoServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// startConnectProxy starts a minimal HTTP CONNECT proxy that requires the given authorization
+func startConnectProxy(t *testing.T, authorization string) (string, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	targets := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				request, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil || request.Method != http.MethodConnect {
+					return
+				} else if request.Header.Get("Proxy-Authorization") != authorization {
+					_, _ = conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+					return
+				}
+
+				targets <- request.Host
+				target, err := net.Dial("tcp", request.Host)
+				if err != nil {
+					_, _ = conn.Write([]byte("HTTP/1.1 502 Bad Gateway\r\n\r\n"))
+					return
+				}
+				defer target.Close()
+
+				_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+				go func() {
+					_, _ = io.Copy(target, conn)
+				}()
+				_, _ = io.Copy(conn, target)
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), targets
+}
+
+func assertEcho(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+
+	_, err := conn.Write([]byte("hello"))
+	assert.NilError(t, err)
+	out := make([]byte, 5)
+	_, err = io.ReadFull(conn, out)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "hello")
+}
+
+func TestDialContextExplicitProxy(t *testing.T) {
+	target := startEchoServer(t)
+	proxyAddr, targets := startConnectProxy(t, "Basic dXNlcjpwYXNz")
+
+	conn, err := DialContext(context.Background(), target, "http://user:pass@"+proxyAddr, nil)
+	assert.NilError(t, err)
+	assertEcho(t, conn)
+	assert.Equal(t, <-targets, target)
+}
+
+func TestDialContextProxyRefused(t *testing.T) {
+	target := startEchoServer(t)
+	proxyAddr, _ := startConnectProxy(t, "Basic dXNlcjpwYXNz")
+
+	_, err := DialContext(context.Background(), target, "http://"+proxyAddr, nil)
+	assert.ErrorContains(t, err, "407")
+}
+
+func TestDialContextEnvironmentProxy(t *testing.T) {
+	target := startEchoServer(t)
+	proxyAddr, targets := startConnectProxy(t, "")
+	defer func() { proxyFromEnvironment = http.ProxyFromEnvironment }()
+
+	// proxy configured for the target
+	proxyFromEnvironment = func(request *http.Request) (*url.URL, error) {
+		assert.Equal(t, request.URL.Host, target)
+		return &url.URL{Scheme: "http", Host: proxyAddr}, nil
+	}
+	conn, err := DialContext(context.Background(), target, "", nil)
+	assert.NilError(t, err)
+	assertEcho(t, conn)
+	assert.Equal(t, <-targets, target)
+
+	// target excluded via NO_PROXY
+	proxyFromEnvironment = func(request *http.Request) (*url.URL, error) {
+		return nil, nil
+	}
+	conn, err = DialContext(context.Background(), target, "", nil)
+	assert.NilError(t, err)
+	assertEcho(t, conn)
+	assert.Equal(t, len(targets), 0)
+}
@@ -0,0 +1,71 @@
+package ssh
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/alessio/shellescape"
+)
+
+// rcMarker separates the environment before and after the rc command from its output
+const rcMarker = "\x00__DEVPOD_RC__\x00"
+
+// rcIgnoredEnv are variables that change with every shell and are not taken over from the rc command
+var rcIgnoredEnv = map[string]bool{
+	"PWD":    true,
+	"OLDPWD": true,
+	"SHLVL":  true,
+	"_":      true,
+}
+
+// RCCommand returns a command that runs rcCommand via sh and prints the environment before and
+// after it, so the variables it exports can be applied to the interactive session via
+// ParseRCCommandOutput. The exit code of the command is the one of rcCommand.
+func RCCommand(rcCommand string) string {
+	script := strings.Join([]string{
+		"env -0",
+		"printf '\\0%s\\0' __DEVPOD_RC__",
+		rcCommand,
+		"__devpod_rc_status=$?",
+		"printf '\\0%s\\0' __DEVPOD_RC__",
+		"env -0",
+		"exit $__devpod_rc_status",
+	}, "\n")
+
+	return shellescape.QuoteCommand([]string{"sh", "-c", script})
+}
+
+// ParseRCCommandOutput splits the stdout of RCCommand into the output of the rc command and the
+// variables it exported or changed. If the rc command exited the shell early, only its output is
+// returned.
+func ParseRCCommandOutput(stdout []byte) ([]byte, map[string]string) {
+	parts := bytes.Split(stdout, []byte(rcMarker))
+	if len(parts) == 2 {
+		return parts[1], nil
+	} else if len(parts) != 3 {
+		return nil, nil
+	}
+
+	before := parseEnv0(parts[0])
+	env := map[string]string{}
+	for k, v := range parseEnv0(parts[2]) {
+		if old, ok := before[k]; (!ok || old != v) && !rcIgnoredEnv[k] {
+			env[k] = v
+		}
+	}
+
+	return parts[1], env
+}
+
+// parseEnv0 parses the output of env -0
+func parseEnv0(out []byte) map[string]string {
+	env := map[string]string{}
+	for _, entry := range strings.Split(string(out), "\x00") {
+		k, v, found := strings.Cut(entry, "=")
+		if found && k != "" {
+			env[k] = v
+		}
+	}
+
+	return env
+}
@@ -0,0 +1,37 @@
+package ssh
+
+import (
+	"os/exec"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRCCommand(t *testing.T) {
+	out, err := exec.Command("sh", "-c", RCCommand("echo activated; export VENV=/opt/venv; export PATH=/opt/venv/bin:$PATH; cd /")).Output()
+	assert.NilError(t, err)
+
+	output, env := ParseRCCommandOutput(out)
+	assert.Equal(t, string(output), "activated\n")
+	assert.Equal(t, env["VENV"], "/opt/venv")
+	assert.Assert(t, env["PATH"] != "")
+	_, ok := env["PWD"]
+	assert.Assert(t, !ok)
+	_, ok = env["HOME"]
+	assert.Assert(t, !ok)
+
+	// a failed rc command keeps its exit code and the variables exported before
+	out, err = exec.Command("sh", "-c", RCCommand("export A=1; false")).Output()
+	exitErr, ok := err.(*exec.ExitError)
+	assert.Assert(t, ok)
+	assert.Equal(t, exitErr.ExitCode(), 1)
+	output, env = ParseRCCommandOutput(out)
+	assert.Equal(t, string(output), "")
+	assert.DeepEqual(t, env, map[string]string{"A": "1"})
+
+	// exiting the shell only keeps the output
+	out, _ = exec.Command("sh", "-c", RCCommand("echo bye; exit 3")).Output()
+	output, env = ParseRCCommandOutput(out)
+	assert.Equal(t, string(output), "bye\n")
+	assert.Assert(t, env == nil)
+}
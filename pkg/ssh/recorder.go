@@ -0,0 +1,160 @@
+package ssh
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Recorder records a terminal session in the asciinema v2 format
+type Recorder struct {
+	m sync.Mutex
+
+	writer      io.WriteCloser
+	recordInput bool
+	start       time.Time
+
+	// incomplete utf-8 sequences that are kept until the rest arrives
+	pending map[string][]byte
+}
+
+// NewRecorder creates a new recorder that writes the recording to writer. If recordInput is true,
+// the session input, e.g. keystrokes, is recorded as well.
+func NewRecorder(writer io.WriteCloser, recordInput bool) *Recorder {
+	return &Recorder{
+		writer:      writer,
+		recordInput: recordInput,
+		pending:     map[string][]byte{},
+	}
+}
+
+// Start writes the recording header, all events are timed relative to the call of Start
+func (r *Recorder) Start(width, height int) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.start = time.Now()
+	header, err := json.Marshal(map[string]interface{}{
+		"version":   2,
+		"width":     width,
+		"height":    height,
+		"timestamp": r.start.Unix(),
+		"env": map[string]string{
+			"TERM":  os.Getenv("TERM"),
+			"SHELL": os.Getenv("SHELL"),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.writer.Write(append(header, '\n'))
+	return err
+}
+
+// Output returns a writer that writes to w and records everything written as output
+func (r *Recorder) Output(w io.Writer) io.Writer {
+	return &recordWriter{writer: w, recorder: r}
+}
+
+// Input returns a reader that reads from reader and records everything read as input if enabled
+func (r *Recorder) Input(reader io.Reader) io.Reader {
+	if !r.recordInput {
+		return reader
+	}
+
+	return &recordReader{reader: reader, recorder: r}
+}
+
+// Resize records a change of the terminal size
+func (r *Recorder) Resize(width, height int) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.writeEvent("r", fmt.Sprintf("%dx%d", width, height))
+}
+
+// Close flushes pending data and closes the underlying writer
+func (r *Recorder) Close() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	for eventType, data := range r.pending {
+		if len(data) > 0 {
+			r.writeEvent(eventType, string(data))
+		}
+	}
+	r.pending = map[string][]byte{}
+
+	return r.writer.Close()
+}
+
+func (r *Recorder) record(eventType string, data []byte) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	data = append(r.pending[eventType], data...)
+	complete := completeUTF8(data)
+	r.pending[eventType] = append([]byte{}, data[complete:]...)
+	if complete > 0 {
+		r.writeEvent(eventType, string(data[:complete]))
+	}
+}
+
+func (r *Recorder) writeEvent(eventType, data string) {
+	out, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), eventType, data})
+	if err != nil {
+		return
+	}
+
+	// a failing recording should never interrupt the session
+	_, _ = r.writer.Write(append(out, '\n'))
+}
+
+// completeUTF8 returns the length of data without a trailing incomplete utf-8 sequence
+func completeUTF8(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(data[i]) {
+			continue
+		}
+		if !utf8.FullRune(data[i:]) {
+			return i
+		}
+
+		break
+	}
+
+	return len(data)
+}
+
+type recordWriter struct {
+	writer   io.Writer
+	recorder *Recorder
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	if n > 0 {
+		w.recorder.record("o", p[:n])
+	}
+
+	return n, err
+}
+
+type recordReader struct {
+	reader   io.Reader
+	recorder *Recorder
+}
+
+func (r *recordReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.recorder.record("i", p[:n])
+	}
+
+	return n, err
+}
@@ -0,0 +1,85 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func readCast(t *testing.T, cast string) (map[string]interface{}, [][]interface{}) {
+	lines := strings.Split(strings.TrimSpace(cast), "\n")
+	header := map[string]interface{}{}
+	assert.NilError(t, json.Unmarshal([]byte(lines[0]), &header))
+
+	events := [][]interface{}{}
+	for _, line := range lines[1:] {
+		event := []interface{}{}
+		assert.NilError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+
+	return header, events
+}
+
+func TestRecorder(t *testing.T) {
+	cast := &bytes.Buffer{}
+	recorder := NewRecorder(nopWriteCloser{cast}, false)
+	assert.NilError(t, recorder.Start(120, 40))
+
+	// output is passed through unchanged
+	stdout := &bytes.Buffer{}
+	_, err := recorder.Output(stdout).Write([]byte("hello\r\n"))
+	assert.NilError(t, err)
+	assert.Equal(t, stdout.String(), "hello\r\n")
+
+	// utf-8 sequences split across writes are recorded as a whole
+	euro := []byte("€")
+	_, _ = recorder.Output(stdout).Write(euro[:1])
+	_, _ = recorder.Output(stdout).Write(euro[1:])
+
+	// input is not recorded by default
+	out, err := io.ReadAll(recorder.Input(strings.NewReader("secret")))
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "secret")
+
+	recorder.Resize(100, 30)
+	assert.NilError(t, recorder.Close())
+
+	header, events := readCast(t, cast.String())
+	assert.Equal(t, header["version"], float64(2))
+	assert.Equal(t, header["width"], float64(120))
+	assert.Equal(t, header["height"], float64(40))
+	assert.Equal(t, len(events), 3)
+	assert.Equal(t, events[0][1], "o")
+	assert.Equal(t, events[0][2], "hello\r\n")
+	assert.Equal(t, events[1][2], "€")
+	assert.Equal(t, events[2][1], "r")
+	assert.Equal(t, events[2][2], "100x30")
+}
+
+func TestRecorderInput(t *testing.T) {
+	cast := &bytes.Buffer{}
+	recorder := NewRecorder(nopWriteCloser{cast}, true)
+	assert.NilError(t, recorder.Start(80, 24))
+
+	_, err := io.ReadAll(recorder.Input(strings.NewReader("ls\r")))
+	assert.NilError(t, err)
+	assert.NilError(t, recorder.Close())
+
+	_, events := readCast(t, cast.String())
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, events[0][1], "i")
+	assert.Equal(t, events[0][2], "ls\r")
+}
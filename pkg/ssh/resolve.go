@@ -0,0 +1,131 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolver resolves host names with retries and caches successful resolutions. A resolver
+// should only be used for a single invocation, so no stale entries are kept across runs.
+type Resolver struct {
+	// Retries is the amount of additional lookups after a failed lookup
+	Retries int
+
+	// Backoff is the wait time after the first failed lookup, it doubles with every retry
+	Backoff time.Duration
+
+	lookup func(ctx context.Context, host string) ([]string, error)
+
+	m         sync.Mutex
+	overrides map[string]string
+	cache     map[string][]string
+}
+
+// NewResolver creates a new resolver with the given overrides in the form host:ip
+func NewResolver(overrides []string) (*Resolver, error) {
+	resolver := &Resolver{
+		Retries:   3,
+		Backoff:   500 * time.Millisecond,
+		lookup:    net.DefaultResolver.LookupHost,
+		overrides: map[string]string{},
+		cache:     map[string][]string{},
+	}
+	for _, override := range overrides {
+		host, ip, found := strings.Cut(override, ":")
+		if !found || host == "" || net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid resolve override %s, expected format host:ip", override)
+		}
+
+		resolver.overrides[strings.ToLower(host)] = ip
+	}
+
+	return resolver, nil
+}
+
+// DialContext resolves the host of the given host:port address and connects to its ip
+// addresses in turn until one succeeds. Like net.Dialer it returns the first error if
+// all of them fail.
+func (r *Resolver) DialContext(ctx context.Context, dialer *net.Dialer, addr string) (net.Conn, error) {
+	addresses, err := r.ResolveAddress(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	for _, address := range addresses {
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err == nil {
+			return conn, nil
+		} else if firstErr == nil {
+			firstErr = err
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, firstErr
+}
+
+// ResolveAddress replaces the host of the given host:port address with each of its ip addresses
+func (r *Resolver) ResolveAddress(ctx context.Context, addr string) ([]string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := r.Resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addresses = append(addresses, net.JoinHostPort(ip, port))
+	}
+
+	return addresses, nil
+}
+
+// Resolve returns the ip addresses of the given host in the order of the lookup
+func (r *Resolver) Resolve(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	key := strings.ToLower(host)
+	if ip, ok := r.overrides[key]; ok {
+		return []string{ip}, nil
+	} else if ips, ok := r.cache[key]; ok {
+		return ips, nil
+	}
+
+	backoff := r.Backoff
+	for attempt := 0; ; attempt++ {
+		addresses, err := r.lookup(ctx, host)
+		if err == nil && len(addresses) > 0 {
+			r.cache[key] = addresses
+			return addresses, nil
+		} else if err == nil {
+			err = fmt.Errorf("no addresses found")
+		}
+		if attempt >= r.Retries {
+			return nil, fmt.Errorf("resolve %s: %w", host, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("resolve %s: %w", host, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,82 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestResolver(t *testing.T) {
+	resolver, err := NewResolver([]string{"internal.example.com:10.0.0.1"})
+	assert.NilError(t, err)
+	resolver.Backoff = time.Millisecond
+
+	lookups := 0
+	resolver.lookup = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		if lookups < 3 {
+			return nil, fmt.Errorf("temporary failure")
+		}
+
+		return []string{"192.168.0.1", "192.168.0.2"}, nil
+	}
+
+	// overrides and ips don't need a lookup
+	addrs, err := resolver.ResolveAddress(context.Background(), "internal.example.com:22")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, addrs, []string{"10.0.0.1:22"})
+	addrs, err = resolver.ResolveAddress(context.Background(), "127.0.0.1:22")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, addrs, []string{"127.0.0.1:22"})
+	assert.Equal(t, lookups, 0)
+
+	// transient failures are retried and the result is cached
+	addrs, err = resolver.ResolveAddress(context.Background(), "example.com:22")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, addrs, []string{"192.168.0.1:22", "192.168.0.2:22"})
+	addrs, err = resolver.ResolveAddress(context.Background(), "EXAMPLE.com:2222")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, addrs, []string{"192.168.0.1:2222", "192.168.0.2:2222"})
+	assert.Equal(t, lookups, 3)
+
+	// give up after the retries
+	resolver.lookup = func(ctx context.Context, host string) ([]string, error) {
+		return nil, fmt.Errorf("no such host")
+	}
+	_, err = resolver.ResolveAddress(context.Background(), "unknown.example.com:22")
+	assert.ErrorContains(t, err, "no such host")
+
+	_, err = NewResolver([]string{"example.com"})
+	assert.ErrorContains(t, err, "expected format host:ip")
+}
+
+func TestResolverDialContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer listener.Close()
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	resolver, err := NewResolver(nil)
+	assert.NilError(t, err)
+	resolver.lookup = func(ctx context.Context, host string) ([]string, error) {
+		return []string{"127.0.0.2", "127.0.0.1"}, nil
+	}
+
+	// the first address refuses the connection, so the next one is used
+	conn, err := resolver.DialContext(context.Background(), &net.Dialer{}, net.JoinHostPort("example.com", port))
+	assert.NilError(t, err)
+	assert.Equal(t, conn.RemoteAddr().String(), net.JoinHostPort("127.0.0.1", port))
+	assert.NilError(t, conn.Close())
+
+	// the first error is returned if no address works
+	resolver.lookup = func(ctx context.Context, host string) ([]string, error) {
+		return []string{"127.0.0.2", "127.0.0.3"}, nil
+	}
+	_, err = resolver.DialContext(context.Background(), &net.Dialer{}, net.JoinHostPort("other.example.com", port))
+	assert.ErrorContains(t, err, "127.0.0.2")
+}
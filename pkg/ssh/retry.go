@@ -0,0 +1,48 @@
+package ssh
+
+import "strings"
+
+// DefaultRetryableErrors are substrings of errors of transient connection failures
+var DefaultRetryableErrors = []string{
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"i/o timeout",
+	"unexpected eof",
+	"no route to host",
+	"temporary failure in name resolution",
+}
+
+// authErrors are substrings of authentication failures, which are never retried
+var authErrors = []string{
+	"unable to authenticate",
+	"authentication failed",
+	"permission denied",
+	"unauthorized",
+	"credentials are invalid",
+}
+
+// RetryRule returns the rule that makes the error retryable, which is either one of
+// DefaultRetryableErrors or of the additional rules. Rules match case-insensitively anywhere in
+// the error. Authentication failures are never retryable.
+func RetryRule(err error, additionalRules []string) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, authError := range authErrors {
+		if strings.Contains(message, authError) {
+			return "", false
+		}
+	}
+
+	for _, rule := range append(append([]string{}, DefaultRetryableErrors...), additionalRules...) {
+		rule = strings.TrimSpace(rule)
+		if rule != "" && strings.Contains(message, strings.ToLower(rule)) {
+			return rule, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,26 @@
+package ssh
+
+import (
+	"errors"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRetryRule(t *testing.T) {
+	rule, retry := RetryRule(errors.New("dial tcp 10.0.0.1:22: connect: Connection Refused"), nil)
+	assert.Assert(t, retry)
+	assert.Equal(t, rule, "connection refused")
+
+	_, retry = RetryRule(errors.New("provider quota exceeded"), nil)
+	assert.Assert(t, !retry)
+	rule, retry = RetryRule(errors.New("provider Quota Exceeded"), []string{"timeout", " quota exceeded"})
+	assert.Assert(t, retry)
+	assert.Equal(t, rule, "quota exceeded")
+
+	// authentication failures are never retried
+	_, retry = RetryRule(errors.New("ssh: unable to authenticate, connection reset"), []string{"unable"})
+	assert.Assert(t, !retry)
+	_, retry = RetryRule(nil, []string{""})
+	assert.Assert(t, !retry)
+}
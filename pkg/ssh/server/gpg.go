@@ -0,0 +1,102 @@
+package server
+
+import (
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gliderlabs/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const (
+	// GPGAgentChannelType is the type of the channels the server opens to the client for every
+	// connection to the forwarded gpg agent
+	GPGAgentChannelType = "gpg-agent@devpod.sh"
+
+	// GPGAgentForwardingEnv is set by the client to request gpg agent forwarding for a session
+	GPGAgentForwardingEnv = "DEVPOD_GPG_AGENT_FORWARDING"
+)
+
+// gpgAgentRequested returns true if the client requested gpg agent forwarding
+func gpgAgentRequested(sess ssh.Session) bool {
+	for _, env := range sess.Environ() {
+		if env == GPGAgentForwardingEnv+"=true" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newGPGAgentListener listens on the socket gpg expects its agent at. A gpg agent running in the
+// container is stopped first, as it would otherwise answer instead of the forwarded one.
+func newGPGAgentListener() (net.Listener, string, error) {
+	_ = exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+
+	socket := gpgAgentSocket()
+	err := os.MkdirAll(filepath.Dir(socket), 0700)
+	if err != nil {
+		return nil, "", err
+	}
+
+	_ = os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return listener, socket, nil
+}
+
+// gpgAgentSocket returns the agent socket of the current user, which depends on the gpg version
+// and on whether /run/user exists
+func gpgAgentSocket() string {
+	out, err := exec.Command("gpgconf", "--list-dirs", "agent-socket").Output()
+	if err == nil && strings.TrimSpace(string(out)) != "" {
+		return strings.TrimSpace(string(out))
+	}
+
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".gnupg", "S.gpg-agent")
+}
+
+// forwardGPGAgentConnections forwards the connections to the listener to the client, similar to
+// the ssh agent forwarding
+func forwardGPGAgentConnections(listener net.Listener, sess ssh.Session) {
+	sshConn := sess.Context().Value(ssh.ContextKeyConn).(gossh.Conn)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			channel, reqs, err := sshConn.OpenChannel(GPGAgentChannelType, nil)
+			if err != nil {
+				return
+			}
+			defer channel.Close()
+			go gossh.DiscardRequests(reqs)
+
+			waitGroup := sync.WaitGroup{}
+			waitGroup.Add(2)
+			go func() {
+				defer waitGroup.Done()
+				_, _ = io.Copy(conn, channel)
+				_ = conn.(*net.UnixConn).CloseWrite()
+			}()
+			go func() {
+				defer waitGroup.Done()
+				_, _ = io.Copy(channel, conn)
+				_ = channel.CloseWrite()
+			}()
+			waitGroup.Wait()
+		}(conn)
+	}
+}
@@ -10,8 +10,20 @@ import (
 	"unsafe"
 
 	"github.com/creack/pty"
+	"github.com/gliderlabs/ssh"
 )
 
+// sessionSignals are the signals of the client that are sent to the command of the session
+var sessionSignals = map[ssh.Signal]os.Signal{
+	ssh.SIGINT:  syscall.SIGINT,
+	ssh.SIGTERM: syscall.SIGTERM,
+	ssh.SIGHUP:  syscall.SIGHUP,
+	ssh.SIGQUIT: syscall.SIGQUIT,
+	ssh.SIGUSR1: syscall.SIGUSR1,
+	ssh.SIGUSR2: syscall.SIGUSR2,
+	ssh.SIGKILL: syscall.SIGKILL,
+}
+
 func startPTY(cmd *exec.Cmd) (*os.File, error) {
 	return pty.Start(cmd)
 }
@@ -7,8 +7,15 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+
+	"github.com/gliderlabs/ssh"
 )
 
+// sessionSignals are the signals of the client that are sent to the command of the session
+var sessionSignals = map[ssh.Signal]os.Signal{
+	ssh.SIGKILL: os.Kill,
+}
+
 func startPTY(cmd *exec.Cmd) (*os.File, error) {
 	return nil, fmt.Errorf("pty is currently not supported on windows")
 }
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// restrictedShellCharacters are characters that would allow a client to chain or
+// substitute commands and are therefore rejected in restricted mode
+const restrictedShellCharacters = ";&|`$<>()\\\n"
+
+// EncodeRestrictedCommands encodes the allowed commands as base64 encoded json, so they can be
+// passed to the ssh server through any number of shells without quoting issues
+func EncodeRestrictedCommands(allowedCommands []string) (string, error) {
+	out, err := json.Marshal(allowedCommands)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// DecodeRestrictedCommands decodes the allowed commands encoded by EncodeRestrictedCommands
+func DecodeRestrictedCommands(encoded string) ([]string, error) {
+	out, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode restricted commands: %w", err)
+	}
+
+	allowedCommands := []string{}
+	err = json.Unmarshal(out, &allowedCommands)
+	if err != nil {
+		return nil, fmt.Errorf("parse restricted commands: %w", err)
+	}
+
+	return allowedCommands, nil
+}
+
+// Restrict limits the server to the given allowed commands. Interactive sessions
+// will run a restricted bash that only finds the allowed commands, sftp and port
+// forwarding are disabled. This is a coarse restriction and not a sandbox, every
+// allowed command can still do everything it is capable of.
+func (s *Server) Restrict(allowedCommands []string) {
+	s.allowedCommands = allowedCommands
+	s.sshServer.LocalPortForwardingCallback = nil
+	s.sshServer.ReversePortForwardingCallback = nil
+	delete(s.sshServer.SubsystemHandlers, "sftp")
+}
+
+func (s *Server) restricted() bool {
+	return len(s.allowedCommands) > 0
+}
+
+// checkRestrictedCommand returns the arguments of the command or an error if it is not allowed
+func (s *Server) checkRestrictedCommand(rawCommand string) ([]string, error) {
+	if strings.ContainsAny(rawCommand, restrictedShellCharacters) {
+		return nil, fmt.Errorf("command %q contains characters that are not allowed in a restricted session", rawCommand)
+	}
+
+	args, err := parseRestrictedCommand(rawCommand)
+	if err != nil {
+		return nil, err
+	} else if len(args) == 0 {
+		return nil, fmt.Errorf("empty command is not allowed in a restricted session")
+	}
+
+	for _, allowedCommand := range s.allowedCommands {
+		if args[0] == allowedCommand {
+			return args, nil
+		}
+	}
+
+	return nil, fmt.Errorf("command %s is not allowed in a restricted session, allowed commands are: %s", args[0], strings.Join(s.allowedCommands, ", "))
+}
+
+// parseRestrictedCommand splits the command into its arguments like a shell would, so quoted
+// arguments stay intact. Variables, substitutions and redirects are already rejected by
+// checkRestrictedCommand, globs and braces are passed on literally.
+func parseRestrictedCommand(rawCommand string) ([]string, error) {
+	file, err := syntax.NewParser().Parse(strings.NewReader(rawCommand), "")
+	if err != nil {
+		return nil, fmt.Errorf("parse command %q: %w", rawCommand, err)
+	} else if len(file.Stmts) == 0 {
+		return nil, nil
+	} else if len(file.Stmts) > 1 {
+		return nil, fmt.Errorf("command %q contains multiple commands, which is not allowed in a restricted session", rawCommand)
+	}
+
+	call, ok := file.Stmts[0].Cmd.(*syntax.CallExpr)
+	if !ok || len(call.Assigns) > 0 || len(file.Stmts[0].Redirs) > 0 || file.Stmts[0].Background || file.Stmts[0].Negated {
+		return nil, fmt.Errorf("command %q is not a simple command, which is required in a restricted session", rawCommand)
+	}
+
+	args := []string{}
+	for _, word := range call.Args {
+		arg, err := expand.Literal(nil, word)
+		if err != nil {
+			return nil, fmt.Errorf("parse command %q: %w", rawCommand, err)
+		}
+
+		args = append(args, arg)
+	}
+
+	return args, nil
+}
+
+// restrictedShell creates a restricted bash that only has the allowed commands in its PATH.
+// The returned function cleans up the created PATH directory.
+func (s *Server) restrictedShell(extraEnv []string) (*exec.Cmd, func(), error) {
+	bashPath, err := exec.LookPath("bash")
+	if err != nil {
+		return nil, nil, fmt.Errorf("restricted interactive sessions require bash: %w", err)
+	}
+
+	binDir, err := os.MkdirTemp("", "devpod-restricted-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(binDir)
+	}
+
+	for _, allowedCommand := range s.allowedCommands {
+		commandPath, err := exec.LookPath(allowedCommand)
+		if err != nil {
+			s.log.Debugf("Skip allowed command %s: %v", allowedCommand, err)
+			continue
+		}
+
+		err = os.Symlink(commandPath, filepath.Join(binDir, filepath.Base(allowedCommand)))
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+
+	cmd := exec.Command(bashPath, "--restricted", "--noprofile", "--norc")
+	cmd.Env = append(cmd.Env, os.Environ()...)
+	cmd.Env = append(cmd.Env, extraEnv...)
+	cmd.Env = append(cmd.Env, "PATH="+binDir, "SHELL="+bashPath)
+	return cmd, cleanup, nil
+}
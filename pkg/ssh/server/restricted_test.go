@@ -0,0 +1,46 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestEncodeRestrictedCommands(t *testing.T) {
+	allowedCommands := []string{"ls", "cat", "echo 'a,b' \"$HOME\""}
+
+	encoded, err := EncodeRestrictedCommands(allowedCommands)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.ContainsAny(encoded, "'\"$, "))
+
+	decoded, err := DecodeRestrictedCommands(encoded)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, decoded, allowedCommands)
+
+	_, err = DecodeRestrictedCommands("ls,cat")
+	assert.ErrorContains(t, err, "decode restricted commands")
+}
+
+func TestCheckRestrictedCommand(t *testing.T) {
+	server := &Server{allowedCommands: []string{"ls", "cat"}}
+
+	args, err := server.checkRestrictedCommand(`cat 'my file.txt' "other file" plain`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, args, []string{"cat", "my file.txt", "other file", "plain"})
+
+	args, err = server.checkRestrictedCommand("ls *.go {a,b}")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, args, []string{"ls", "*.go", "{a,b}"})
+
+	_, err = server.checkRestrictedCommand("'rm' -rf /")
+	assert.ErrorContains(t, err, "command rm is not allowed")
+	_, err = server.checkRestrictedCommand("ls; rm -rf /")
+	assert.ErrorContains(t, err, "characters that are not allowed")
+	_, err = server.checkRestrictedCommand("FOO=bar ls")
+	assert.ErrorContains(t, err, "not a simple command")
+	_, err = server.checkRestrictedCommand("cat 'unterminated")
+	assert.ErrorContains(t, err, "parse command")
+	_, err = server.checkRestrictedCommand("  ")
+	assert.ErrorContains(t, err, "empty command")
+}
@@ -12,6 +12,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gliderlabs/ssh"
@@ -91,10 +92,11 @@ func NewServer(addr string, hostKey []byte, keys []ssh.PublicKey, log log.Logger
 }
 
 type Server struct {
-	currentUser string
-	shell       []string
-	sshServer   ssh.Server
-	log         log.Logger
+	currentUser     string
+	shell           []string
+	allowedCommands []string
+	sshServer       ssh.Server
+	log             log.Logger
 }
 
 func getUserShell() (string, error) {
@@ -154,7 +156,18 @@ func getShell() ([]string, error) {
 func (s *Server) handler(sess ssh.Session) {
 	ptyReq, winCh, isPty := sess.Pty()
 	cmd := s.getCommand(sess, isPty)
-	if ssh.AgentRequested(sess) {
+	if s.restricted() {
+		var (
+			cleanup func()
+			err     error
+		)
+		cmd, cleanup, err = s.getRestrictedCommand(sess)
+		if err != nil {
+			s.exitWithError(sess, err)
+			return
+		}
+		defer cleanup()
+	} else if ssh.AgentRequested(sess) {
 		l, err := ssh.NewAgentListener()
 		if err != nil {
 			s.exitWithError(sess, perrors.Wrap(err, "start agent"))
@@ -165,6 +178,20 @@ func (s *Server) handler(sess ssh.Session) {
 		go ssh.ForwardAgentConnections(l, sess)
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", "SSH_AUTH_SOCK", l.Addr().String()))
 	}
+	if !s.restricted() && gpgAgentRequested(sess) {
+		l, socket, err := newGPGAgentListener()
+		if err != nil {
+			s.exitWithError(sess, perrors.Wrap(err, "start gpg agent"))
+			return
+		}
+
+		// remove the forwarded socket, so gpg doesn't find a dead agent later on
+		defer func() {
+			_ = l.Close()
+			_ = os.Remove(socket)
+		}()
+		go forwardGPGAgentConnections(l, sess)
+	}
 
 	// start shell session
 	var err error
@@ -200,6 +227,7 @@ func (s *Server) HandleNonPTY(sess ssh.Session, cmd *exec.Cmd) (err error) {
 	if err != nil {
 		return perrors.Wrap(err, "start command")
 	}
+	defer forwardSignals(sess, cmd)()
 
 	go func() {
 		defer stdin.Close()
@@ -247,6 +275,7 @@ func HandlePTY(sess ssh.Session, ptyReq ssh.Pty, winCh <-chan ssh.Window, cmd *e
 		return perrors.Wrap(err, "start pty")
 	}
 	defer f.Close()
+	defer forwardSignals(sess, cmd)()
 
 	go func() {
 		for win := range winCh {
@@ -338,6 +367,21 @@ func (s *Server) getCommand(sess ssh.Session, isPty bool) *exec.Cmd {
 	return cmd
 }
 
+func (s *Server) getRestrictedCommand(sess ssh.Session) (*exec.Cmd, func(), error) {
+	if len(sess.RawCommand()) == 0 {
+		return s.restrictedShell(sess.Environ())
+	}
+
+	args, err := s.checkRestrictedCommand(sess.RawCommand())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(cmd.Env, os.Environ()...)
+	return cmd, func() {}, nil
+}
+
 func (s *Server) exitWithError(sess ssh.Session, err error) {
 	if err != nil {
 		var exitError *exec.ExitError
@@ -394,6 +438,34 @@ func SftpHandler(sess ssh.Session, currentUser string, log log.Logger) {
 	_ = sess.Exit(1)
 }
 
+// forwardSignals sends the signals of the client to the started command until the returned func
+// is called
+func forwardSignals(sess ssh.Session, cmd *exec.Cmd) func() {
+	signals := make(chan ssh.Signal, 8)
+	sess.Signals(signals)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sshSignal := <-signals:
+				if osSignal, ok := sessionSignals[sshSignal]; ok {
+					_ = cmd.Process.Signal(osSignal)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		sess.Signals(nil)
+		close(done)
+	}
+}
+
+// ExitCode returns the exit code of the command, a command killed by a signal exits with 128 plus
+// the signal number like in a shell
 func ExitCode(err error) int {
 	err = perrors.Cause(err)
 	if err == nil {
@@ -405,6 +477,10 @@ func ExitCode(err error) int {
 		return 1
 	}
 
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+
 	return exitErr.ExitCode()
 }
 
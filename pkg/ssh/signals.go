@@ -0,0 +1,43 @@
+package ssh
+
+import (
+	"os"
+	"os/signal"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// signaler is implemented by *ssh.Session
+type signaler interface {
+	Signal(sig ssh.Signal) error
+}
+
+// ForwardSignals sends the signals DevPod receives to the remote command of the session instead of
+// terminating DevPod, so the remote command behaves as if it was run directly. The returned func
+// stops the forwarding. The terminal of an interactive session needs to be put into raw mode via
+// MakeRaw without onSignal, otherwise SIGTERM and SIGHUP also end the session.
+func ForwardSignals(session signaler) func() {
+	signals := make(chan os.Signal, 8)
+	osSignals := []os.Signal{}
+	for osSignal := range forwardedSignals {
+		osSignals = append(osSignals, osSignal)
+	}
+	signal.Notify(signals, osSignals...)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case osSignal := <-signals:
+				_ = session.Signal(forwardedSignals[osSignal])
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,109 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+const (
+	socksVersion = 5
+
+	socksCommandConnect = 1
+
+	socksAddressIPv4   = 1
+	socksAddressDomain = 3
+	socksAddressIPv6   = 4
+
+	socksReplySuccess            = 0
+	socksReplyFailure            = 1
+	socksReplyCommandUnsupported = 7
+)
+
+// socksHandshake negotiates a SOCKS5 connection without authentication and returns the address
+// of the CONNECT request. Other commands are rejected.
+func socksHandshake(conn io.ReadWriter) (string, error) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		return "", err
+	} else if header[0] != socksVersion {
+		return "", fmt.Errorf("unsupported socks version %d", header[0])
+	}
+
+	methods := make([]byte, header[1])
+	_, err = io.ReadFull(conn, methods)
+	if err != nil {
+		return "", err
+	}
+
+	// we only support no authentication
+	noAuth := false
+	for _, method := range methods {
+		noAuth = noAuth || method == 0
+	}
+	if !noAuth {
+		_, _ = conn.Write([]byte{socksVersion, 0xff})
+		return "", fmt.Errorf("socks client doesn't support connecting without authentication")
+	}
+	_, err = conn.Write([]byte{socksVersion, 0})
+	if err != nil {
+		return "", err
+	}
+
+	request := make([]byte, 4)
+	_, err = io.ReadFull(conn, request)
+	if err != nil {
+		return "", err
+	} else if request[1] != socksCommandConnect {
+		_ = socksReply(conn, socksReplyCommandUnsupported)
+		return "", fmt.Errorf("unsupported socks command %d", request[1])
+	}
+
+	var host string
+	switch request[3] {
+	case socksAddressIPv4, socksAddressIPv6:
+		ip := make([]byte, net.IPv4len)
+		if request[3] == socksAddressIPv6 {
+			ip = make([]byte, net.IPv6len)
+		}
+		_, err = io.ReadFull(conn, ip)
+		if err != nil {
+			return "", err
+		}
+
+		host = net.IP(ip).String()
+	case socksAddressDomain:
+		length := make([]byte, 1)
+		_, err = io.ReadFull(conn, length)
+		if err != nil {
+			return "", err
+		}
+
+		domain := make([]byte, length[0])
+		_, err = io.ReadFull(conn, domain)
+		if err != nil {
+			return "", err
+		}
+
+		host = string(domain)
+	default:
+		_ = socksReply(conn, socksReplyFailure)
+		return "", fmt.Errorf("unsupported socks address type %d", request[3])
+	}
+
+	port := make([]byte, 2)
+	_, err = io.ReadFull(conn, port)
+	if err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1]))), nil
+}
+
+// socksReply answers a CONNECT request, the bound address is not reported
+func socksReply(conn io.Writer, reply byte) error {
+	_, err := conn.Write([]byte{socksVersion, reply, 0, socksAddressIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
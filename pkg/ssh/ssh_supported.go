@@ -5,11 +5,24 @@ package ssh
 import (
 	"context"
 	"os"
+	"os/exec"
 	"os/signal"
+	"syscall"
 
+	"golang.org/x/crypto/ssh"
 	"golang.org/x/sys/unix"
 )
 
+// forwardedSignals are the signals ForwardSignals sends to the remote command
+var forwardedSignals = map[os.Signal]ssh.Signal{
+	unix.SIGINT:  ssh.SIGINT,
+	unix.SIGTERM: ssh.SIGTERM,
+	unix.SIGHUP:  ssh.SIGHUP,
+	unix.SIGQUIT: ssh.SIGQUIT,
+	unix.SIGUSR1: ssh.SIGUSR1,
+	unix.SIGUSR2: ssh.SIGUSR2,
+}
+
 func WatchWindowSize(ctx context.Context) <-chan os.Signal {
 	windowSize := make(chan os.Signal, 1)
 	signal.Notify(windowSize, unix.SIGWINCH)
@@ -19,3 +32,14 @@ func WatchWindowSize(ctx context.Context) <-chan os.Signal {
 	}()
 	return windowSize
 }
+
+// DetachProcess starts the command in a new session, so it keeps running when the terminal of
+// the parent is closed
+func DetachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// stopProcess asks the process to terminate
+func stopProcess(process *os.Process) error {
+	return process.Signal(syscall.SIGTERM)
+}
@@ -5,9 +5,18 @@ package ssh
 import (
 	"context"
 	"os"
+	"os/exec"
+	"syscall"
 	"time"
+
+	"golang.org/x/crypto/ssh"
 )
 
+// forwardedSignals are the signals ForwardSignals sends to the remote command
+var forwardedSignals = map[os.Signal]ssh.Signal{
+	os.Interrupt: ssh.SIGINT,
+}
+
 func WatchWindowSize(ctx context.Context) <-chan os.Signal {
 	windowSize := make(chan os.Signal, 3)
 	ticker := time.NewTicker(time.Second)
@@ -24,3 +33,14 @@ func WatchWindowSize(ctx context.Context) <-chan os.Signal {
 	}()
 	return windowSize
 }
+
+// DetachProcess starts the command in a new process group, so it doesn't receive the ctrl-c of
+// the parent
+func DetachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// stopProcess kills the process, windows doesn't support sending it a signal
+func stopProcess(process *os.Process) error {
+	return process.Kill()
+}
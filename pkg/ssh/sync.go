@@ -0,0 +1,244 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/loft-sh/log"
+	"github.com/pkg/sftp"
+)
+
+// syncProgressInterval is how often a running sync logs its progress
+const syncProgressInterval = time.Second
+
+// SyncSpec is a one-shot copy between a path in the workspace and a local path
+type SyncSpec struct {
+	Remote string
+	Local  string
+}
+
+// ParseSyncSpec parses the <remote path>:<local path> of --sync-down and --sync-up. The value is
+// split at the first colon, since remote paths in the container rarely contain one but local
+// paths on Windows do.
+func ParseSyncSpec(value string) (SyncSpec, error) {
+	remote, local, ok := strings.Cut(value, ":")
+	if !ok || remote == "" || local == "" {
+		return SyncSpec{}, fmt.Errorf("invalid sync %q, expected <remote path>:<local path>", value)
+	}
+
+	return SyncSpec{Remote: remote, Local: local}, nil
+}
+
+// SyncStats holds the progress of a sync
+type SyncStats struct {
+	Files   int
+	Skipped int
+	Bytes   int64
+}
+
+func (s SyncStats) String() string {
+	return fmt.Sprintf("%d files (%s), %d unchanged", s.Files, units.HumanSize(float64(s.Bytes)), s.Skipped)
+}
+
+// syncFS is the part of a file system a sync reads from or writes to
+type syncFS interface {
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.FileInfo, error)
+	Open(name string) (io.ReadCloser, error)
+	Create(name string, mode os.FileMode) (io.WriteCloser, error)
+	MkdirAll(name string) error
+	Chtimes(name string, modTime time.Time) error
+	Join(elem ...string) string
+}
+
+// SyncDown copies the remote path recursively to the local path
+func SyncDown(client *sftp.Client, spec SyncSpec, log log.Logger) (SyncStats, error) {
+	return syncTree(sftpFS{client: client}, spec.Remote, localFS{}, spec.Local, log)
+}
+
+// SyncUp copies the local path recursively to the remote path
+func SyncUp(client *sftp.Client, spec SyncSpec, log log.Logger) (SyncStats, error) {
+	return syncTree(localFS{}, spec.Local, sftpFS{client: client}, spec.Remote, log)
+}
+
+// syncTree copies src to dst file by file while walking the tree, so large trees are neither
+// listed nor buffered up front. Files with the same size and modification time at the
+// destination are skipped, which makes repeated syncs of the same tree cheap. Modification times
+// are compared in seconds, since sftp doesn't transfer more.
+func syncTree(srcFS syncFS, src string, dstFS syncFS, dst string, log log.Logger) (SyncStats, error) {
+	stats := SyncStats{}
+	info, err := srcFS.Stat(src)
+	if err != nil {
+		return stats, err
+	}
+
+	lastProgress := time.Now()
+	var copyPath func(src, dst string, info os.FileInfo) error
+	copyPath = func(src, dst string, info os.FileInfo) error {
+		switch {
+		case info.IsDir():
+			err := dstFS.MkdirAll(dst)
+			if err != nil {
+				return fmt.Errorf("create directory %s: %w", dst, err)
+			}
+
+			entries, err := srcFS.ReadDir(src)
+			if err != nil {
+				return fmt.Errorf("read directory %s: %w", src, err)
+			}
+			for _, entry := range entries {
+				err = copyPath(srcFS.Join(src, entry.Name()), dstFS.Join(dst, entry.Name()), entry)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		case info.Mode().IsRegular():
+			dstInfo, err := dstFS.Stat(dst)
+			if err == nil && dstInfo.Mode().IsRegular() && dstInfo.Size() == info.Size() && dstInfo.ModTime().Unix() == info.ModTime().Unix() {
+				stats.Skipped++
+				return nil
+			}
+
+			err = syncFile(srcFS, src, dstFS, dst, info)
+			if err != nil {
+				return err
+			}
+
+			stats.Files++
+			stats.Bytes += info.Size()
+			if time.Since(lastProgress) >= syncProgressInterval {
+				log.Infof("Synced %s", stats)
+				lastProgress = time.Now()
+			}
+
+			return nil
+		default:
+			log.Debugf("Skip syncing %s, it's neither a file nor a directory", src)
+			return nil
+		}
+	}
+
+	return stats, copyPath(src, dst, info)
+}
+
+func syncFile(srcFS syncFS, src string, dstFS syncFS, dst string, info os.FileInfo) error {
+	reader, err := srcFS.Open(src)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", src, err)
+	}
+	defer reader.Close()
+
+	writer, err := dstFS.Create(dst, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("create %s: %w", dst, err)
+	}
+
+	_, err = io.Copy(writer, reader)
+	if err != nil {
+		_ = writer.Close()
+		return fmt.Errorf("copy %s: %w", src, err)
+	}
+	err = writer.Close()
+	if err != nil {
+		return fmt.Errorf("write %s: %w", dst, err)
+	}
+
+	// keep the modification time, so the next sync can skip the file
+	return dstFS.Chtimes(dst, info.ModTime())
+}
+
+type localFS struct{}
+
+func (localFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (localFS) ReadDir(name string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []os.FileInfo{}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+func (localFS) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (localFS) Create(name string, mode os.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+}
+
+func (localFS) MkdirAll(name string) error {
+	return os.MkdirAll(name, 0755)
+}
+
+func (localFS) Chtimes(name string, modTime time.Time) error {
+	return os.Chtimes(name, modTime, modTime)
+}
+
+func (localFS) Join(elem ...string) string {
+	return filepath.Join(elem...)
+}
+
+type sftpFS struct {
+	client *sftp.Client
+}
+
+func (s sftpFS) Stat(name string) (os.FileInfo, error) {
+	return s.client.Stat(name)
+}
+
+func (s sftpFS) ReadDir(name string) ([]os.FileInfo, error) {
+	return s.client.ReadDir(name)
+}
+
+func (s sftpFS) Open(name string) (io.ReadCloser, error) {
+	return s.client.Open(name)
+}
+
+func (s sftpFS) Create(name string, mode os.FileMode) (io.WriteCloser, error) {
+	file, err := s.client.Create(name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = file.Chmod(mode)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (s sftpFS) MkdirAll(name string) error {
+	return s.client.MkdirAll(name)
+}
+
+func (s sftpFS) Chtimes(name string, modTime time.Time) error {
+	return s.client.Chtimes(name, modTime, modTime)
+}
+
+func (s sftpFS) Join(elem ...string) string {
+	return path.Join(elem...)
+}
@@ -0,0 +1,49 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/loft-sh/log"
+	"gotest.tools/assert"
+)
+
+func TestParseSyncSpec(t *testing.T) {
+	spec, err := ParseSyncSpec("/workspace/build:C:\\build")
+	assert.NilError(t, err)
+	assert.Equal(t, spec, SyncSpec{Remote: "/workspace/build", Local: "C:\\build"})
+
+	_, err = ParseSyncSpec("/workspace/build")
+	assert.ErrorContains(t, err, "expected <remote path>:<local path>")
+	_, err = ParseSyncSpec(":./build")
+	assert.ErrorContains(t, err, "expected <remote path>:<local path>")
+}
+
+func TestSyncTree(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "out")
+	assert.NilError(t, os.MkdirAll(filepath.Join(src, "bin", "nested"), 0755))
+	assert.NilError(t, os.WriteFile(filepath.Join(src, "bin", "app"), []byte("binary"), 0755))
+	assert.NilError(t, os.WriteFile(filepath.Join(src, "bin", "nested", "lib"), []byte("library"), 0644))
+
+	stats, err := syncTree(localFS{}, src, localFS{}, dst, log.Discard)
+	assert.NilError(t, err)
+	assert.Equal(t, stats, SyncStats{Files: 2, Bytes: 13})
+
+	content, err := os.ReadFile(filepath.Join(dst, "bin", "nested", "lib"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "library")
+	info, err := os.Stat(filepath.Join(dst, "bin", "app"))
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0755))
+
+	// unchanged files are skipped on the next sync
+	assert.NilError(t, os.WriteFile(filepath.Join(src, "bin", "app"), []byte("binary v2"), 0755))
+	stats, err = syncTree(localFS{}, src, localFS{}, dst, log.Discard)
+	assert.NilError(t, err)
+	assert.Equal(t, stats, SyncStats{Files: 1, Skipped: 1, Bytes: 9})
+	content, err = os.ReadFile(filepath.Join(dst, "bin", "app"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "binary v2")
+}
@@ -0,0 +1,68 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// SignalError is returned if a session ended because DevPod received a terminating signal
+type SignalError struct {
+	Signal syscall.Signal
+}
+
+func (e *SignalError) Error() string {
+	return fmt.Sprintf("session terminated by signal %s", e.Signal)
+}
+
+// ExitCode returns the exit code of a process terminated by the signal
+func (e *SignalError) ExitCode() int {
+	return 128 + int(e.Signal)
+}
+
+// MakeRaw puts the terminal into raw mode and returns a func that restores it. If onSignal is not
+// nil and the process receives SIGTERM or SIGHUP while in raw mode, the terminal is restored and
+// onSignal is called instead of terminating the process, so the caller can end the session and
+// run its cleanup. Without it, the terminal of the user would be left without echo and line
+// wrapping. Pass nil if the caller handles these signals itself.
+func MakeRaw(fd int, onSignal func(sig syscall.Signal)) (func(), error) {
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	restoreOnce := sync.Once{}
+	restore := func() {
+		restoreOnce.Do(func() {
+			_ = term.Restore(fd, state)
+		})
+	}
+	if onSignal == nil {
+		return restore, nil
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			restore()
+			onSignal(sig.(syscall.Signal))
+		case <-done:
+		}
+	}()
+
+	stopOnce := sync.Once{}
+	return func() {
+		stopOnce.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+		restore()
+	}, nil
+}
@@ -0,0 +1,117 @@
+//go:build linux
+
+package ssh
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/sys/unix"
+	"gotest.tools/assert"
+)
+
+func TestMakeRawRestoresOnPanic(t *testing.T) {
+	ptmx, tty, err := pty.Open()
+	assert.NilError(t, err)
+	defer ptmx.Close()
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	assert.Assert(t, echoEnabled(t, fd))
+
+	// simulate a session that fails while the terminal is in raw mode
+	func() {
+		defer func() {
+			_ = recover()
+		}()
+
+		restore, err := MakeRaw(fd, nil)
+		assert.NilError(t, err)
+		defer restore()
+
+		assert.Assert(t, !echoEnabled(t, fd))
+		panic("session failed")
+	}()
+
+	assert.Assert(t, echoEnabled(t, fd))
+}
+
+func TestMakeRawRunsCleanupOnSignal(t *testing.T) {
+	ptmx, tty, err := pty.Open()
+	assert.NilError(t, err)
+	defer ptmx.Close()
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	cleanedUp := false
+	var terminatedBy syscall.Signal
+
+	// simulate a session that ends when DevPod is terminated while the terminal is in raw mode
+	func() {
+		defer func() {
+			cleanedUp = true
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		restore, err := MakeRaw(fd, func(sig syscall.Signal) {
+			terminatedBy = sig
+			cancel()
+		})
+		assert.NilError(t, err)
+		defer restore()
+
+		assert.NilError(t, syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+		<-ctx.Done()
+		assert.Assert(t, echoEnabled(t, fd))
+	}()
+
+	assert.Assert(t, cleanedUp)
+	assert.Equal(t, terminatedBy, syscall.SIGHUP)
+	assert.Equal(t, (&SignalError{Signal: terminatedBy}).ExitCode(), 129)
+}
+
+type fakeSession struct {
+	signals chan ssh.Signal
+}
+
+func (s *fakeSession) Signal(sig ssh.Signal) error {
+	s.signals <- sig
+	return nil
+}
+
+func TestMakeRawWithForwardedSignals(t *testing.T) {
+	ptmx, tty, err := pty.Open()
+	assert.NilError(t, err)
+	defer ptmx.Close()
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	restore, err := MakeRaw(fd, nil)
+	assert.NilError(t, err)
+	defer restore()
+
+	session := &fakeSession{signals: make(chan ssh.Signal, 1)}
+	stop := ForwardSignals(session)
+	defer stop()
+
+	// the signal only reaches the remote command, the session and terminal stay as they are
+	assert.NilError(t, syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+	select {
+	case sig := <-session.signals:
+		assert.Equal(t, sig, ssh.SIGHUP)
+	case <-time.After(10 * time.Second):
+		t.Fatal("signal wasn't forwarded")
+	}
+	assert.Assert(t, !echoEnabled(t, fd))
+}
+
+func echoEnabled(t *testing.T, fd int) bool {
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	assert.NilError(t, err)
+	return termios.Lflag&unix.ECHO != 0
+}
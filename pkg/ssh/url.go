@@ -0,0 +1,78 @@
+package ssh
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ConnectionURLScheme is the scheme of urls that describe a devpod ssh connection,
+// e.g. devpod://user@workspace?command=ls&env=FOO%3Dbar
+const ConnectionURLScheme = "devpod"
+
+// ConnectionURL is a parsed devpod ssh connection url
+type ConnectionURL struct {
+	// Workspace is the name of the workspace to connect to
+	Workspace string
+
+	// User is the user to connect as
+	User string
+
+	// Commands are the commands to run in the order of the url
+	Commands []string
+
+	// Env are the environment variables in the form KEY=VALUE
+	Env []string
+}
+
+// IsConnectionURL returns true if the argument looks like a devpod ssh connection url
+func IsConnectionURL(arg string) bool {
+	return strings.HasPrefix(arg, ConnectionURLScheme+"://")
+}
+
+// ParseConnectionURL parses a devpod ssh connection url and fails on unknown query parameters
+func ParseConnectionURL(rawURL string) (*ConnectionURL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse connection url: %w", err)
+	} else if u.Scheme != ConnectionURLScheme {
+		return nil, fmt.Errorf("connection url needs to start with %s://", ConnectionURLScheme)
+	} else if u.Hostname() == "" {
+		return nil, fmt.Errorf("connection url %s is missing the workspace", rawURL)
+	} else if u.Port() != "" || (u.Path != "" && u.Path != "/") || u.Fragment != "" {
+		return nil, fmt.Errorf("connection url %s can only contain a user, workspace and query parameters", rawURL)
+	}
+
+	connectionURL := &ConnectionURL{
+		Workspace: u.Hostname(),
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return nil, fmt.Errorf("connection url %s cannot contain a password", rawURL)
+		}
+
+		connectionURL.User = u.User.Username()
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("parse connection url query: %w", err)
+	}
+	for key, values := range query {
+		switch key {
+		case "command":
+			connectionURL.Commands = append(connectionURL.Commands, values...)
+		case "env":
+			for _, value := range values {
+				if !strings.Contains(value, "=") {
+					return nil, fmt.Errorf("env %s in connection url needs to be in the form KEY=VALUE", value)
+				}
+			}
+			connectionURL.Env = append(connectionURL.Env, values...)
+		default:
+			return nil, fmt.Errorf("unknown parameter %s in connection url, supported are: command, env", key)
+		}
+	}
+
+	return connectionURL, nil
+}
@@ -0,0 +1,250 @@
+package ssh
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/loft-sh/devpod/pkg/websocket"
+	"github.com/loft-sh/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// The types of web terminal messages
+const (
+	// WebTerminalInput is sent by the front-end with the input in data
+	WebTerminalInput = "input"
+	// WebTerminalResize is sent by the front-end with the new cols and rows of the terminal
+	WebTerminalResize = "resize"
+	// WebTerminalExit is sent by DevPod with the exit code once the session ended
+	WebTerminalExit = "exit"
+)
+
+// WebTerminalProtocol is the WebSocket subprotocol of the web terminal. The front-end offers it
+// together with the token as second subprotocol, e.g. new WebSocket(url, ["devpod-terminal", token]).
+const WebTerminalProtocol = "devpod-terminal"
+
+// WebTerminalMessage is a text message between DevPod and the front-end of a web terminal. The
+// output of the terminal is sent as binary messages instead.
+type WebTerminalMessage struct {
+	Type string `json:"type"`
+	Data string `json:"data,omitempty"`
+	Cols int    `json:"cols,omitempty"`
+	Rows int    `json:"rows,omitempty"`
+	Code int    `json:"code"`
+}
+
+// ServeWebTerminal serves the session as terminal to the first WebSocket connection on the
+// listener that presents the token as subprotocol next to WebTerminalProtocol, the token is
+// invalid afterwards. Connections from browsers are only accepted from the same origin or one
+// of allowedOrigins. The session runs the command or a shell if it is empty, it returns once the
+// session ended.
+func ServeWebTerminal(ctx context.Context, listener net.Listener, token string, allowedOrigins []string, session *ssh.Session, command string, log log.Logger) error {
+	used := atomic.Bool{}
+	done := make(chan error, 1)
+	finish := func(err error) {
+		select {
+		case done <- err:
+		default:
+		}
+	}
+	server := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := authorizeWebTerminal(r, token, allowedOrigins)
+			if err != nil {
+				log.Debugf("Rejected web terminal connection from %s: %v", r.RemoteAddr, err)
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			} else if !used.CompareAndSwap(false, true) {
+				http.Error(w, "token was already used", http.StatusForbidden)
+				return
+			}
+
+			conn, err := websocket.Upgrade(w, r, WebTerminalProtocol)
+			if err != nil {
+				log.Debugf("Error upgrading web terminal connection: %v", err)
+				used.Store(false)
+				return
+			}
+
+			log.Debugf("Web terminal connected from %s", r.RemoteAddr)
+			finish(runWebTerminal(conn, session, command, log))
+		}),
+	}
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			finish(err)
+		}
+	}()
+	defer server.Close()
+
+	select {
+	case <-ctx.Done():
+		_ = session.Close()
+		return nil
+	case err := <-done:
+		return err
+	}
+}
+
+// authorizeWebTerminal checks the origin and the token of a web terminal connection. Requests
+// without origin come from clients other than browsers, which don't need the check.
+func authorizeWebTerminal(r *http.Request, token string, allowedOrigins []string) error {
+	origin := r.Header.Get("Origin")
+	if origin != "" && !webTerminalOriginAllowed(origin, r.Host, allowedOrigins) {
+		return fmt.Errorf("origin %s is not allowed", origin)
+	}
+
+	protocols := websocket.Protocols(r)
+	hasProtocol := false
+	hasToken := false
+	for _, protocol := range protocols {
+		if protocol == WebTerminalProtocol {
+			hasProtocol = true
+		} else if subtle.ConstantTimeCompare([]byte(protocol), []byte(token)) == 1 {
+			hasToken = true
+		}
+	}
+	if !hasProtocol {
+		return fmt.Errorf("expected subprotocol %s", WebTerminalProtocol)
+	} else if !hasToken {
+		return fmt.Errorf("invalid token")
+	}
+
+	return nil
+}
+
+// webTerminalOriginAllowed returns true if the origin is the one of the web terminal itself or
+// one of the allowed origins
+func webTerminalOriginAllowed(origin, host string, allowedOrigins []string) bool {
+	for _, allowed := range allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, host)
+}
+
+func runWebTerminal(conn *websocket.Conn, session *ssh.Session, command string, log log.Logger) error {
+	defer conn.Close()
+
+	err := session.RequestPty("xterm-256color", 24, 80, ssh.TerminalModes{})
+	if err != nil {
+		return err
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+	output := &webTerminalWriter{conn: conn}
+	session.Stdout = output
+	session.Stderr = output
+	if command == "" {
+		err = session.Shell()
+	} else {
+		err = session.Start(command)
+	}
+	if err != nil {
+		return err
+	}
+
+	// forward input and resizes until the front-end disconnects
+	go func() {
+		defer session.Close()
+
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				log.Debugf("Web terminal disconnected: %v", err)
+				return
+			} else if messageType == websocket.BinaryMessage {
+				_, err = stdin.Write(data)
+				if err != nil {
+					return
+				}
+				continue
+			}
+
+			message := WebTerminalMessage{}
+			err = json.Unmarshal(data, &message)
+			if err != nil {
+				log.Debugf("Error parsing web terminal message: %v", err)
+				continue
+			}
+
+			switch message.Type {
+			case WebTerminalInput:
+				_, err = stdin.Write([]byte(message.Data))
+				if err != nil {
+					return
+				}
+			case WebTerminalResize:
+				if message.Cols > 0 && message.Rows > 0 {
+					_ = session.WindowChange(message.Rows, message.Cols)
+				}
+			default:
+				log.Debugf("Unknown web terminal message type %s", message.Type)
+			}
+		}
+	}()
+
+	err = session.Wait()
+	code := 0
+	exitErr := &ssh.ExitError{}
+	if errors.As(err, &exitErr) {
+		code = exitErr.ExitStatus()
+	} else if err != nil {
+		code = -1
+	}
+
+	exit, _ := json.Marshal(WebTerminalMessage{Type: WebTerminalExit, Code: code})
+	_ = conn.WriteMessage(websocket.TextMessage, exit)
+	_ = conn.WriteMessage(websocket.CloseMessage, []byte{0x03, 0xe8})
+	return err
+}
+
+// webTerminalWriter sends the output of the terminal as binary messages
+type webTerminalWriter struct {
+	conn *websocket.Conn
+}
+
+func (w *webTerminalWriter) Write(p []byte) (int, error) {
+	err := w.conn.WriteMessage(websocket.BinaryMessage, p)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// ParseWebTerminalAddress validates that the address only listens on the loopback interface
+func ParseWebTerminalAddress(address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %s, expected host:port: %w", address, err)
+	}
+
+	if host == "" {
+		host = "127.0.0.1"
+	} else if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return "", fmt.Errorf("the web terminal can only listen on localhost, got %s", host)
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
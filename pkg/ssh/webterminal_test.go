@@ -0,0 +1,56 @@
+package ssh
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseWebTerminalAddress(t *testing.T) {
+	address, err := ParseWebTerminalAddress(":8022")
+	assert.NilError(t, err)
+	assert.Equal(t, address, "127.0.0.1:8022")
+
+	address, err = ParseWebTerminalAddress("localhost:0")
+	assert.NilError(t, err)
+	assert.Equal(t, address, "localhost:0")
+
+	address, err = ParseWebTerminalAddress("[::1]:8022")
+	assert.NilError(t, err)
+	assert.Equal(t, address, "[::1]:8022")
+
+	_, err = ParseWebTerminalAddress("0.0.0.0:8022")
+	assert.ErrorContains(t, err, "can only listen on localhost")
+
+	_, err = ParseWebTerminalAddress("8022")
+	assert.ErrorContains(t, err, "expected host:port")
+}
+
+func TestAuthorizeWebTerminal(t *testing.T) {
+	request := func(origin string, protocols string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8022/", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if protocols != "" {
+			r.Header.Set("Sec-WebSocket-Protocol", protocols)
+		}
+		return r
+	}
+
+	assert.NilError(t, authorizeWebTerminal(request("", "devpod-terminal, secret"), "secret", nil))
+	assert.NilError(t, authorizeWebTerminal(request("http://localhost:8022", "devpod-terminal, secret"), "secret", nil))
+	assert.NilError(t, authorizeWebTerminal(request("http://localhost:3000", "devpod-terminal, secret"), "secret", []string{"http://localhost:3000/"}))
+
+	assert.ErrorContains(t, authorizeWebTerminal(request("https://evil.example.com", "devpod-terminal, secret"), "secret", nil), "origin https://evil.example.com is not allowed")
+	assert.ErrorContains(t, authorizeWebTerminal(request("http://localhost:3000", "devpod-terminal, secret"), "secret", nil), "is not allowed")
+	assert.ErrorContains(t, authorizeWebTerminal(request("", "devpod-terminal, other"), "secret", nil), "invalid token")
+	assert.ErrorContains(t, authorizeWebTerminal(request("", "secret"), "secret", nil), "expected subprotocol devpod-terminal")
+
+	// the token in the query is not accepted anymore
+	r := request("", "devpod-terminal")
+	r.URL.RawQuery = "token=secret"
+	assert.ErrorContains(t, authorizeWebTerminal(r, "secret", nil), "invalid token")
+}
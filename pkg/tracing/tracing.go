@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/loft-sh/devpod/pkg/version"
+	"github.com/loft-sh/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// EndpointEnv is the standard OpenTelemetry env var for the OTLP endpoint, /v1/traces is appended
+	EndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+
+	// TracesEndpointEnv is the standard OpenTelemetry env var for the full OTLP traces url
+	TracesEndpointEnv = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+
+	// HeadersEnv holds additional headers as comma separated KEY=VALUE pairs, e.g. for authentication
+	HeadersEnv = "OTEL_EXPORTER_OTLP_HEADERS"
+
+	// TraceParentEnv holds the W3C traceparent of the calling process to continue its trace
+	TraceParentEnv = "TRACEPARENT"
+
+	// TraceStateEnv holds the W3C tracestate of the calling process
+	TraceStateEnv = "TRACESTATE"
+)
+
+const tracerName = "github.com/loft-sh/devpod"
+
+// flushTimeout is the maximum time to wait for the spans to be exported on exit
+const flushTimeout = 5 * time.Second
+
+// Start exports spans via OTLP/HTTP if an endpoint is configured and returns a context that
+// continues the trace from TRACEPARENT. Without an endpoint nothing is configured and spans are
+// no-ops. The returned func exports the remaining spans and needs to be called before exiting.
+func Start(ctx context.Context, log log.Logger) (context.Context, func()) {
+	endpoint := tracesEndpoint()
+	if endpoint == "" {
+		return ctx, func() {}
+	}
+
+	exporter, err := otlptrace.New(ctx, newHTTPClient(endpoint, parseHeaders(os.Getenv(HeadersEnv))))
+	if err != nil {
+		log.Debugf("Error creating trace exporter: %v", err)
+		return ctx, func() {}
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String("devpod"),
+			semconv.ServiceVersionKey.String(version.GetVersion()),
+		)),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Debugf("Error exporting traces: %v", err)
+	}))
+
+	ctx = propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{
+		"traceparent": os.Getenv(TraceParentEnv),
+		"tracestate":  os.Getenv(TraceStateEnv),
+	})
+	return ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+
+		err := tracerProvider.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Debugf("Error flushing traces: %v", err)
+		}
+	}
+}
+
+// StartSpan starts a new span as child of the span in the context, the span needs to be ended
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// EndSpan records the error if there is one and ends the span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+func tracesEndpoint() string {
+	if endpoint := os.Getenv(TracesEndpointEnv); endpoint != "" {
+		return endpoint
+	} else if endpoint := os.Getenv(EndpointEnv); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+
+	return ""
+}
+
+func parseHeaders(headers string) map[string]string {
+	parsed := map[string]string{}
+	for _, header := range strings.Split(headers, ",") {
+		key, value, found := strings.Cut(header, "=")
+		if found && strings.TrimSpace(key) != "" {
+			parsed[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	return parsed
+}
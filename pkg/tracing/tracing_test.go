@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/loft-sh/log"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+	"gotest.tools/assert"
+)
+
+func TestStartExportsSpans(t *testing.T) {
+	requests := make(chan *coltracepb.ExportTraceServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/v1/traces")
+		assert.Equal(t, r.Header.Get("Authorization"), "token")
+
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+		request := &coltracepb.ExportTraceServiceRequest{}
+		assert.NilError(t, proto.Unmarshal(body, request))
+		requests <- request
+	}))
+	defer server.Close()
+
+	t.Setenv(EndpointEnv, server.URL)
+	t.Setenv(HeadersEnv, "Authorization=token")
+	t.Setenv(TraceParentEnv, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	ctx, flush := Start(context.Background(), log.Discard)
+	_, span := StartSpan(ctx, "connect")
+	span.End()
+	flush()
+
+	request := <-requests
+	spans := request.ResourceSpans[0].InstrumentationLibrarySpans[0].Spans
+	assert.Equal(t, len(spans), 1)
+	assert.Equal(t, spans[0].Name, "connect")
+	assert.DeepEqual(t, spans[0].TraceId, []byte{0x0a, 0xf7, 0x65, 0x19, 0x16, 0xcd, 0x43, 0xdd, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c})
+}
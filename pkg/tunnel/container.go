@@ -6,24 +6,31 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/loft-sh/devpod/pkg/agent"
 	"github.com/loft-sh/devpod/pkg/client"
+	"github.com/loft-sh/devpod/pkg/inject"
 	"github.com/loft-sh/devpod/pkg/provider"
 	devssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/devpod/pkg/tracing"
+	"github.com/loft-sh/devpod/pkg/version"
 	"github.com/loft-sh/log"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/ssh"
 )
 
-func NewContainerTunnel(client client.WorkspaceClient, proxy bool, log log.Logger) *ContainerHandler {
+// NewContainerTunnel creates a new container tunnel. If autoInstallAgent is true, a missing or
+// outdated agent is installed on the host before connecting, otherwise connecting fails.
+func NewContainerTunnel(client client.WorkspaceClient, proxy, autoInstallAgent bool, log log.Logger) *ContainerHandler {
 	updateConfigInterval := time.Second * 30
 	return &ContainerHandler{
 		client:               client,
 		updateConfigInterval: updateConfigInterval,
 		proxy:                proxy,
+		autoInstallAgent:     autoInstallAgent,
 		log:                  log,
 	}
 }
@@ -32,16 +39,59 @@ type ContainerHandler struct {
 	client               client.WorkspaceClient
 	updateConfigInterval time.Duration
 	proxy                bool
+	autoInstallAgent     bool
 	log                  log.Logger
 }
 
 type Handler func(ctx context.Context, containerClient *ssh.Client) error
 
+// RawAgentProtocolVersion is the version of the protocol the agent speaks on the raw channel of
+// RunRaw. Version 1 is SSH-2.0 without authentication, served by 'devpod helper ssh-server --stdio'
+// in the container.
+const RawAgentProtocolVersion = 1
+
 func (c *ContainerHandler) Run(ctx context.Context, handler Handler) error {
 	if handler == nil {
 		return nil
 	}
 
+	return c.runOnHost(ctx, func(ctx context.Context, sshClient *ssh.Client) error {
+		// update workspace remotely
+		if !c.proxy && c.updateConfigInterval > 0 {
+			go func() {
+				c.updateConfig(ctx, sshClient)
+			}()
+		}
+
+		// wait until we are done
+		return errors.Wrap(c.runRunInContainer(ctx, sshClient, handler), "run in container")
+	})
+}
+
+// RunRaw connects stdin and stdout directly to the agent endpoint in the container as the given
+// user, without an ssh session of DevPod in between. The endpoint speaks the protocol of
+// RawAgentProtocolVersion.
+func (c *ContainerHandler) RunRaw(ctx context.Context, user string, stdin io.Reader, stdout io.Writer) error {
+	return c.runOnHost(ctx, func(ctx context.Context, sshClient *ssh.Client) error {
+		workspaceInfo, _, err := c.client.AgentInfoContext(ctx, provider.CLIOptions{Proxy: c.proxy})
+		if err != nil {
+			return errors.Wrap(err, "agent info")
+		}
+
+		writer := c.log.Writer(logrus.InfoLevel, false)
+		defer writer.Close()
+
+		command := fmt.Sprintf("'%s' agent container-tunnel --workspace-info '%s'", c.client.AgentPath(), workspaceInfo)
+		if user != "" {
+			command += fmt.Sprintf(" --user '%s'", user)
+		}
+		c.log.Debugf("Run raw agent tunnel with protocol version %d", RawAgentProtocolVersion)
+		return devssh.Run(ctx, sshClient, command, stdin, stdout, writer)
+	})
+}
+
+// runOnHost connects to the agent on the host and runs the handler with the ssh client
+func (c *ContainerHandler) runOnHost(ctx context.Context, handler Handler) error {
 	// create context
 	cancelCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -69,21 +119,29 @@ func (c *ContainerHandler) Run(ctx context.Context, handler Handler) error {
 		if c.log.GetLevel() == logrus.DebugLevel {
 			command += " --debug"
 		}
-		tunnelChan <- agent.InjectAgentAndExecute(cancelCtx, func(ctx context.Context, command string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+		exec := func(ctx context.Context, command string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 			return c.client.Command(ctx, client.CommandOptions{
 				Command: command,
 				Stdin:   stdin,
 				Stdout:  stdout,
 				Stderr:  stderr,
 			})
-		}, c.client.AgentLocal(), c.client.AgentPath(), c.client.AgentURL(), true, command, stdinReader, stdoutWriter, writer, c.log.ErrorStreamOnly())
+		}
+		if !c.autoInstallAgent && !c.client.AgentLocal() {
+			tunnelChan <- c.executeWithInstalledAgent(cancelCtx, exec, command, stdinReader, stdoutWriter, writer)
+			return
+		}
+
+		tunnelChan <- agent.InjectAgentAndExecute(cancelCtx, exec, c.client.AgentLocal(), c.client.AgentPath(), c.client.AgentURL(), true, command, stdinReader, stdoutWriter, writer, c.log.ErrorStreamOnly())
 	}()
 
 	// connect to container
 	containerChan := make(chan error, 1)
 	go func() {
 		// start ssh client as root / default user
+		_, handshakeSpan := tracing.StartSpan(cancelCtx, "handshake")
 		sshClient, err := devssh.StdioClient(stdoutReader, stdinWriter, false)
+		tracing.EndSpan(handshakeSpan, err)
 		if err != nil {
 			containerChan <- errors.Wrap(err, "create ssh client")
 			return
@@ -94,15 +152,7 @@ func (c *ContainerHandler) Run(ctx context.Context, handler Handler) error {
 		defer c.log.Debugf("Connection to container closed")
 		c.log.Debugf("Successfully connected to host")
 
-		// update workspace remotely
-		if !c.proxy && c.updateConfigInterval > 0 {
-			go func() {
-				c.updateConfig(cancelCtx, sshClient)
-			}()
-		}
-
-		// wait until we are done
-		containerChan <- errors.Wrap(c.runRunInContainer(cancelCtx, sshClient, handler), "run in container")
+		containerChan <- handler(cancelCtx, sshClient)
 	}()
 
 	// wait for result
@@ -114,6 +164,29 @@ func (c *ContainerHandler) Run(ctx context.Context, handler Handler) error {
 	}
 }
 
+// executeWithInstalledAgent runs the command without installing the agent and fails if the
+// agent on the host is missing or doesn't match the version of the CLI
+func (c *ContainerHandler) executeWithInstalledAgent(ctx context.Context, exec inject.ExecFunc, command string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	agentPath := c.client.AgentPath()
+	if agentPath == "" {
+		agentPath = agent.RemoteDevPodHelperLocation
+	}
+
+	buf := &bytes.Buffer{}
+	err := exec(ctx, fmt.Sprintf("'%s' version", agentPath), nil, buf, buf)
+	if err != nil {
+		return fmt.Errorf("devpod agent is missing at %s, rerun without --auto-install-agent=false to install it: %s%w", agentPath, buf.String(), err)
+	}
+
+	agentVersion := strings.TrimSpace(buf.String())
+	if agentVersion != version.GetVersion() && version.GetVersion() != version.DevVersion {
+		return fmt.Errorf("devpod agent at %s has version %s, but version %s is required, rerun without --auto-install-agent=false to update it", agentPath, agentVersion, version.GetVersion())
+	}
+
+	c.log.Debugf("Found devpod agent %s at %s", agentVersion, agentPath)
+	return exec(ctx, command, stdin, stdout, stderr)
+}
+
 func (c *ContainerHandler) updateConfig(ctx context.Context, sshClient *ssh.Client) {
 	for {
 		select {
@@ -130,7 +203,7 @@ func (c *ContainerHandler) updateConfig(ctx context.Context, sshClient *ssh.Clie
 			}
 
 			// compress info
-			workspaceInfo, agentInfo, err := c.client.AgentInfo(provider.CLIOptions{Proxy: c.proxy})
+			workspaceInfo, agentInfo, err := c.client.AgentInfoContext(ctx, provider.CLIOptions{Proxy: c.proxy})
 			if err != nil {
 				c.log.Errorf("Error compressing workspace info: %v", err)
 				break
@@ -156,9 +229,9 @@ func (c *ContainerHandler) updateConfig(ctx context.Context, sshClient *ssh.Clie
 
 func (c *ContainerHandler) runRunInContainer(ctx context.Context, sshClient *ssh.Client, runInContainer Handler) error {
 	// compress info
-	workspaceInfo, _, err := c.client.AgentInfo(provider.CLIOptions{Proxy: c.proxy})
+	workspaceInfo, _, err := c.client.AgentInfoContext(ctx, provider.CLIOptions{Proxy: c.proxy})
 	if err != nil {
-		return err
+		return errors.Wrap(err, "agent info")
 	}
 
 	// create pipes
@@ -8,6 +8,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/docker/go-connections/nat"
@@ -29,7 +30,9 @@ func RunInContainer(
 	ctx context.Context,
 	devPodConfig *config.Config,
 	containerClient *ssh.Client,
-	user string,
+	users []string,
+	runtimeDir,
+	helperPath string,
 	forwardPorts bool,
 	gitCredentials,
 	dockerCredentials bool,
@@ -51,68 +54,118 @@ func RunInContainer(
 	dockerCredentials = dockerCredentials && devPodConfig.ContextOption(config.ContextOptionSSHInjectDockerCredentials) == "true"
 	gitCredentials = gitCredentials && devPodConfig.ContextOption(config.ContextOptionSSHInjectGitCredentials) == "true"
 
-	stdoutReader, stdoutWriter, err := os.Pipe()
-	if err != nil {
-		return err
+	// run credentials server
+	writer := log.ErrorStreamOnly().Writer(logrus.DebugLevel, false)
+	defer writer.Close()
+
+	command := fmt.Sprintf("'%s' agent container credentials-server", agent.ContainerDevPodHelperLocation)
+	for _, user := range users {
+		command += fmt.Sprintf(" --user '%s'", user)
+	}
+	if gitCredentials {
+		command += " --configure-git-helper"
+	}
+	if dockerCredentials {
+		command += " --configure-docker-helper"
+	}
+	if forwardPorts {
+		command += " --forward-ports"
+	}
+	if runtimeDir != "" {
+		command += fmt.Sprintf(" --runtime-dir '%s'", runtimeDir)
+	}
+	if helperPath != "" {
+		command += fmt.Sprintf(" --helper-path '%s'", helperPath)
+	}
+	if log.GetLevel() == logrus.DebugLevel {
+		command += " --debug"
 	}
-	defer stdoutWriter.Close()
 
-	stdinReader, stdinWriter, err := os.Pipe()
-	if err != nil {
-		return err
+	// create a port forwarder
+	var forwarder netstat.Forwarder
+	if forwardPorts {
+		forwarder = newForwarder(containerClient, append(forwardedPorts, fmt.Sprintf("%d", openvscode.DefaultVSCodePort)), log)
 	}
-	defer stdinWriter.Close()
 
-	// start server on stdio
-	cancelCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	// the credentials server fails until the helper in a slow container is ready, so it is
+	// restarted until it connected once
+	return retryHandshake(ctx, credentialsServerAttempts, credentialsServerRetryDelay, func(ctx context.Context, onHandshake func()) error {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		defer stdoutWriter.Close()
 
-	// run credentials server
-	errChan := make(chan error, 1)
-	go func() {
+		stdinReader, stdinWriter, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		defer stdinWriter.Close()
+
+		// start server on stdio
+		cancelCtx, cancel := context.WithCancel(ctx)
 		defer cancel()
-		writer := log.ErrorStreamOnly().Writer(logrus.DebugLevel, false)
-		defer writer.Close()
 
-		command := fmt.Sprintf("'%s' agent container credentials-server --user '%s'", agent.ContainerDevPodHelperLocation, user)
-		if gitCredentials {
-			command += " --configure-git-helper"
-		}
-		if dockerCredentials {
-			command += " --configure-docker-helper"
-		}
-		if forwardPorts {
-			command += " --forward-ports"
-		}
-		if log.GetLevel() == logrus.DebugLevel {
-			command += " --debug"
+		errChan := make(chan error, 1)
+		go func() {
+			defer cancel()
+			errChan <- devssh.Run(cancelCtx, containerClient, command, stdinReader, stdoutWriter, writer)
+		}()
+
+		// forward credentials to container
+		err = tunnelserver.RunServicesServer(
+			cancelCtx,
+			stdoutReader,
+			stdinWriter,
+			gitCredentials,
+			dockerCredentials,
+			forwarder,
+			log,
+			tunnelserver.WithForwardPolicy(tunnelserver.ParseForwardPolicy(
+				devPodConfig.ContextOption(config.ContextOptionSSHForwardAllowlist),
+				devPodConfig.ContextOption(config.ContextOptionSSHForwardDenylist),
+			)),
+			tunnelserver.WithCredentialProviders(strings.Split(devPodConfig.ContextOption(config.ContextOptionSSHCredentialProviders), ",")...),
+			tunnelserver.WithPingHandler(onHandshake),
+		)
+		if err != nil {
+			return errors.Wrap(err, "run tunnel server")
 		}
 
-		errChan <- devssh.Run(cancelCtx, containerClient, command, stdinReader, stdoutWriter, writer)
-	}()
+		// wait until command finished
+		return <-errChan
+	}, log)
+}
 
-	// create a port forwarder
-	var forwarder netstat.Forwarder
-	if forwardPorts {
-		forwarder = newForwarder(containerClient, append(forwardedPorts, fmt.Sprintf("%d", openvscode.DefaultVSCodePort)), log)
-	}
+const (
+	// credentialsServerAttempts is how often the credentials server is started before giving up
+	credentialsServerAttempts = 5
 
-	// forward credentials to container
-	err = tunnelserver.RunServicesServer(
-		cancelCtx,
-		stdoutReader,
-		stdinWriter,
-		gitCredentials,
-		dockerCredentials,
-		forwarder,
-		log,
-	)
-	if err != nil {
-		return errors.Wrap(err, "run tunnel server")
-	}
+	// credentialsServerRetryDelay is the delay before the first restart, it doubles with every attempt
+	credentialsServerRetryDelay = time.Second
+)
 
-	// wait until command finished
-	return <-errChan
+// retryHandshake runs the server until it returns without error or fails after its handshake. A
+// server that fails before its handshake is restarted up to attempts times with an exponential
+// delay.
+func retryHandshake(ctx context.Context, attempts int, delay time.Duration, run func(ctx context.Context, onHandshake func()) error, log log.Logger) error {
+	for attempt := 1; ; attempt++ {
+		handshake := atomic.Bool{}
+		err := run(ctx, func() { handshake.Store(true) })
+		if err == nil || handshake.Load() || ctx.Err() != nil {
+			return err
+		} else if attempt >= attempts {
+			return fmt.Errorf("no handshake after %d attempts: %w", attempts, err)
+		}
+
+		log.Debugf("Credentials server failed before the handshake, retrying in %s: %v", delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
 }
 
 func forwardDevContainerPorts(ctx context.Context, containerClient *ssh.Client, extraPorts []string, exitAfterTimeout time.Duration, log log.Logger) ([]string, error) {
@@ -0,0 +1,49 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/loft-sh/log"
+	"gotest.tools/assert"
+)
+
+func TestRetryHandshakeDelayedHelper(t *testing.T) {
+	// the helper fails twice before it comes up and connects
+	runs := 0
+	err := retryHandshake(context.Background(), 5, time.Millisecond, func(ctx context.Context, onHandshake func()) error {
+		runs++
+		if runs < 3 {
+			return fmt.Errorf("helper not ready")
+		}
+
+		onHandshake()
+		return nil
+	}, log.Discard)
+	assert.NilError(t, err)
+	assert.Equal(t, runs, 3)
+}
+
+func TestRetryHandshakeExhausted(t *testing.T) {
+	runs := 0
+	err := retryHandshake(context.Background(), 3, time.Millisecond, func(ctx context.Context, onHandshake func()) error {
+		runs++
+		return fmt.Errorf("helper not ready")
+	}, log.Discard)
+	assert.ErrorContains(t, err, "no handshake after 3 attempts: helper not ready")
+	assert.Equal(t, runs, 3)
+}
+
+func TestRetryHandshakeFailsAfterHandshake(t *testing.T) {
+	// a server that connected once is not restarted
+	runs := 0
+	err := retryHandshake(context.Background(), 3, time.Millisecond, func(ctx context.Context, onHandshake func()) error {
+		runs++
+		onHandshake()
+		return fmt.Errorf("connection lost")
+	}, log.Discard)
+	assert.ErrorContains(t, err, "connection lost")
+	assert.Equal(t, runs, 1)
+}
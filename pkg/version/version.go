@@ -1,6 +1,10 @@
 package version
 
-import "strings"
+import (
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
 
 var DevVersion = "v0.0.0"
 
@@ -10,6 +14,21 @@ func GetVersion() string {
 	return version
 }
 
+// Compatible returns true if both versions are valid and share the same major and minor version.
+// The v prefix is optional and pre-releases are compatible with their release.
+func Compatible(a, b string) bool {
+	a, b = withPrefix(a), withPrefix(b)
+	return semver.IsValid(a) && semver.IsValid(b) && semver.MajorMinor(a) == semver.MajorMinor(b)
+}
+
+func withPrefix(version string) string {
+	if strings.HasPrefix(version, "v") {
+		return version
+	}
+
+	return "v" + version
+}
+
 func GetMajorVersion() string {
 	// use golang.org/x/mod/semver instead?
 	s := strings.Split(strings.TrimLeft(GetVersion(), "v"), ".")
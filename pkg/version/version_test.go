@@ -0,0 +1,25 @@
+package version
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestCompatible(t *testing.T) {
+	assert.Assert(t, Compatible("v0.5.1", "v0.5.0"))
+	assert.Assert(t, Compatible("0.5.1", "v0.5.0"))
+	assert.Assert(t, Compatible("v0.5.0", "0.5.2"))
+	assert.Assert(t, Compatible("v0.5.0-alpha.1", "v0.5.0"))
+	assert.Assert(t, Compatible("v0.5.0+build.1", "v0.5.3-beta"))
+
+	assert.Assert(t, !Compatible("v0.6.0", "v0.5.0"))
+	assert.Assert(t, !Compatible("v1.5.0", "v0.5.0"))
+	assert.Assert(t, !Compatible("v0.6.0-alpha.1", "v0.5.0"))
+
+	assert.Assert(t, !Compatible("", "v0.5.0"))
+	assert.Assert(t, !Compatible("latest", "v0.5.0"))
+	assert.Assert(t, !Compatible("v0.5.0", "latest"))
+	assert.Assert(t, !Compatible("", ""))
+	assert.Assert(t, !Compatible("devpod version v0.5.0", "v0.5.0"))
+}
@@ -0,0 +1,262 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// example of RFC 6455
+	assert.Equal(t, AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+}
+
+func TestConn(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, "echo")
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// echo messages until the client closes the connection
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			_ = conn.WriteMessage(messageType, message)
+		}
+	}))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	assert.NilError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Protocol: chat, echo\r\n\r\n"))
+	assert.NilError(t, err)
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, response.StatusCode, http.StatusSwitchingProtocols)
+	assert.Equal(t, response.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+	assert.Equal(t, response.Header.Get("Sec-WebSocket-Protocol"), "echo")
+
+	// a fragmented text message with a ping in between
+	writeFrame(t, conn, false, TextMessage, "hello ")
+	writeFrame(t, conn, true, PingMessage, "ping")
+	writeFrame(t, conn, true, continuationFrame, "world")
+	assert.DeepEqual(t, readFrame(t, reader), []byte{0x80 | PongMessage, 4, 'p', 'i', 'n', 'g'})
+	assert.DeepEqual(t, readFrame(t, reader), append([]byte{0x80 | TextMessage, 11}, "hello world"...))
+
+	writeFrame(t, conn, true, CloseMessage, "")
+	assert.DeepEqual(t, readFrame(t, reader), []byte{0x80 | CloseMessage, 0})
+}
+
+func TestUpgradeInvalid(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = Upgrade(w, r, "")
+	}))
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	assert.NilError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, response.StatusCode, http.StatusBadRequest)
+}
+
+func TestReadMessageFragmentation(t *testing.T) {
+	conn, client := pipeConn(t)
+	writeFrames(client,
+		clientFrame(false, BinaryMessage, []byte("a")),
+		clientFrame(false, continuationFrame, []byte("b")),
+		clientFrame(true, PongMessage, []byte("pong")),
+		clientFrame(true, continuationFrame, []byte("c")),
+		clientFrame(true, TextMessage, []byte("next")),
+	)
+
+	// pongs in between fragments are skipped
+	messageType, message, err := conn.ReadMessage()
+	assert.NilError(t, err)
+	assert.Equal(t, messageType, BinaryMessage)
+	assert.Equal(t, string(message), "abc")
+	messageType, message, err = conn.ReadMessage()
+	assert.NilError(t, err)
+	assert.Equal(t, messageType, TextMessage)
+	assert.Equal(t, string(message), "next")
+
+	// a continuation needs a started message
+	conn, client = pipeConn(t)
+	writeFrames(client, clientFrame(true, continuationFrame, []byte("a")))
+	_, _, err = conn.ReadMessage()
+	assert.ErrorContains(t, err, "unexpected continuation frame")
+
+	// a new message cannot start before the fragmented one is finished
+	conn, client = pipeConn(t)
+	writeFrames(client, clientFrame(false, TextMessage, []byte("a")), clientFrame(true, TextMessage, []byte("b")))
+	_, _, err = conn.ReadMessage()
+	assert.ErrorContains(t, err, "expected a continuation frame")
+}
+
+func TestReadMessageControlFrames(t *testing.T) {
+	conn, client := pipeConn(t)
+	reader := bufio.NewReader(client)
+	writeFrames(client, clientFrame(true, PingMessage, []byte("ping")), clientFrame(true, CloseMessage, []byte{0x03, 0xe8}))
+
+	// the ping is answered with its payload and the close status is echoed
+	errChan := make(chan error, 1)
+	go func() {
+		_, _, err := conn.ReadMessage()
+		errChan <- err
+	}()
+	assert.DeepEqual(t, readFrame(t, reader), []byte{0x80 | PongMessage, 4, 'p', 'i', 'n', 'g'})
+	assert.DeepEqual(t, readFrame(t, reader), []byte{0x80 | CloseMessage, 2, 0x03, 0xe8})
+	assert.Equal(t, <-errChan, io.EOF)
+
+	// control frames cannot be fragmented
+	conn, client = pipeConn(t)
+	writeFrames(client, clientFrame(false, PingMessage, []byte("ping")))
+	_, _, err := conn.ReadMessage()
+	assert.ErrorContains(t, err, "control frames must not be fragmented")
+
+	// control frames are limited to 125 bytes
+	conn, client = pipeConn(t)
+	writeFrames(client, clientFrame(true, PingMessage, bytes.Repeat([]byte("a"), 126)))
+	_, _, err = conn.ReadMessage()
+	assert.ErrorContains(t, err, "exceed 125 bytes")
+
+	// unknown opcodes are rejected
+	conn, client = pipeConn(t)
+	writeFrames(client, clientFrame(true, 3, nil))
+	_, _, err = conn.ReadMessage()
+	assert.ErrorContains(t, err, "unsupported opcode 3")
+}
+
+func TestReadMessageInvalidFrames(t *testing.T) {
+	// the length is checked before the payload is read
+	conn, client := pipeConn(t)
+	header := []byte{0x80 | BinaryMessage, 0x80 | 127, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint64(header[2:], maxMessageSize+1)
+	writeFrames(client, header)
+	_, _, err := conn.ReadMessage()
+	assert.ErrorContains(t, err, "frame exceeds")
+
+	// fragments cannot add up to more than the limit
+	conn, client = pipeConn(t)
+	fragment := bytes.Repeat([]byte("a"), maxMessageSize/2+1)
+	writeFrames(client, clientFrame(false, BinaryMessage, fragment), clientFrame(true, continuationFrame, fragment))
+	_, _, err = conn.ReadMessage()
+	assert.ErrorContains(t, err, "message exceeds")
+
+	// frames of the client need a mask
+	conn, client = pipeConn(t)
+	writeFrames(client, []byte{0x80 | TextMessage, 1, 'a'})
+	_, _, err = conn.ReadMessage()
+	assert.ErrorContains(t, err, "need to be masked")
+
+	// extensions are not supported
+	conn, client = pipeConn(t)
+	frame := clientFrame(true, TextMessage, []byte("a"))
+	frame[0] |= 0x40
+	writeFrames(client, frame)
+	_, _, err = conn.ReadMessage()
+	assert.ErrorContains(t, err, "reserved bits")
+}
+
+func TestWriteMessageLength(t *testing.T) {
+	for _, testCase := range []struct {
+		length int
+		header []byte
+	}{
+		{length: 125, header: []byte{0x80 | BinaryMessage, 125}},
+		{length: 126, header: []byte{0x80 | BinaryMessage, 126, 0, 126}},
+		{length: 0x10000, header: []byte{0x80 | BinaryMessage, 127, 0, 0, 0, 0, 0, 1, 0, 0}},
+	} {
+		conn, client := pipeConn(t)
+		go func(length int) {
+			_ = conn.WriteMessage(BinaryMessage, make([]byte, length))
+		}(testCase.length)
+
+		out := make([]byte, len(testCase.header)+testCase.length)
+		_, err := io.ReadFull(client, out)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, out[:len(testCase.header)], testCase.header)
+	}
+}
+
+// pipeConn returns the server side of an in-memory connection and the client end of it
+func pipeConn(t *testing.T) (*Conn, net.Conn) {
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		_ = server.Close()
+		_ = client.Close()
+	})
+
+	return &Conn{conn: server, reader: bufio.NewReader(server)}, client
+}
+
+// writeFrames writes the frames in the background, as the pipe blocks until they are read
+func writeFrames(conn net.Conn, frames ...[]byte) {
+	go func() {
+		for _, frame := range frames {
+			_, err := conn.Write(frame)
+			if err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// clientFrame returns a masked frame like a client sends it
+func clientFrame(final bool, opcode int, payload []byte) []byte {
+	first := byte(opcode)
+	if final {
+		first |= 0x80
+	}
+
+	frame := []byte{first}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 0x80|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	}
+
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i := range payload {
+		frame = append(frame, payload[i]^mask[i%4])
+	}
+
+	return frame
+}
+
+func writeFrame(t *testing.T, conn net.Conn, final bool, opcode int, payload string) {
+	_, err := conn.Write(clientFrame(final, opcode, []byte(payload)))
+	assert.NilError(t, err)
+}
+
+func readFrame(t *testing.T, reader io.Reader) []byte {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(reader, header)
+	assert.NilError(t, err)
+
+	payload := make([]byte, header[1])
+	_, err = io.ReadFull(reader, payload)
+	assert.NilError(t, err)
+	return append(header, payload...)
+}
@@ -0,0 +1,179 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/loft-sh/devpod/pkg/client"
+	"github.com/loft-sh/devpod/pkg/config"
+	provider2 "github.com/loft-sh/devpod/pkg/provider"
+	"github.com/loft-sh/log"
+	"github.com/loft-sh/log/survey"
+	"github.com/loft-sh/log/terminal"
+)
+
+// PickerStatusTimeout is how long PickWorkspace waits for the providers to report the status
+// of the workspaces
+const PickerStatusTimeout = 5 * time.Second
+
+// PickerStatusConcurrency is how many providers PickWorkspace asks for a status at the same time
+const PickerStatusConcurrency = 4
+
+// statusUnknown is shown for workspaces whose provider didn't report a status in time
+const statusUnknown = "Unknown"
+
+// PickWorkspace asks the user to select one of the workspaces of the current context from a
+// list with their status and when they were last used, most recently used first. It returns
+// the id of the selected workspace.
+func PickWorkspace(ctx context.Context, devPodConfig *config.Config, log log.Logger) (string, error) {
+	return pickWorkspace(devPodConfig, func(workspaces []*provider2.Workspace) map[string]client.Status {
+		return workspaceStatuses(ctx, devPodConfig, workspaces)
+	}, log)
+}
+
+// pickWorkspace asks the user to select one of the workspaces of the current context, the list
+// shows the status returned by statuses unless it is nil
+func pickWorkspace(devPodConfig *config.Config, statuses func(workspaces []*provider2.Workspace) map[string]client.Status, log log.Logger) (string, error) {
+	if !terminal.IsTerminalIn {
+		return "", errProvideWorkspaceArg
+	}
+
+	workspaces, err := ListWorkspaces(devPodConfig, log)
+	if err != nil {
+		return "", err
+	} else if len(workspaces) == 0 {
+		return "", errProvideWorkspaceArg
+	}
+
+	var workspaceStatuses map[string]client.Status
+	if statuses != nil {
+		workspaceStatuses = statuses(workspaces)
+	}
+	ids, labels := pickerOptions(workspaces, workspaceStatuses, time.Now())
+	answer, err := log.Question(&survey.QuestionOptions{
+		Question:     "Please select a workspace from the list below",
+		DefaultValue: labels[0],
+		Options:      labels,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for i, label := range labels {
+		if label == answer {
+			return ids[i], nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown workspace %s", answer)
+}
+
+// workspaceStatuses asks the providers for the status of at most PickerStatusConcurrency
+// workspaces at a time and returns it by workspace id. Workspaces whose status couldn't be
+// retrieved within PickerStatusTimeout are missing.
+func workspaceStatuses(ctx context.Context, devPodConfig *config.Config, workspaces []*provider2.Workspace) map[string]client.Status {
+	ctx, cancel := context.WithTimeout(ctx, PickerStatusTimeout)
+	defer cancel()
+
+	statuses := map[string]client.Status{}
+	mutex := sync.Mutex{}
+	waitGroup := sync.WaitGroup{}
+	semaphore := make(chan struct{}, PickerStatusConcurrency)
+	for _, workspace := range workspaces {
+		waitGroup.Add(1)
+		go func(workspaceID string) {
+			defer waitGroup.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+
+			provider, workspace, machine, err := loadExistingWorkspace(workspaceID, devPodConfig, false, log.Discard)
+			if err != nil {
+				return
+			}
+			workspaceClient, err := newWorkspaceClient(devPodConfig, provider, workspace, machine, log.Discard)
+			if err != nil {
+				return
+			}
+			status, err := workspaceClient.Status(ctx, client.StatusOptions{})
+			if err != nil {
+				return
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			statuses[workspaceID] = status
+		}(workspace.ID)
+	}
+
+	// don't wait for providers that ignore the context
+	done := make(chan struct{})
+	go func() {
+		waitGroup.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	result := map[string]client.Status{}
+	for id, status := range statuses {
+		result[id] = status
+	}
+	return result
+}
+
+// pickerOptions returns the ids of the workspaces, most recently used first, and a label with
+// aligned name, status and last used columns for each of them. Without statuses, the status
+// column is left out.
+func pickerOptions(workspaces []*provider2.Workspace, statuses map[string]client.Status, now time.Time) ([]string, []string) {
+	sorted := append([]*provider2.Workspace{}, workspaces...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LastUsedTimestamp.Time.After(sorted[j].LastUsedTimestamp.Time)
+	})
+
+	nameWidth, statusWidth := 0, len(statusUnknown)
+	for _, workspace := range sorted {
+		if len(workspace.ID) > nameWidth {
+			nameWidth = len(workspace.ID)
+		}
+		if len(statuses[workspace.ID]) > statusWidth {
+			statusWidth = len(statuses[workspace.ID])
+		}
+	}
+
+	ids := []string{}
+	labels := []string{}
+	for _, workspace := range sorted {
+		status := string(statuses[workspace.ID])
+		if status == "" {
+			status = statusUnknown
+		}
+
+		lastUsed := "never used"
+		if !workspace.LastUsedTimestamp.Time.IsZero() {
+			lastUsed = "used " + units.HumanDuration(now.Sub(workspace.LastUsedTimestamp.Time)) + " ago"
+		}
+
+		ids = append(ids, workspace.ID)
+		if statuses == nil {
+			labels = append(labels, fmt.Sprintf("%-*s  %s", nameWidth, workspace.ID, lastUsed))
+		} else {
+			labels = append(labels, strings.TrimRight(fmt.Sprintf("%-*s  %-*s  %s", nameWidth, workspace.ID, statusWidth, status, lastUsed), " "))
+		}
+	}
+
+	return ids, labels
+}
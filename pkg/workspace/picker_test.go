@@ -0,0 +1,44 @@
+package workspace
+
+import (
+	"testing"
+	"time"
+
+	"github.com/loft-sh/devpod/pkg/client"
+	provider2 "github.com/loft-sh/devpod/pkg/provider"
+	"github.com/loft-sh/devpod/pkg/types"
+	"gotest.tools/assert"
+)
+
+func TestPickerOptions(t *testing.T) {
+	now := time.Now()
+	workspaces := []*provider2.Workspace{
+		{ID: "old", LastUsedTimestamp: types.NewTime(now.Add(-3 * time.Hour))},
+		{ID: "new"},
+		{ID: "recent-project", LastUsedTimestamp: types.NewTime(now.Add(-5 * time.Minute))},
+	}
+	statuses := map[string]client.Status{"old": client.StatusStopped, "recent-project": client.StatusRunning}
+
+	ids, labels := pickerOptions(workspaces, statuses, now)
+	assert.DeepEqual(t, ids, []string{"recent-project", "old", "new"})
+	assert.DeepEqual(t, labels, []string{
+		"recent-project  Running  used 5 minutes ago",
+		"old             Stopped  used 3 hours ago",
+		"new             Unknown  never used",
+	})
+}
+
+func TestPickerOptionsWithoutStatus(t *testing.T) {
+	now := time.Now()
+	workspaces := []*provider2.Workspace{
+		{ID: "new"},
+		{ID: "recent-project", LastUsedTimestamp: types.NewTime(now.Add(-5 * time.Minute))},
+	}
+
+	ids, labels := pickerOptions(workspaces, nil, now)
+	assert.DeepEqual(t, ids, []string{"recent-project", "new"})
+	assert.DeepEqual(t, labels, []string{
+		"recent-project  used 5 minutes ago",
+		"new             never used",
+	})
+}
@@ -0,0 +1,75 @@
+package workspace
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// PollInterval is the interval between status polls while waiting for a workspace. The first
+// polls are fast, afterwards the interval grows by Factor with every poll up to Max. Jitter
+// randomizes every interval, so many clients don't poll a provider in lockstep.
+type PollInterval struct {
+	// Base is the interval of the first polls
+	Base time.Duration
+
+	// Max caps the interval
+	Max time.Duration
+
+	// Jitter is the fraction the interval varies by, e.g. 0.25 for ±25%. 0 disables the jitter
+	Jitter float64
+
+	// FastPolls is the number of polls with the base interval before the backoff starts
+	FastPolls int
+
+	// Factor is the growth of the interval per poll after the fast polls
+	Factor float64
+
+	random func() float64
+}
+
+// DefaultPollInterval polls every 2s ± 25% for 5 polls and backs off to at most 15s afterwards
+func DefaultPollInterval() PollInterval {
+	return PollInterval{
+		Base:      2 * time.Second,
+		Max:       15 * time.Second,
+		Jitter:    0.25,
+		FastPolls: 5,
+		Factor:    1.5,
+	}
+}
+
+// Validate checks that the parameters are within their ranges
+func (p PollInterval) Validate() error {
+	if p.Base <= 0 {
+		return fmt.Errorf("poll interval needs to be greater than zero")
+	} else if p.Max < p.Base {
+		return fmt.Errorf("max poll interval %s needs to be at least the poll interval %s", p.Max, p.Base)
+	} else if p.Jitter < 0 || p.Jitter >= 1 {
+		return fmt.Errorf("poll jitter needs to be between 0 and 1")
+	}
+
+	return nil
+}
+
+// Next returns the interval to wait after the given poll, starting at 0
+func (p PollInterval) Next(poll int) time.Duration {
+	interval := float64(p.Base)
+	for i := p.FastPolls; i <= poll && p.Factor > 1 && interval < float64(p.Max); i++ {
+		interval *= p.Factor
+	}
+	if p.Max > 0 && interval > float64(p.Max) {
+		interval = float64(p.Max)
+	}
+
+	if p.Jitter > 0 {
+		random := p.random
+		if random == nil {
+			random = rand.Float64
+		}
+
+		interval *= 1 + p.Jitter*(2*random()-1)
+	}
+
+	return time.Duration(interval)
+}
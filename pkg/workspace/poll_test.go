@@ -0,0 +1,32 @@
+package workspace
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestPollIntervalNext(t *testing.T) {
+	poll := DefaultPollInterval()
+	poll.Jitter = 0
+
+	// the first polls are fast
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, poll.Next(i), 2*time.Second)
+	}
+	assert.Equal(t, poll.Next(5), 3*time.Second)
+	assert.Equal(t, poll.Next(6), 4500*time.Millisecond)
+
+	// the interval is capped
+	assert.Equal(t, poll.Next(100), 15*time.Second)
+}
+
+func TestPollIntervalJitter(t *testing.T) {
+	poll := DefaultPollInterval()
+	poll.random = func() float64 { return 0 }
+	assert.Equal(t, poll.Next(0), 1500*time.Millisecond)
+	poll.random = func() float64 { return 1 }
+	assert.Equal(t, poll.Next(0), 2500*time.Millisecond)
+	assert.Equal(t, poll.Next(100), 18750*time.Millisecond)
+}
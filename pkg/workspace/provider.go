@@ -348,6 +348,46 @@ func FindProvider(devPodConfig *config.Config, name string, log log.Logger) (*Pr
 	return retProviders[name], nil
 }
 
+// OverlayProviderOptions overrides the provider options in the given config with the given
+// KEY=VALUE options without saving them. Options set specifically for a workspace or machine
+// still take precedence.
+func OverlayProviderOptions(devPodConfig *config.Config, providerName string, options []string, log log.Logger) error {
+	if len(options) == 0 {
+		return nil
+	}
+
+	parsedOptions, err := provider2.ParseOptions(options)
+	if err != nil {
+		return errors.Wrap(err, "parse options")
+	}
+
+	provider, err := FindProvider(devPodConfig, providerName, log)
+	if err != nil {
+		return err
+	}
+
+	dynamicOptions := devPodConfig.DynamicProviderOptionDefinitions(providerName)
+	providerState := devPodConfig.Current().Providers[providerName]
+	if providerState == nil {
+		return fmt.Errorf("provider %s is not configured in the current context", providerName)
+	} else if providerState.Options == nil {
+		providerState.Options = map[string]config.OptionValue{}
+	}
+	for key, value := range parsedOptions {
+		if provider.Config.Options[key] == nil && dynamicOptions[key] == nil {
+			return fmt.Errorf("provider %s has no option %s", providerName, key)
+		}
+
+		log.Debugf("Override provider option %s for this session", key)
+		providerState.Options[key] = config.OptionValue{
+			Value:        value,
+			UserProvided: true,
+		}
+	}
+
+	return nil
+}
+
 func LoadAllProviders(devPodConfig *config.Config, log log.Logger) (map[string]*ProviderWithOptions, error) {
 	retProviders := map[string]*ProviderWithOptions{}
 	defaultContext := devPodConfig.Current()
@@ -0,0 +1,188 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/loft-sh/devpod/pkg/client"
+	"github.com/loft-sh/devpod/pkg/config"
+	"github.com/loft-sh/devpod/pkg/file"
+	provider2 "github.com/loft-sh/devpod/pkg/provider"
+	"github.com/loft-sh/log"
+)
+
+// Resolver maps the arguments of a command to the id of an existing workspace. If the returned
+// id is empty, the user is asked to select a workspace.
+type Resolver func(devPodConfig *config.Config, args []string, log log.Logger) (string, error)
+
+// ResolveOptions control how GetWorkspaceWithOptions resolves an existing workspace
+type ResolveOptions struct {
+	// Resolver maps the arguments to a workspace id, defaults to DefaultResolver. Use it to
+	// e.g. map aliases or apply fuzzy matching.
+	Resolver Resolver
+
+	// ChangeLastUsed updates the last used timestamp of the resolved workspace
+	ChangeLastUsed bool
+}
+
+// DefaultResolver converts the first argument, which is either a workspace name or a local
+// folder, to a workspace id. Without arguments the user is asked to select a workspace.
+func DefaultResolver(devPodConfig *config.Config, args []string, log log.Logger) (string, error) {
+	if len(args) == 0 {
+		return "", nil
+	}
+
+	_, name := file.IsLocalDir(args[0])
+	return ToID(name), nil
+}
+
+// LastUsedResolver resolves the most recently used workspace and ignores the arguments
+func LastUsedResolver(devPodConfig *config.Config, args []string, log log.Logger) (string, error) {
+	workspaces, err := ListWorkspaces(devPodConfig, log)
+	if err != nil {
+		return "", err
+	}
+
+	var lastUsed *provider2.Workspace
+	for _, workspace := range workspaces {
+		if lastUsed == nil || workspace.LastUsedTimestamp.Time.After(lastUsed.LastUsedTimestamp.Time) {
+			lastUsed = workspace
+		}
+	}
+	if lastUsed == nil {
+		return "", fmt.Errorf("no workspace found in context %s", devPodConfig.DefaultContext)
+	}
+
+	return lastUsed.ID, nil
+}
+
+// CurrentDirResolver resolves the workspace that was created from the current directory or one
+// of its parents, like devpod up . does. Explicit arguments are converted by DefaultResolver.
+func CurrentDirResolver(devPodConfig *config.Config, args []string, log log.Logger) (string, error) {
+	if len(args) > 0 {
+		return DefaultResolver(devPodConfig, args, log)
+	}
+
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	workspaces, err := ListWorkspaces(devPodConfig, log)
+	if err != nil {
+		return "", err
+	}
+
+	workspace := findWorkspaceForPath(workspaces, currentDir)
+	if workspace == nil {
+		return "", fmt.Errorf("current directory %s isn't linked to a workspace, run 'devpod up .' to create one or specify the workspace name", currentDir)
+	}
+
+	log.Debugf("Resolved workspace %s from folder %s", workspace.ID, workspace.Source.LocalFolder)
+	return workspace.ID, nil
+}
+
+// FuzzyResolver resolves the first argument like DefaultResolver, but falls back to the unique
+// workspace whose id starts with or contains it if no workspace with that exact id exists.
+// Workspaces with a matching prefix take precedence over the ones only containing the argument.
+func FuzzyResolver(devPodConfig *config.Config, args []string, log log.Logger) (string, error) {
+	workspaceID, err := DefaultResolver(devPodConfig, args, log)
+	if err != nil || workspaceID == "" || provider2.WorkspaceExists(devPodConfig.DefaultContext, workspaceID) {
+		return workspaceID, err
+	}
+
+	workspaces, err := ListWorkspaces(devPodConfig, log)
+	if err != nil {
+		return "", err
+	}
+
+	matches := matchWorkspaces(workspaces, workspaceID)
+	if len(matches) > 1 {
+		return "", fmt.Errorf("workspace name %s is ambiguous, it matches: %s. Please specify the full name", workspaceID, strings.Join(matches, ", "))
+	} else if len(matches) == 1 {
+		log.Debugf("Resolved workspace %s from %s", matches[0], workspaceID)
+		return matches[0], nil
+	}
+
+	return workspaceID, nil
+}
+
+// matchWorkspaces returns the sorted ids of the workspaces starting with query or, if there are
+// none, containing query
+func matchWorkspaces(workspaces []*provider2.Workspace, query string) []string {
+	prefixMatches := []string{}
+	containsMatches := []string{}
+	for _, workspace := range workspaces {
+		if strings.HasPrefix(workspace.ID, query) {
+			prefixMatches = append(prefixMatches, workspace.ID)
+		} else if strings.Contains(workspace.ID, query) {
+			containsMatches = append(containsMatches, workspace.ID)
+		}
+	}
+
+	matches := prefixMatches
+	if len(matches) == 0 {
+		matches = containsMatches
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// findWorkspaceForPath returns the workspace with the deepest local folder that contains path. If
+// several workspaces use the same folder, the most recently used one wins.
+func findWorkspaceForPath(workspaces []*provider2.Workspace, path string) *provider2.Workspace {
+	var found *provider2.Workspace
+	for _, workspace := range workspaces {
+		folder := workspace.Source.LocalFolder
+		if folder == "" {
+			continue
+		}
+
+		rel, err := filepath.Rel(filepath.Clean(folder), filepath.Clean(path))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		if found == nil || len(folder) > len(found.Source.LocalFolder) ||
+			(len(folder) == len(found.Source.LocalFolder) && workspace.LastUsedTimestamp.Time.After(found.LastUsedTimestamp.Time)) {
+			found = workspace
+		}
+	}
+
+	return found
+}
+
+// GetWorkspaceWithOptions retrieves an already existing workspace like GetWorkspace, but lets
+// the caller control the resolution of the workspace
+func GetWorkspaceWithOptions(devPodConfig *config.Config, args []string, options ResolveOptions, log log.Logger) (client.BaseWorkspaceClient, error) {
+	resolver := options.Resolver
+	if resolver == nil {
+		resolver = DefaultResolver
+	}
+
+	workspaceID, err := resolver(devPodConfig, args, log)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		provider  *provider2.ProviderConfig
+		workspace *provider2.Workspace
+		machine   *provider2.Machine
+	)
+	if workspaceID == "" {
+		provider, workspace, machine, err = selectWorkspace(devPodConfig, options.ChangeLastUsed, log)
+	} else if !provider2.WorkspaceExists(devPodConfig.DefaultContext, workspaceID) {
+		return nil, fmt.Errorf("workspace %s doesn't exist", workspaceID)
+	} else {
+		provider, workspace, machine, err = loadExistingWorkspace(workspaceID, devPodConfig, options.ChangeLastUsed, log)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newWorkspaceClient(devPodConfig, provider, workspace, machine, log)
+}
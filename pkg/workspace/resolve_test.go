@@ -0,0 +1,36 @@
+package workspace
+
+import (
+	"testing"
+	"time"
+
+	provider2 "github.com/loft-sh/devpod/pkg/provider"
+	"github.com/loft-sh/devpod/pkg/types"
+	"gotest.tools/assert"
+)
+
+func TestFindWorkspaceForPath(t *testing.T) {
+	now := time.Now()
+	workspaces := []*provider2.Workspace{
+		{ID: "git", Source: provider2.WorkspaceSource{GitRepository: "https://github.com/loft-sh/devpod"}},
+		{ID: "project", Source: provider2.WorkspaceSource{LocalFolder: "/home/user/project"}, LastUsedTimestamp: types.NewTime(now)},
+		{ID: "project-old", Source: provider2.WorkspaceSource{LocalFolder: "/home/user/project"}, LastUsedTimestamp: types.NewTime(now.Add(-time.Hour))},
+		{ID: "nested", Source: provider2.WorkspaceSource{LocalFolder: "/home/user/project/nested"}},
+	}
+
+	assert.Equal(t, findWorkspaceForPath(workspaces, "/home/user/project").ID, "project")
+	assert.Equal(t, findWorkspaceForPath(workspaces, "/home/user/project/src").ID, "project")
+	assert.Equal(t, findWorkspaceForPath(workspaces, "/home/user/project/nested/src").ID, "nested")
+	assert.Assert(t, findWorkspaceForPath(workspaces, "/home/user/project-other") == nil)
+	assert.Assert(t, findWorkspaceForPath(workspaces, "/home/user") == nil)
+}
+
+func TestMatchWorkspaces(t *testing.T) {
+	workspaces := []*provider2.Workspace{{ID: "myproj-api"}, {ID: "myproj-web"}, {ID: "old-myproj"}, {ID: "devpod"}}
+
+	assert.DeepEqual(t, matchWorkspaces(workspaces, "myproj"), []string{"myproj-api", "myproj-web"})
+	assert.DeepEqual(t, matchWorkspaces(workspaces, "myproj-w"), []string{"myproj-web"})
+	assert.DeepEqual(t, matchWorkspaces(workspaces, "old"), []string{"old-myproj"})
+	assert.DeepEqual(t, matchWorkspaces(workspaces, "proj-a"), []string{"myproj-api"})
+	assert.DeepEqual(t, matchWorkspaces(workspaces, "loft"), []string{})
+}
@@ -0,0 +1,86 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/loft-sh/devpod/pkg/client"
+	"github.com/loft-sh/devpod/pkg/client/clientimplementation"
+	"github.com/loft-sh/devpod/pkg/config"
+	"github.com/loft-sh/devpod/pkg/encoding"
+	provider2 "github.com/loft-sh/devpod/pkg/provider"
+	"github.com/loft-sh/devpod/pkg/types"
+	"github.com/loft-sh/log"
+	"github.com/pkg/errors"
+)
+
+// CloneFromSnapshot creates a temporary copy of the workspace on a new machine that the provider
+// restores from the given snapshot. The copy keeps the UID of the workspace, so the agent finds the
+// dev container of the snapshot. Deleting the returned workspace deletes its machine as well.
+func CloneFromSnapshot(ctx context.Context, devPodConfig *config.Config, workspaceClient client.BaseWorkspaceClient, snapshotID string, log log.Logger) (client.WorkspaceClient, error) {
+	provider, err := FindProvider(devPodConfig, workspaceClient.Provider(), log)
+	if err != nil {
+		return nil, err
+	} else if !provider.Config.SupportsSnapshots() {
+		return nil, fmt.Errorf("provider '%s' doesn't support snapshots, it needs to be a machine provider that defines exec.createFromSnapshot", provider.Config.Name)
+	}
+
+	// copy the workspace config
+	workspace := *workspaceClient.WorkspaceConfig()
+	workspace.ID = encoding.CreateNewUIDShort(workspace.ID + "-snapshot")
+	workspace.Folder, err = provider2.GetWorkspaceDir(devPodConfig.DefaultContext, workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+	workspace.Machine = provider2.WorkspaceMachineConfig{
+		ID:         encoding.CreateNewUIDShort(workspace.ID),
+		AutoDelete: true,
+	}
+	workspace.CreationTimestamp = types.Now()
+	workspace.LastUsedTimestamp = types.Now()
+	err = saveWorkspaceConfig(&workspace)
+	if err != nil {
+		return nil, errors.Wrap(err, "save config")
+	}
+
+	// create the machine from the snapshot
+	machineConfig, err := createMachine(workspace.Context, workspace.Machine.ID, provider.Config.Name)
+	if err != nil {
+		_ = clientimplementation.DeleteWorkspaceFolder(workspace.Context, workspace.ID, log)
+		return nil, err
+	}
+	cleanup := func() {
+		_ = clientimplementation.DeleteMachineFolder(machineConfig.Context, machineConfig.ID)
+		_ = clientimplementation.DeleteWorkspaceFolder(workspace.Context, workspace.ID, log)
+	}
+
+	machineClient, err := clientimplementation.NewMachineClient(devPodConfig, provider.Config, machineConfig, log)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	err = machineClient.RefreshOptions(ctx, nil)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	err = machineClient.Create(ctx, client.CreateOptions{SnapshotID: snapshotID})
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	snapshotClient, err := newWorkspaceClient(devPodConfig, provider.Config, &workspace, machineConfig, log)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceSnapshotClient, ok := snapshotClient.(client.WorkspaceClient)
+	if !ok {
+		return nil, fmt.Errorf("provider '%s' doesn't support snapshots", provider.Config.Name)
+	}
+
+	return workspaceSnapshotClient, nil
+}
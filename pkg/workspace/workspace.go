@@ -22,8 +22,6 @@ import (
 	provider2 "github.com/loft-sh/devpod/pkg/provider"
 	"github.com/loft-sh/devpod/pkg/types"
 	"github.com/loft-sh/log"
-	"github.com/loft-sh/log/survey"
-	"github.com/loft-sh/log/terminal"
 	"github.com/pkg/errors"
 )
 
@@ -107,11 +105,11 @@ func GetWorkspaceName(args []string) string {
 
 // GetWorkspace tries to retrieve an already existing workspace
 func GetWorkspace(devPodConfig *config.Config, args []string, changeLastUsed bool, log log.Logger) (client.BaseWorkspaceClient, error) {
-	provider, workspace, machine, err := getWorkspace(devPodConfig, args, changeLastUsed, log)
-	if err != nil {
-		return nil, err
-	}
+	return GetWorkspaceWithOptions(devPodConfig, args, ResolveOptions{ChangeLastUsed: changeLastUsed}, log)
+}
 
+func newWorkspaceClient(devPodConfig *config.Config, provider *provider2.ProviderConfig, workspace *provider2.Workspace, machine *provider2.Machine, log log.Logger) (client.BaseWorkspaceClient, error) {
+	var err error
 	var workspaceClient client.BaseWorkspaceClient
 	if provider.IsProxyProvider() {
 		workspaceClient, err = clientimplementation.NewProxyClient(devPodConfig, provider, workspace, log)
@@ -544,41 +542,14 @@ func ToID(str string) string {
 }
 
 func selectWorkspace(devPodConfig *config.Config, changeLastUsed bool, log log.Logger) (*provider2.ProviderConfig, *provider2.Workspace, *provider2.Machine, error) {
-	if !terminal.IsTerminalIn {
-		return nil, nil, nil, errProvideWorkspaceArg
-	}
-
 	// ask which workspace to use
-	workspacesDir, err := provider2.GetWorkspacesDir(devPodConfig.DefaultContext)
-	if err != nil {
-		return nil, nil, nil, err
-	}
-
-	workspaceIDs := []string{}
-	workspacesDirs, err := os.ReadDir(workspacesDir)
-	if err != nil {
-		return nil, nil, nil, err
-	}
-
-	for _, workspace := range workspacesDirs {
-		workspaceIDs = append(workspaceIDs, workspace.Name())
-	}
-	if len(workspaceIDs) == 0 {
-		return nil, nil, nil, errProvideWorkspaceArg
-	}
-
-	answer, err := log.Question(&survey.QuestionOptions{
-		Question:     "Please select a workspace from the list below",
-		DefaultValue: workspaceIDs[0],
-		Options:      workspaceIDs,
-		Sort:         true,
-	})
+	workspaceID, err := pickWorkspace(devPodConfig, nil, log)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
 	// load workspace
-	return loadExistingWorkspace(answer, devPodConfig, changeLastUsed, log)
+	return loadExistingWorkspace(workspaceID, devPodConfig, changeLastUsed, log)
 }
 
 func loadExistingWorkspace(workspaceID string, devPodConfig *config.Config, changeLastUsed bool, log log.Logger) (*provider2.ProviderConfig, *provider2.Workspace, *provider2.Machine, error) {